@@ -0,0 +1,134 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupSpec defines the desired state of Backup
+type BackupSpec struct {
+	// ClusterName represents the name of the cluster to be backed up.
+	ClusterName string `json:"clusterName"`
+
+	// HostName represents the pod from which to take the backup. If it is empty,
+	// the operator will choose a healthy follower, or the leader if there is none.
+	// +optional
+	HostName string `json:"hostName,omitempty"`
+
+	// To specify the image that will be used for the backup job.
+	// +optional
+	// +kubebuilder:default:="zhyass/sidecar:0.1"
+	Image string `json:"image,omitempty"`
+
+	// Destination is the S3-compatible storage where the backup will be uploaded.
+	Destination BackupDestination `json:"destination"`
+}
+
+// BackupDestination defines the S3-compatible storage used to keep backups.
+type BackupDestination struct {
+	// Endpoint of the S3-compatible storage, eg: http://minio.default:9000.
+	Endpoint string `json:"endpoint"`
+
+	// Bucket in which the backups are stored.
+	Bucket string `json:"bucket"`
+
+	// Region of the bucket.
+	// +optional
+	// +kubebuilder:default:="us-east-1"
+	Region string `json:"region,omitempty"`
+
+	// SecretName is the name of the secret that contains the `s3-access-key`
+	// and `s3-secret-key` used to access the storage.
+	SecretName string `json:"secretName"`
+}
+
+type BackupConditionType string
+
+const (
+	// BackupComplete means the backup has finished its execution.
+	BackupComplete BackupConditionType = "Complete"
+	// BackupFailed means the backup has failed.
+	BackupFailed BackupConditionType = "Failed"
+)
+
+// BackupCondition defines type for backup conditions.
+type BackupCondition struct {
+	// type of backup condition, values in (\"Complete\", \"Failed\")
+	Type BackupConditionType `json:"type"`
+	// Status of the condition, one of (\"True\", \"False\", \"Unknown\")
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason
+	Reason string `json:"reason,omitempty"`
+	// Message
+	Message string `json:"message,omitempty"`
+}
+
+// BackupStatus defines the observed state of Backup
+type BackupStatus struct {
+	// Completed indicates whether the backup is in a final state,
+	// no matter whether its corresponding job failed or succeeded.
+	Completed bool `json:"completed,omitempty"`
+
+	// BackupURL is the location of the backup, eg: s3://bucket/name.
+	BackupURL string `json:"backupURL,omitempty"`
+	// BackupSize is the size in bytes of the uploaded backup stream.
+	BackupSize int64 `json:"backupSize,omitempty"`
+	// GtidSet is the executed gtid set of the backup.
+	GtidSet string `json:"gtidSet,omitempty"`
+
+	// StartTime represents time when the backup was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime represents time when the backup was completed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Conditions contains the list of the backup conditions fulfilled
+	Conditions []BackupCondition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="The name of the backed up cluster"
+// +kubebuilder:printcolumn:name="Host",type="string",JSONPath=".spec.hostName",description="The pod the backup was taken from"
+// +kubebuilder:printcolumn:name="Complete",type="string",JSONPath=".status.conditions[?(@.type == 'Complete')].status",description="Whether the backup is complete"
+// +kubebuilder:printcolumn:name="Size",type="integer",JSONPath=".status.backupSize",description="The size of the backup"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// Backup is the Schema for the backups API
+type Backup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupSpec   `json:"spec,omitempty"`
+	Status BackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// BackupList contains a list of Backup
+type BackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Backup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Backup{}, &BackupList{})
+}
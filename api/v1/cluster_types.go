@@ -38,7 +38,7 @@ type ClusterSpec struct {
 
 	// MysqlOpts is the options of MySQL container.
 	// +optional
-	// +kubebuilder:default:={rootPassword: "", user: "qc_usr", password: "Qing@123", database: "qingcloud", initTokuDB: true, resources: {limits: {cpu: "500m", memory: "1Gi"}, requests: {cpu: "100m", memory: "256Mi"}}}
+	// +kubebuilder:default:={user: "qc_usr", database: "qingcloud", initTokuDB: true, resources: {limits: {cpu: "500m", memory: "1Gi"}, requests: {cpu: "100m", memory: "256Mi"}}}
 	MysqlOpts MysqlOpts `json:"mysqlOpts,omitempty"`
 
 	// XenonOpts is the options of xenon container.
@@ -50,7 +50,7 @@ type ClusterSpec struct {
 	// +kubebuilder:default:={image: "prom/mysqld-exporter:v0.12.1", resources: {limits: {cpu: "100m", memory: "128Mi"}, requests: {cpu: "10m", memory: "32Mi"}}, enabled: false}
 	MetricsOpts MetricsOpts `json:"metricsOpts,omitempty"`
 
-	// Represents the MySQL version that will be run. The available version can be found here:
+	// Represents the MySQL version that will be run. The available versions are 5.7 and 8.0.
 	// This field should be set even if the Image is set to let the operator know which mysql version is running.
 	// Based on this version the operator can take decisions which features can be used.
 	// +optional
@@ -66,39 +66,175 @@ type ClusterSpec struct {
 	// +optional
 	// +kubebuilder:default:={enabled: true, accessModes: {"ReadWriteOnce"}, size: "10Gi"}
 	Persistence Persistence `json:"persistence,omitempty"`
+
+	// DiskOpts is the thresholds of the disk usage and the protections of
+	// the cluster before the volumes are exhausted.
+	// +optional
+	// +kubebuilder:default:={warningThreshold: 80}
+	DiskOpts DiskOpts `json:"diskOpts,omitempty"`
+
+	// BackupSchedule is the options to take backups periodically.
+	// +optional
+	BackupSchedule *BackupSchedule `json:"backupSchedule,omitempty"`
+
+	// BinlogArchive continuously archives the binlogs of the leader to the
+	// storage, which enables the point-in-time recovery.
+	// +optional
+	BinlogArchive *BinlogArchive `json:"binlogArchive,omitempty"`
+
+	// RestoreFrom is the backup used to bootstrap the new cluster.
+	// +optional
+	RestoreFrom *RestoreFrom `json:"restoreFrom,omitempty"`
+
+	// Leader is the name of the pod expected to be the leader, the operator
+	// switches the leadership over to it if it is a healthy follower.
+	// +optional
+	Leader string `json:"leader,omitempty"`
+
+	// UpgradeBackup is the storage of the backup taken before upgrading the
+	// mysql version. No backup is taken if it is not set.
+	// +optional
+	UpgradeBackup *BackupDestination `json:"upgradeBackup,omitempty"`
+
+	// TLS enables the encrypted connections of the clients and the replication.
+	// +optional
+	TLS *TLSOpts `json:"tls,omitempty"`
+
+	// Proxy deploys a proxysql that splits the reads and writes, the clients
+	// connect to the proxy service instead of the leader and follower services.
+	// +optional
+	Proxy *ProxyOpts `json:"proxy,omitempty"`
+
+	// DeletionPolicy is what happens to the data when the cluster is deleted,
+	// one of ("Delete", "Retain", "BackupThenDelete"). Retain keeps the
+	// volumes and the secret, BackupThenDelete takes a backup to FinalBackup
+	// before deleting them.
+	// +optional
+	// +kubebuilder:default:="Delete"
+	DeletionPolicy ClusterDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// FinalBackup is the storage of the backup taken before deleting the
+	// cluster, required by the BackupThenDelete policy.
+	// +optional
+	FinalBackup *BackupDestination `json:"finalBackup,omitempty"`
+}
+
+// ClusterDeletionPolicy defines what happens to the data when the Cluster is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain;BackupThenDelete
+type ClusterDeletionPolicy string
+
+const (
+	// ClusterDelete deletes the volumes with the cluster.
+	ClusterDelete ClusterDeletionPolicy = "Delete"
+	// ClusterRetain keeps the volumes and the secret, a new cluster with the
+	// same name runs on them.
+	ClusterRetain ClusterDeletionPolicy = "Retain"
+	// ClusterBackupThenDelete takes a final backup before deleting the volumes.
+	ClusterBackupThenDelete ClusterDeletionPolicy = "BackupThenDelete"
+)
+
+// ProxyOpts defines the options of the proxysql deployment.
+type ProxyOpts struct {
+	// To specify the image that will be used for proxysql container.
+	// +optional
+	// +kubebuilder:default:="proxysql/proxysql:2.0.18"
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of the proxysql pods.
+	// +optional
+	// +kubebuilder:default:=2
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// +optional
+	// +kubebuilder:default:={limits: {cpu: "500m", memory: "512Mi"}, requests: {cpu: "100m", memory: "128Mi"}}
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// TLSOpts defines the certificate of mysql.
+type TLSOpts struct {
+	// SecretName is the name of the secret that contains the `ca.crt`, `tls.crt`
+	// and `tls.key`.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// IssuerRef is the cert-manager issuer of the certificate, which is saved to
+	// the secret `<cluster name>-tls`. It is ignored if SecretName is set.
+	// +optional
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+
+	// RequireSecureTransport rejects the TCP connections without TLS.
+	// +optional
+	RequireSecureTransport bool `json:"requireSecureTransport,omitempty"`
+}
+
+// IssuerReference is the reference to a cert-manager issuer.
+type IssuerReference struct {
+	// Name of the issuer.
+	Name string `json:"name"`
+
+	// Kind of the issuer, Issuer or ClusterIssuer.
+	// +optional
+	// +kubebuilder:default:="Issuer"
+	Kind string `json:"kind,omitempty"`
+
+	// Group of the issuer.
+	// +optional
+	// +kubebuilder:default:="cert-manager.io"
+	Group string `json:"group,omitempty"`
 }
 
 // MysqlOpts defines the options of MySQL container.
 type MysqlOpts struct {
-	// Password for the root user.
+	// Password for the root user, it must be alpha-numeric.
+	// Deprecated: use RootPasswordSecretRef instead.
 	// +optional
-	// +kubebuilder:default:=""
 	RootPassword string `json:"rootPassword,omitempty"`
 
+	// RootPasswordSecretRef is the key of the secret that contains the initial
+	// password for the root user, it must be alpha-numeric. A random password
+	// is generated if neither it nor RootPassword is set.
+	// +optional
+	RootPasswordSecretRef *corev1.SecretKeySelector `json:"rootPasswordSecretRef,omitempty"`
+
 	// Username of new user to create.
 	// +optional
 	// +kubebuilder:default:="qc_usr"
 	User string `json:"user,omitempty"`
 
-	// Password for the new user.
+	// Password for the new user, it must be alpha-numeric.
+	// Deprecated: use UserPasswordSecretRef instead.
 	// +optional
-	// +kubebuilder:default:="Qing@123"
 	Password string `json:"password,omitempty"`
 
+	// UserPasswordSecretRef is the key of the secret that contains the password
+	// for the new user, it must be alpha-numeric. A random password is
+	// generated if neither it nor Password is set.
+	// +optional
+	UserPasswordSecretRef *corev1.SecretKeySelector `json:"userPasswordSecretRef,omitempty"`
+
 	// Name for new database to create.
 	// +optional
 	// +kubebuilder:default:="qingcloud"
 	Database string `json:"database,omitempty"`
 
-	// Install tokudb engine.
+	// Install tokudb engine, it is ignored by mysql 8.0.
 	// +optional
 	// +kubebuilder:default:=true
 	InitTokuDB bool `json:"initTokuDB,omitempty"`
 
-	// A map[string]string that will be passed to my.cnf file.
+	// A map[string]string that will be passed to my.cnf file. The dynamic
+	// configs are applied at runtime, the others by restarting the pods.
+	// Removing a dynamic config does not reset it to the default until the
+	// pods are restarted.
 	// +optional
 	MysqlConf MysqlConf `json:"mysqlConf,omitempty"`
 
+	// TuningProfile is used to tune the innodb and connection configs by the
+	// resources of the mysql container, the configs in MysqlConf take precedence.
+	// +optional
+	// +kubebuilder:default:="OLTP"
+	TuningProfile TuningProfile `json:"tuningProfile,omitempty"`
+
 	// +optional
 	// +kubebuilder:default:={limits: {cpu: "500m", memory: "1Gi"}, requests: {cpu: "100m", memory: "256Mi"}}
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
@@ -140,6 +276,19 @@ type MetricsOpts struct {
 	Enabled bool `json:"enabled,omitempty"`
 }
 
+// TuningProfile is the profile used to tune the mysql configs.
+// +kubebuilder:validation:Enum=OLTP;Small;Analytical
+type TuningProfile string
+
+const (
+	// TuningProfileOLTP serves many short transactions, most of the memory is used by the buffer pool.
+	TuningProfileOLTP TuningProfile = "OLTP"
+	// TuningProfileSmall is for the small instances, such as the development and test ones.
+	TuningProfileSmall TuningProfile = "Small"
+	// TuningProfileAnalytical serves few connections running large queries with bigger per-thread buffers.
+	TuningProfileAnalytical TuningProfile = "Analytical"
+)
+
 // MysqlConf defines type for extra cluster configs. It's a simple map between
 // string and string.
 type MysqlConf map[string]intstr.IntOrString
@@ -150,13 +299,32 @@ type PodSpec struct {
 	// +kubebuilder:default:="IfNotPresent"
 	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
 
-	Labels            map[string]string   `json:"labels,omitempty"`
-	Annotations       map[string]string   `json:"annotations,omitempty"`
-	Affinity          *corev1.Affinity    `json:"affinity,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Affinity    *corev1.Affinity  `json:"affinity,omitempty"`
+
+	// AntiAffinity spreads the pods across the nodes and zones when Affinity
+	// is not set. "hard" requires the pods on different nodes, "soft" prefers
+	// it, "none" disables it. Both prefer the pods in different zones.
+	// +optional
+	// +kubebuilder:validation:Enum=soft;hard;none
+	// +kubebuilder:default:="soft"
+	AntiAffinity AntiAffinity `json:"antiAffinity,omitempty"`
+
+	// TopologySpreadConstraints of the pods, the pods of the cluster are
+	// selected if the labelSelector is not set.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
 	PriorityClassName string              `json:"priorityClassName,omitempty"`
 	Tolerations       []corev1.Toleration `json:"tolerations,omitempty"`
 	SchedulerName     string              `json:"schedulerName,omitempty"`
 
+	// MaxUnavailable is the number or percentage of the pods that can be
+	// evicted at the same time by the pod disruption budget, defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
 	// +optional
 	// +kubebuilder:default:={requests: {cpu: "10m", memory: "32Mi"}}
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
@@ -178,6 +346,15 @@ type PodSpec struct {
 	AuditLogTail bool `json:"auditLogTail,omitempty"`
 }
 
+// AntiAffinity is the type of the default pod anti-affinity.
+type AntiAffinity string
+
+const (
+	AntiAffinitySoft AntiAffinity = "soft"
+	AntiAffinityHard AntiAffinity = "hard"
+	AntiAffinityNone AntiAffinity = "none"
+)
+
 // Persistence is the desired spec for storing mysql data. Only one of its
 // members may be specified.
 type Persistence struct {
@@ -191,7 +368,8 @@ type Persistence struct {
 	// +kubebuilder:default:={"ReadWriteOnce"}
 	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
 
-	// Name of the StorageClass required by the claim.
+	// Name of the StorageClass required by the claim, "-" means the empty
+	// StorageClass, which disables the dynamic provisioning.
 	// More info: https://kubernetes.io/docs/concepts/storage/persistent-volumes#class-1
 	// +optional
 	StorageClass *string `json:"storageClass,omitempty"`
@@ -199,6 +377,128 @@ type Persistence struct {
 	// +optional
 	// +kubebuilder:default:="10Gi"
 	Size string `json:"size,omitempty"`
+
+	// Binlog is the separate volume of the binlogs and the relay logs.
+	// +optional
+	Binlog *VolumeOpts `json:"binlog,omitempty"`
+
+	// RedoLog is the separate volume of the innodb redo logs.
+	// +optional
+	RedoLog *VolumeOpts `json:"redoLog,omitempty"`
+
+	// Tmp is the separate volume of the tmpdir, which holds the temporary
+	// tables and the files of the sorts.
+	// +optional
+	Tmp *VolumeOpts `json:"tmp,omitempty"`
+}
+
+// DiskOpts defines the thresholds of the disk usage and the protections.
+type DiskOpts struct {
+	// WarningThreshold is the used percentage of a volume above which the
+	// warning events are emitted and the DiskPressure condition is set.
+	// +optional
+	// +kubebuilder:default:=80
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	WarningThreshold int32 `json:"warningThreshold,omitempty"`
+
+	// PurgeBinlogs purges the binlogs of the leader which have been applied
+	// on all the followers when a volume of the leader is above the warning
+	// threshold. It is ignored if the binlogs are archived.
+	// +optional
+	PurgeBinlogs bool `json:"purgeBinlogs,omitempty"`
+
+	// ReadOnlyThreshold is the used percentage of a volume of the leader
+	// above which the cluster is set read-only, the writes are enabled again
+	// once the usage drops below the warning threshold. It is disabled if
+	// not set.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	ReadOnlyThreshold *int32 `json:"readOnlyThreshold,omitempty"`
+}
+
+// VolumeOpts defines a separate volume of the mysql files, so that they can't
+// fill the data volume.
+type VolumeOpts struct {
+	// +optional
+	// +kubebuilder:default:="10Gi"
+	Size string `json:"size,omitempty"`
+
+	// Name of the StorageClass required by the claim, the one of the data
+	// volume is used if it is not set. "-" means the empty StorageClass.
+	// +optional
+	StorageClass *string `json:"storageClass,omitempty"`
+}
+
+// BackupSchedule defines the options to take scheduled backups and their retention.
+type BackupSchedule struct {
+	// Schedule is a cron expression in the standard format, eg: "0 0 * * *".
+	Schedule string `json:"schedule"`
+
+	// Destination is the S3-compatible storage where the backups will be uploaded.
+	Destination BackupDestination `json:"destination"`
+
+	// BackupsToKeep is the number of the successful scheduled backups to keep,
+	// the failed ones are deleted once they are older than the kept ones.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	BackupsToKeep *int32 `json:"backupsToKeep,omitempty"`
+
+	// DaysToKeep is the number of days to keep the completed scheduled backups,
+	// the newest successful one is always kept.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	DaysToKeep *int32 `json:"daysToKeep,omitempty"`
+}
+
+// RestoreFrom defines the backup from which to restore a new cluster. Either
+// BackupName or BackupURL with the storage options should be specified.
+type RestoreFrom struct {
+	// BackupName is the name of a completed Backup in the same namespace, the
+	// operator will fill the other fields from it.
+	// +optional
+	BackupName string `json:"backupName,omitempty"`
+
+	// BackupURL is the location of the backup, eg: s3://bucket/cluster/backup.
+	// +optional
+	BackupURL string `json:"backupURL,omitempty"`
+
+	// Endpoint of the S3-compatible storage, eg: http://minio.default:9000.
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Region of the bucket.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// SecretName is the name of the secret that contains the `s3-access-key`
+	// and `s3-secret-key` used to access the storage.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// BinlogURL is the location of the archived binlogs on the same storage,
+	// eg: s3://bucket/cluster/binlogs. It defaults to the binlog archive of the
+	// backup's cluster when restoring from BackupName.
+	// +optional
+	BinlogURL string `json:"binlogURL,omitempty"`
+
+	// RestoreTime is the point in time to recover to by applying the archived
+	// binlogs on top of the backup.
+	// +optional
+	RestoreTime *metav1.Time `json:"restoreTime,omitempty"`
+
+	// RestoreGtid is the gtid set to recover to by applying the archived
+	// binlogs on top of the backup, eg: 3E11FA47-71CA-11E1-9E33-C80AA9429562:1-100.
+	// +optional
+	RestoreGtid string `json:"restoreGtid,omitempty"`
+}
+
+// BinlogArchive defines where to archive the binlogs. The binlogs are stored
+// under `<cluster name>/binlogs/` of the bucket.
+type BinlogArchive struct {
+	// Destination is the storage of the archived binlogs.
+	Destination BackupDestination `json:"destination"`
 }
 
 type ClusterConditionType string
@@ -207,8 +507,144 @@ const (
 	ClusterInit  ClusterConditionType = "Initializing"
 	ClusterReady ClusterConditionType = "Ready"
 	ClusterError ClusterConditionType = "Error"
+	// ClusterSwitchover is true when a switchover is in progress.
+	ClusterSwitchover ClusterConditionType = "Switchover"
+	// ClusterDiskPressure is true when a volume is above the warning threshold.
+	ClusterDiskPressure ClusterConditionType = "DiskPressure"
+)
+
+// SwitchoverPhase defines the phase of a switchover.
+type SwitchoverPhase string
+
+const (
+	SwitchoverInProgress SwitchoverPhase = "InProgress"
+	SwitchoverSucceeded  SwitchoverPhase = "Succeeded"
+	SwitchoverFailed     SwitchoverPhase = "Failed"
+)
+
+// SwitchoverStatus defines the status of the last switchover.
+type SwitchoverStatus struct {
+	// From is the name of the pod which was the leader.
+	From string `json:"from,omitempty"`
+	// Target is the name of the pod expected to be the leader.
+	Target string `json:"target"`
+	// Phase of the switchover, one of (\"InProgress\", \"Succeeded\", \"Failed\").
+	Phase SwitchoverPhase `json:"phase"`
+	// Message about the result of the switchover.
+	Message string `json:"message,omitempty"`
+	// StartTime is the time the switchover started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the switchover succeeded or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RolloutPhase defines the phase of a rolling update.
+type RolloutPhase string
+
+const (
+	RolloutUpdating  RolloutPhase = "Updating"
+	RolloutCompleted RolloutPhase = "Completed"
+)
+
+// RolloutStatus defines the status of the rolling update driven by the
+// operator, which updates the followers one by one and the leader last.
+type RolloutStatus struct {
+	// Phase of the rolling update, one of (\"Updating\", \"Completed\").
+	Phase RolloutPhase `json:"phase"`
+	// UpdateRevision is the revision of the statefulset being rolled out.
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// UpdatedReplicas is the number of the pods at the update revision.
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// Current is the name of the pod being updated.
+	Current string `json:"current,omitempty"`
+	// Message about the progress of the rolling update.
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the phase changed.
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// SwitchoverTarget is the follower the leadership is being switched over
+	// to before the leader is updated.
+	SwitchoverTarget string `json:"switchoverTarget,omitempty"`
+	// SwitchoverFailures is the number of the switchovers which failed to move
+	// the leadership away from the outdated leader.
+	SwitchoverFailures int32 `json:"switchoverFailures,omitempty"`
+	// LastSwitchoverFailureTime is the last time a switchover failed, the next
+	// one is requested after a backoff.
+	LastSwitchoverFailureTime *metav1.Time `json:"lastSwitchoverFailureTime,omitempty"`
+}
+
+// UpgradePhase defines the phase of a mysql version upgrade.
+type UpgradePhase string
+
+const (
+	UpgradeBackingUp UpgradePhase = "BackingUp"
+	UpgradeUpgrading UpgradePhase = "Upgrading"
+	UpgradeSucceeded UpgradePhase = "Succeeded"
+	UpgradeFailed    UpgradePhase = "Failed"
+)
+
+// UpgradeStatus defines the status of the last mysql version upgrade.
+type UpgradeStatus struct {
+	// From is the mysql version before the upgrade.
+	From string `json:"from"`
+	// To is the mysql version to upgrade to.
+	To string `json:"to"`
+	// Phase of the upgrade, one of (\"BackingUp\", \"Upgrading\", \"Succeeded\", \"Failed\").
+	Phase UpgradePhase `json:"phase"`
+	// BackupName is the name of the backup taken before the upgrade.
+	BackupName string `json:"backupName,omitempty"`
+	// Message about the progress or the result of the upgrade.
+	Message string `json:"message,omitempty"`
+	// StartTime is the time the upgrade started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// RolloutTime is the time the new version started to roll out to the pods,
+	// the upgrade fails if the pods are not upgraded in time. The pods of the
+	// failed upgrade keep the new version until the version is set back.
+	RolloutTime *metav1.Time `json:"rolloutTime,omitempty"`
+	// CompletionTime is the time the upgrade succeeded or failed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Retries is the number of times the failed upgrade has been retried.
+	Retries int32 `json:"retries,omitempty"`
+}
+
+// CredentialRotationPhase defines the phase of a credential rotation.
+type CredentialRotationPhase string
+
+const (
+	CredentialRotationRotating  CredentialRotationPhase = "Rotating"
+	CredentialRotationSucceeded CredentialRotationPhase = "Succeeded"
 )
 
+// CredentialRotationStatus defines the status of the last credential rotation.
+type CredentialRotationStatus struct {
+	// Trigger is the value of the annotation that requested the rotation.
+	Trigger string `json:"trigger"`
+	// Phase of the rotation, one of (\"Rotating\", \"Succeeded\").
+	Phase CredentialRotationPhase `json:"phase"`
+	// Message about the progress or the result of the rotation.
+	Message string `json:"message,omitempty"`
+	// Gtid is the gtid set executed by the leader once the users were altered,
+	// the followers have to reach it before the passwords are saved.
+	Gtid string `json:"gtid,omitempty"`
+	// StartTime is the time the rotation started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the new passwords were saved to the secret.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// MysqlConfStatus defines the status of the mysqlConf.
+type MysqlConfStatus struct {
+	// Static are the static configs all the pods have been restarted with.
+	Static map[string]string `json:"static,omitempty"`
+	// PendingRestart are the static configs which take effect after the pods
+	// are restarted.
+	PendingRestart []string `json:"pendingRestart,omitempty"`
+	// ReadOnly are the configs reported read-only by mysqld when they were set
+	// at runtime, they are applied as the static ones.
+	ReadOnly []string `json:"readOnly,omitempty"`
+	// Message about the failure of applying the dynamic configs.
+	Message string `json:"message,omitempty"`
+}
+
 // ClusterCondition defines type for cluster conditions.
 type ClusterCondition struct {
 	// type of cluster condition, values in (\"Ready\")
@@ -229,6 +665,28 @@ type NodeStatus struct {
 	Name       string          `json:"name"`
 	Message    string          `json:"message,omitempty"`
 	Conditions []NodeCondition `json:"conditions,omitempty"`
+	// DiskUsage is the disk usage reported by the sidecar of the node.
+	DiskUsage *DiskUsage `json:"diskUsage,omitempty"`
+}
+
+// DiskUsage defines the disk usage of a node.
+type DiskUsage struct {
+	// Volumes are the file systems of the data directory and the separate volumes.
+	Volumes []VolumeUsage `json:"volumes,omitempty"`
+	// Binlogs is the total size of the binlogs.
+	Binlogs string `json:"binlogs,omitempty"`
+	// RelayLogs is the total size of the relay logs.
+	RelayLogs string `json:"relayLogs,omitempty"`
+}
+
+// VolumeUsage defines the usage of the file system of a volume.
+type VolumeUsage struct {
+	// Name of the volume, eg: data, binlog.
+	Name     string `json:"name"`
+	Used     string `json:"used,omitempty"`
+	Capacity string `json:"capacity,omitempty"`
+	// UsedPercent is the used percentage of the space available to mysql.
+	UsedPercent int32 `json:"usedPercent"`
 }
 
 // NodeCondition defines type for representing node conditions.
@@ -263,6 +721,71 @@ type ClusterStatus struct {
 	// Conditions contains the list of the cluster conditions fulfilled
 	Conditions []ClusterCondition `json:"conditions,omitempty"`
 	Nodes      []NodeStatus       `json:"nodes,omitempty"`
+
+	// LastScheduledBackupTime is the last time a scheduled backup was created.
+	LastScheduledBackupTime *metav1.Time `json:"lastScheduledBackupTime,omitempty"`
+	// LastSuccessfulBackupTime is the completion time of the last successful backup.
+	LastSuccessfulBackupTime *metav1.Time `json:"lastSuccessfulBackupTime,omitempty"`
+
+	// Switchover is the status of the last switchover.
+	Switchover *SwitchoverStatus `json:"switchover,omitempty"`
+
+	// Rollout is the status of the rolling update.
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// MysqlVersion is the mysql version the cluster is running.
+	MysqlVersion string `json:"mysqlVersion,omitempty"`
+	// Upgrade is the status of the last mysql version upgrade.
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
+
+	// CredentialRotation is the status of the last credential rotation.
+	CredentialRotation *CredentialRotationStatus `json:"credentialRotation,omitempty"`
+	// LastCredentialRotationTime is the last time the passwords were rotated,
+	// the pods are restarted when it changes.
+	LastCredentialRotationTime *metav1.Time `json:"lastCredentialRotationTime,omitempty"`
+
+	// MysqlConf is the status of applying the mysqlConf.
+	MysqlConf *MysqlConfStatus `json:"mysqlConf,omitempty"`
+
+	// FinalBackup is the name of the backup taken before deleting the cluster.
+	FinalBackup string `json:"finalBackup,omitempty"`
+
+	// Volumes are the status of the persistent volume claims of the data.
+	Volumes []VolumeStatus `json:"volumes,omitempty"`
+
+	// ReadOnly is true if the cluster has been set read-only because a
+	// volume of the leader is above the read-only threshold.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// DiskPressure is the message about the volumes above the warning
+	// threshold, empty if there is none. It is kept out of the conditions,
+	// whose history is capped.
+	DiskPressure string `json:"diskPressure,omitempty"`
+}
+
+// VolumeResizePhase is the phase of expanding a volume.
+type VolumeResizePhase string
+
+const (
+	// VolumeReady means the capacity of the volume has reached the size.
+	VolumeReady VolumeResizePhase = "Ready"
+	// VolumeResizing means the volume is being expanded.
+	VolumeResizing VolumeResizePhase = "Resizing"
+	// VolumeFileSystemResizePending means the file system waits to be expanded on the node.
+	VolumeFileSystemResizePending VolumeResizePhase = "FileSystemResizePending"
+	// VolumeResizeUnsupported means the storage class does not allow the expansion.
+	VolumeResizeUnsupported VolumeResizePhase = "Unsupported"
+)
+
+// VolumeStatus defines the expanding status of a persistent volume claim.
+type VolumeStatus struct {
+	// Name of the persistent volume claim.
+	Name string `json:"name"`
+	// Size is the requested size.
+	Size string `json:"size,omitempty"`
+	// Capacity is the actual capacity of the volume.
+	Capacity string            `json:"capacity,omitempty"`
+	Phase    VolumeResizePhase `json:"phase,omitempty"`
+	Message  string            `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
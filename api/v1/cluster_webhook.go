@@ -0,0 +1,373 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/robfig/cron/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// log is for logging in this package.
+var clusterlog = logf.Log.WithName("cluster-resource")
+
+// reservedMysqlVariables are managed by the operator and xenon, they can't be
+// overwritten by the mysqlConf.
+var reservedMysqlVariables = map[string]bool{
+	"read_only":                 true,
+	"super_read_only":           true,
+	"log_bin":                   true,
+	"gtid_mode":                 true,
+	"enforce_gtid_consistency":  true,
+	"binlog_format":             true,
+	"server_id":                 true,
+	"relay_log":                 true,
+	"relay_log_index":           true,
+	"master_info_repository":    true,
+	"relay_log_info_repository": true,
+	"plugin_load":               true,
+	"datadir":                   true,
+	"socket":                    true,
+	"port":                      true,
+	"ssl_ca":                    true,
+	"ssl_cert":                  true,
+	"ssl_key":                   true,
+	"require_secure_transport":  true,
+}
+
+func (r *Cluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-mysql-radondb-io-v1-cluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=mysql.radondb.io,resources=clusters,verbs=create;update,versions=v1,name=mcluster.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &Cluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Cluster) Default() {
+	clusterlog.Info("default", "name", r.Name)
+
+	if len(r.Spec.MysqlVersion) == 0 {
+		r.Spec.MysqlVersion = "5.7"
+	}
+	if r.Spec.XenonOpts.AdmitDefeatHearbeatCount == nil {
+		count := int32(5)
+		r.Spec.XenonOpts.AdmitDefeatHearbeatCount = &count
+	}
+	if r.Spec.XenonOpts.ElectionTimeout == nil {
+		timeout := int32(10000)
+		r.Spec.XenonOpts.ElectionTimeout = &timeout
+	}
+	if len(r.Spec.Persistence.Size) == 0 {
+		r.Spec.Persistence.Size = "10Gi"
+	}
+	if r.Spec.DiskOpts.WarningThreshold == 0 {
+		r.Spec.DiskOpts.WarningThreshold = 80
+	}
+	for _, opts := range []*VolumeOpts{r.Spec.Persistence.Binlog, r.Spec.Persistence.RedoLog, r.Spec.Persistence.Tmp} {
+		if opts != nil && len(opts.Size) == 0 {
+			opts.Size = "10Gi"
+		}
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-mysql-radondb-io-v1-cluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=mysql.radondb.io,resources=clusters,verbs=create;update,versions=v1,name=vcluster.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &Cluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateCreate() error {
+	clusterlog.Info("validate create", "name", r.Name)
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validatePasswords(nil)...)
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateUpdate(old runtime.Object) error {
+	clusterlog.Info("validate update", "name", r.Name)
+
+	oldCluster, ok := old.(*Cluster)
+	if !ok {
+		return fmt.Errorf("expected a Cluster but got a %T", old)
+	}
+
+	allErrs := r.validateSpec()
+	allErrs = append(allErrs, r.validatePasswords(oldCluster)...)
+	allErrs = append(allErrs, r.validatePersistenceUpdate(oldCluster)...)
+	allErrs = append(allErrs, r.validateVersionUpdate(oldCluster)...)
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Cluster) ValidateDelete() error {
+	return nil
+}
+
+func (r *Cluster) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if _, err := getMysqlSemVer(r.Spec.MysqlVersion); err != nil {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("mysqlVersion"),
+			r.Spec.MysqlVersion, supportedMysqlVersions()))
+	}
+
+	xenonPath := specPath.Child("xenonOpts")
+	count := r.Spec.XenonOpts.AdmitDefeatHearbeatCount
+	if count != nil && *count <= 0 {
+		allErrs = append(allErrs, field.Invalid(xenonPath.Child("admitDefeatHearbeatCount"),
+			*count, "must be greater than 0"))
+	}
+	timeout := r.Spec.XenonOpts.ElectionTimeout
+	if timeout != nil && *timeout <= 0 {
+		allErrs = append(allErrs, field.Invalid(xenonPath.Child("electionTimeout"),
+			*timeout, "must be greater than 0"))
+	}
+	if count != nil && timeout != nil && *count > 0 && *timeout < *count {
+		allErrs = append(allErrs, field.Invalid(xenonPath.Child("electionTimeout"),
+			*timeout, "must not be less than admitDefeatHearbeatCount"))
+	}
+
+	persistencePath := specPath.Child("persistence")
+	if _, err := resource.ParseQuantity(r.Spec.Persistence.Size); err != nil {
+		allErrs = append(allErrs, field.Invalid(persistencePath.Child("size"),
+			r.Spec.Persistence.Size, err.Error()))
+	}
+	for name, opts := range r.getExtraVolumeOpts() {
+		if _, err := resource.ParseQuantity(opts.Size); err != nil {
+			allErrs = append(allErrs, field.Invalid(persistencePath.Child(name, "size"),
+				opts.Size, err.Error()))
+		}
+	}
+
+	if threshold := r.Spec.DiskOpts.ReadOnlyThreshold; threshold != nil &&
+		*threshold <= r.Spec.DiskOpts.WarningThreshold {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("diskOpts", "readOnlyThreshold"),
+			*threshold, "must be greater than warningThreshold"))
+	}
+
+	if max := r.Spec.PodSpec.MaxUnavailable; max != nil {
+		if value, err := intstr.GetValueFromIntOrPercent(max, 100, false); err != nil || value < 0 {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("podSpec", "maxUnavailable"),
+				max.String(), "must be a non-negative integer or percentage"))
+		}
+	}
+
+	mysqlPath := specPath.Child("mysqlOpts")
+	if len(r.Spec.MysqlOpts.RootPassword) != 0 && r.Spec.MysqlOpts.RootPasswordSecretRef != nil {
+		allErrs = append(allErrs, field.Forbidden(mysqlPath.Child("rootPassword"),
+			"may not be set together with rootPasswordSecretRef"))
+	}
+	if len(r.Spec.MysqlOpts.Password) != 0 && r.Spec.MysqlOpts.UserPasswordSecretRef != nil {
+		allErrs = append(allErrs, field.Forbidden(mysqlPath.Child("password"),
+			"may not be set together with userPasswordSecretRef"))
+	}
+
+	for key := range r.Spec.MysqlOpts.MysqlConf {
+		if reservedMysqlVariables[utils.NormalizeMysqlVariable(key)] {
+			allErrs = append(allErrs, field.Forbidden(mysqlPath.Child("mysqlConf").Key(key),
+				"the variable is managed by the operator"))
+		}
+	}
+
+	if tls := r.Spec.TLS; tls != nil && len(tls.SecretName) == 0 && tls.IssuerRef == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("tls"),
+			"either secretName or issuerRef must be specified"))
+	}
+
+	if r.Spec.DeletionPolicy == ClusterBackupThenDelete && r.Spec.FinalBackup == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("finalBackup"),
+			"must be specified with the BackupThenDelete policy"))
+	}
+
+	if schedule := r.Spec.BackupSchedule; schedule != nil {
+		if _, err := cron.ParseStandard(schedule.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("backupSchedule", "schedule"),
+				schedule.Schedule, err.Error()))
+		}
+	}
+
+	if restore := r.Spec.RestoreFrom; restore != nil &&
+		len(restore.BackupName) == 0 && len(restore.BackupURL) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("restoreFrom"),
+			"either backupName or backupURL must be specified"))
+	}
+
+	return allErrs
+}
+
+// validatePasswords checks the plaintext passwords on create, or on update if
+// they changed, so the clusters created with the former defaults can still be
+// updated. The passwords are not echoed in the errors.
+func (r *Cluster) validatePasswords(old *Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	path := field.NewPath("spec", "mysqlOpts")
+
+	rootPassword, password := r.Spec.MysqlOpts.RootPassword, r.Spec.MysqlOpts.Password
+	if (old == nil || rootPassword != old.Spec.MysqlOpts.RootPassword) && !utils.IsAlphaNumeric(rootPassword) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("rootPassword"), "must be alpha-numeric"))
+	}
+	if (old == nil || password != old.Spec.MysqlOpts.Password) && !utils.IsAlphaNumeric(password) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("password"), "must be alpha-numeric"))
+	}
+	return allErrs
+}
+
+// validatePersistenceUpdate rejects the changes of the volume claim templates
+// except the expansion, which is applied to the volumes by the operator.
+func (r *Cluster) validatePersistenceUpdate(old *Cluster) field.ErrorList {
+	var allErrs field.ErrorList
+	path := field.NewPath("spec", "persistence")
+
+	oldSize, err := resource.ParseQuantity(old.Spec.Persistence.Size)
+	if err != nil {
+		return nil
+	}
+	newSize, err := resource.ParseQuantity(r.Spec.Persistence.Size)
+	if err != nil {
+		return nil
+	}
+	if newSize.Cmp(oldSize) < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("size"), r.Spec.Persistence.Size,
+			fmt.Sprintf("can not be decreased from %s", old.Spec.Persistence.Size)))
+	}
+
+	if r.Spec.Persistence.Enabled != old.Spec.Persistence.Enabled {
+		allErrs = append(allErrs, field.Forbidden(path.Child("enabled"), "field is immutable"))
+	}
+	if !reflect.DeepEqual(r.Spec.Persistence.AccessModes, old.Spec.Persistence.AccessModes) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("accessModes"), "field is immutable"))
+	}
+	if !reflect.DeepEqual(r.Spec.Persistence.StorageClass, old.Spec.Persistence.StorageClass) {
+		allErrs = append(allErrs, field.Forbidden(path.Child("storageClass"), "field is immutable"))
+	}
+	// the files can't be moved between the volumes of the running pods.
+	oldVolumes, newVolumes := old.getExtraVolumeOpts(), r.getExtraVolumeOpts()
+	for name, opts := range newVolumes {
+		oldOpts, ok := oldVolumes[name]
+		if !ok {
+			allErrs = append(allErrs, field.Forbidden(path.Child(name), "can not be added to a running cluster"))
+			continue
+		}
+		if !reflect.DeepEqual(opts.StorageClass, oldOpts.StorageClass) {
+			allErrs = append(allErrs, field.Forbidden(path.Child(name, "storageClass"), "field is immutable"))
+		}
+		oldSize, err1 := resource.ParseQuantity(oldOpts.Size)
+		newSize, err2 := resource.ParseQuantity(opts.Size)
+		if err1 == nil && err2 == nil && newSize.Cmp(oldSize) < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(name, "size"), opts.Size,
+				fmt.Sprintf("can not be decreased from %s", oldOpts.Size)))
+		}
+	}
+	for name := range oldVolumes {
+		if _, ok := newVolumes[name]; !ok {
+			allErrs = append(allErrs, field.Forbidden(path.Child(name), "can not be removed from a running cluster"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateVersionUpdate rejects the downgrades and the unsupported upgrades
+// from the running version.
+func (r *Cluster) validateVersionUpdate(old *Cluster) field.ErrorList {
+	newVer, err := getMysqlSemVer(r.Spec.MysqlVersion)
+	if err != nil {
+		// reported by validateSpec.
+		return nil
+	}
+
+	running := old.Status.MysqlVersion
+	if len(running) == 0 {
+		oldVer, err := getMysqlSemVer(old.Spec.MysqlVersion)
+		if err != nil {
+			return nil
+		}
+		running = oldVer
+	}
+
+	if running == newVer {
+		return nil
+	}
+	if err := utils.ValidateMysqlUpgrade(running, newVer); err != nil {
+		return field.ErrorList{field.Forbidden(field.NewPath("spec", "mysqlVersion"), err.Error())}
+	}
+	return nil
+}
+
+// getExtraVolumeOpts returns the separate volumes by their field names.
+func (r *Cluster) getExtraVolumeOpts() map[string]VolumeOpts {
+	volumes := make(map[string]VolumeOpts)
+	if opts := r.Spec.Persistence.Binlog; opts != nil {
+		volumes["binlog"] = *opts
+	}
+	if opts := r.Spec.Persistence.RedoLog; opts != nil {
+		volumes["redoLog"] = *opts
+	}
+	if opts := r.Spec.Persistence.Tmp; opts != nil {
+		volumes["tmp"] = *opts
+	}
+	return volumes
+}
+
+func (r *Cluster) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("Cluster").GroupKind(), r.Name, allErrs)
+}
+
+// getMysqlSemVer returns the semver of the mysql version or tag, it returns an
+// error if the version is not supported.
+func getMysqlSemVer(version string) (string, error) {
+	if v, ok := utils.MySQLTagsToSemVer[version]; ok {
+		version = v
+	}
+	if _, ok := utils.MysqlImageVersions[version]; !ok {
+		return "", fmt.Errorf("mysql version %s is not supported", version)
+	}
+	return version, nil
+}
+
+// supportedMysqlVersions returns the supported versions and tags.
+func supportedMysqlVersions() []string {
+	var versions []string
+	for tag := range utils.MySQLTagsToSemVer {
+		versions = append(versions, tag)
+	}
+	for version := range utils.MysqlImageVersions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
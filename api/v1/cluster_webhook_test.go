@@ -0,0 +1,418 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func newWebhookCluster() *Cluster {
+	c := &Cluster{}
+	c.Default()
+	return c
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+// errorFields returns the sorted fields of the errors.
+func errorFields(allErrs field.ErrorList) []string {
+	var fields []string
+	for _, err := range allErrs {
+		fields = append(fields, err.Field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestValidateSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Cluster)
+		want   []string
+	}{
+		{
+			name:   "defaults",
+			mutate: func(c *Cluster) {},
+		},
+		{
+			name: "supported version",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlVersion = "8.0.25"
+			},
+		},
+		{
+			name: "unsupported version",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlVersion = "5.6"
+			},
+			want: []string{"spec.mysqlVersion"},
+		},
+		{
+			name: "non-positive xenon options",
+			mutate: func(c *Cluster) {
+				c.Spec.XenonOpts.AdmitDefeatHearbeatCount = int32Ptr(0)
+				c.Spec.XenonOpts.ElectionTimeout = int32Ptr(-1)
+			},
+			want: []string{"spec.xenonOpts.admitDefeatHearbeatCount", "spec.xenonOpts.electionTimeout"},
+		},
+		{
+			name: "election timeout less than the heartbeat count",
+			mutate: func(c *Cluster) {
+				c.Spec.XenonOpts.AdmitDefeatHearbeatCount = int32Ptr(10)
+				c.Spec.XenonOpts.ElectionTimeout = int32Ptr(5)
+			},
+			want: []string{"spec.xenonOpts.electionTimeout"},
+		},
+		{
+			name: "invalid size",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Size = "10 GB"
+			},
+			want: []string{"spec.persistence.size"},
+		},
+		{
+			name: "passwords set together with the secrets",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlOpts.RootPassword = "RootPassw0rd"
+				c.Spec.MysqlOpts.RootPasswordSecretRef = &corev1.SecretKeySelector{Key: "root"}
+				c.Spec.MysqlOpts.Password = "Passw0rd"
+				c.Spec.MysqlOpts.UserPasswordSecretRef = &corev1.SecretKeySelector{Key: "user"}
+			},
+			want: []string{"spec.mysqlOpts.password", "spec.mysqlOpts.rootPassword"},
+		},
+		{
+			name: "passwords from the secrets",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlOpts.RootPassword = ""
+				c.Spec.MysqlOpts.RootPasswordSecretRef = &corev1.SecretKeySelector{Key: "root"}
+				c.Spec.MysqlOpts.Password = ""
+				c.Spec.MysqlOpts.UserPasswordSecretRef = &corev1.SecretKeySelector{Key: "user"}
+			},
+		},
+		{
+			name: "invalid binlog size",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Binlog = &VolumeOpts{Size: "large"}
+			},
+			want: []string{"spec.persistence.binlog.size"},
+		},
+		{
+			name: "read only threshold not above the warning threshold",
+			mutate: func(c *Cluster) {
+				c.Spec.DiskOpts.ReadOnlyThreshold = int32Ptr(80)
+			},
+			want: []string{"spec.diskOpts.readOnlyThreshold"},
+		},
+		{
+			name: "read only threshold above the warning threshold",
+			mutate: func(c *Cluster) {
+				c.Spec.DiskOpts.ReadOnlyThreshold = int32Ptr(95)
+			},
+		},
+		{
+			name: "reserved mysql variable",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlOpts.MysqlConf = MysqlConf{
+					"read-only":       intstr.FromInt(1),
+					"max_connections": intstr.FromInt(1024),
+				}
+			},
+			want: []string{"spec.mysqlOpts.mysqlConf[read-only]"},
+		},
+		{
+			name: "tls without a secret or an issuer",
+			mutate: func(c *Cluster) {
+				c.Spec.TLS = &TLSOpts{}
+			},
+			want: []string{"spec.tls"},
+		},
+		{
+			name: "tls with an issuer",
+			mutate: func(c *Cluster) {
+				c.Spec.TLS = &TLSOpts{IssuerRef: &IssuerReference{Name: "ca"}}
+			},
+		},
+		{
+			name: "reserved ssl variable",
+			mutate: func(c *Cluster) {
+				c.Spec.MysqlOpts.MysqlConf = MysqlConf{"ssl-ca": intstr.FromString("/tmp/ca.crt")}
+			},
+			want: []string{"spec.mysqlOpts.mysqlConf[ssl-ca]"},
+		},
+		{
+			name: "invalid max unavailable",
+			mutate: func(c *Cluster) {
+				max := intstr.FromString("half")
+				c.Spec.PodSpec.MaxUnavailable = &max
+			},
+			want: []string{"spec.podSpec.maxUnavailable"},
+		},
+		{
+			name: "negative max unavailable",
+			mutate: func(c *Cluster) {
+				max := intstr.FromInt(-1)
+				c.Spec.PodSpec.MaxUnavailable = &max
+			},
+			want: []string{"spec.podSpec.maxUnavailable"},
+		},
+		{
+			name: "percentage max unavailable",
+			mutate: func(c *Cluster) {
+				max := intstr.FromString("50%")
+				c.Spec.PodSpec.MaxUnavailable = &max
+			},
+		},
+		{
+			name: "backup then delete without the final backup",
+			mutate: func(c *Cluster) {
+				c.Spec.DeletionPolicy = ClusterBackupThenDelete
+			},
+			want: []string{"spec.finalBackup"},
+		},
+		{
+			name: "backup then delete with the final backup",
+			mutate: func(c *Cluster) {
+				c.Spec.DeletionPolicy = ClusterBackupThenDelete
+				c.Spec.FinalBackup = &BackupDestination{Endpoint: "http://minio:9000", Bucket: "backups"}
+			},
+		},
+		{
+			name: "invalid schedule",
+			mutate: func(c *Cluster) {
+				c.Spec.BackupSchedule = &BackupSchedule{Schedule: "every day"}
+			},
+			want: []string{"spec.backupSchedule.schedule"},
+		},
+		{
+			name: "valid schedule",
+			mutate: func(c *Cluster) {
+				c.Spec.BackupSchedule = &BackupSchedule{Schedule: "0 0 * * *"}
+			},
+		},
+		{
+			name: "empty restore source",
+			mutate: func(c *Cluster) {
+				c.Spec.RestoreFrom = &RestoreFrom{}
+			},
+			want: []string{"spec.restoreFrom"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newWebhookCluster()
+			tt.mutate(c)
+			if got := errorFields(c.validateSpec()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePasswords(t *testing.T) {
+	tests := []struct {
+		name string
+		// the passwords of the existing cluster, nil on create.
+		old          *MysqlOpts
+		rootPassword string
+		password     string
+		want         []string
+	}{
+		{
+			name:         "alpha-numeric passwords",
+			rootPassword: "RootPassw0rd",
+			password:     "Passw0rd",
+		},
+		{
+			name:         "non alpha-numeric passwords",
+			rootPassword: "pass'word",
+			password:     "pass word",
+			want:         []string{"spec.mysqlOpts.password", "spec.mysqlOpts.rootPassword"},
+		},
+		{
+			name:         "unchanged on update",
+			old:          &MysqlOpts{RootPassword: "Qing@123", Password: "Qing@123"},
+			rootPassword: "Qing@123",
+			password:     "Qing@123",
+		},
+		{
+			name:         "changed on update",
+			old:          &MysqlOpts{RootPassword: "Qing@123", Password: "Qing@123"},
+			rootPassword: "Qing@456",
+			password:     "Passw0rd",
+			want:         []string{"spec.mysqlOpts.rootPassword"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old *Cluster
+			if tt.old != nil {
+				old = newWebhookCluster()
+				old.Spec.MysqlOpts = *tt.old
+			}
+			c := newWebhookCluster()
+			c.Spec.MysqlOpts.RootPassword = tt.rootPassword
+			c.Spec.MysqlOpts.Password = tt.password
+			if got := errorFields(c.validatePasswords(old)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validatePasswords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePersistenceUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(c *Cluster)
+		want   []string
+	}{
+		{
+			name:   "unchanged",
+			mutate: func(c *Cluster) {},
+		},
+		{
+			name: "size increased",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Size = "20Gi"
+			},
+		},
+		{
+			name: "size decreased",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Size = "5Gi"
+			},
+			want: []string{"spec.persistence.size"},
+		},
+		{
+			name: "storage class changed",
+			mutate: func(c *Cluster) {
+				class := "fast"
+				c.Spec.Persistence.StorageClass = &class
+			},
+			want: []string{"spec.persistence.storageClass"},
+		},
+		{
+			name: "persistence disabled",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Enabled = !c.Spec.Persistence.Enabled
+			},
+			want: []string{"spec.persistence.enabled"},
+		},
+		{
+			name: "binlog size increased",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Binlog.Size = "20Gi"
+			},
+		},
+		{
+			name: "binlog size decreased",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Binlog.Size = "5Gi"
+			},
+			want: []string{"spec.persistence.binlog.size"},
+		},
+		{
+			name: "binlog storage class changed",
+			mutate: func(c *Cluster) {
+				class := "fast"
+				c.Spec.Persistence.Binlog.StorageClass = &class
+			},
+			want: []string{"spec.persistence.binlog.storageClass"},
+		},
+		{
+			name: "tmp volume added",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Tmp = &VolumeOpts{Size: "10Gi"}
+			},
+			want: []string{"spec.persistence.tmp"},
+		},
+		{
+			name: "binlog volume removed",
+			mutate: func(c *Cluster) {
+				c.Spec.Persistence.Binlog = nil
+			},
+			want: []string{"spec.persistence.binlog"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newWebhookCluster()
+			old.Spec.Persistence.Binlog = &VolumeOpts{Size: "10Gi"}
+			c := old.DeepCopy()
+			tt.mutate(c)
+			if got := errorFields(c.validatePersistenceUpdate(old)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validatePersistenceUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateVersionUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldVersion string
+		running    string
+		newVersion string
+		wantErr    bool
+	}{
+		{name: "unchanged tag", oldVersion: "5.7", newVersion: "5.7"},
+		{name: "tag to its version", oldVersion: "5.7", newVersion: "5.7.33"},
+		{name: "5.7 to 8.0", oldVersion: "5.7", newVersion: "8.0"},
+		{name: "8.0 to 5.7", oldVersion: "8.0", newVersion: "5.7", wantErr: true},
+		{
+			name:       "downgrade from the running version",
+			oldVersion: "5.7",
+			running:    "8.0.25",
+			newVersion: "5.7",
+			wantErr:    true,
+		},
+		{
+			name:       "upgrade from the running version",
+			oldVersion: "8.0",
+			running:    "5.7.33",
+			newVersion: "8.0",
+		},
+		{
+			name:       "unsupported version is left to validateSpec",
+			oldVersion: "8.0",
+			newVersion: "5.6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := newWebhookCluster()
+			old.Spec.MysqlVersion = tt.oldVersion
+			old.Status.MysqlVersion = tt.running
+			c := old.DeepCopy()
+			c.Spec.MysqlVersion = tt.newVersion
+			if allErrs := c.validateVersionUpdate(old); (len(allErrs) != 0) != tt.wantErr {
+				t.Errorf("validateVersionUpdate() = %v, wantErr %v", allErrs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DatabaseDeletionPolicy defines what happens to the database when the
+// MysqlDatabase is deleted.
+// +kubebuilder:validation:Enum=Retain;Delete
+type DatabaseDeletionPolicy string
+
+const (
+	// DatabaseRetain keeps the database in the cluster.
+	DatabaseRetain DatabaseDeletionPolicy = "Retain"
+	// DatabaseDelete drops the database from the cluster.
+	DatabaseDelete DatabaseDeletionPolicy = "Delete"
+)
+
+// MysqlDatabaseSpec defines the desired state of MysqlDatabase
+type MysqlDatabaseSpec struct {
+	// ClusterName is the name of the cluster in the same namespace.
+	ClusterName string `json:"clusterName"`
+
+	// Database is the name of the database. Changing it creates a new
+	// database and leaves the old one.
+	// +kubebuilder:validation:MaxLength=64
+	Database string `json:"database"`
+
+	// CharacterSet of the database.
+	// +optional
+	// +kubebuilder:default:="utf8mb4"
+	CharacterSet string `json:"characterSet,omitempty"`
+
+	// Collation of the database, the default collation of the character set
+	// is used if it is empty.
+	// +optional
+	Collation string `json:"collation,omitempty"`
+
+	// DeletionPolicy is what happens to the database when the MysqlDatabase
+	// is deleted, one of (\"Retain\", \"Delete\").
+	// +optional
+	// +kubebuilder:default:="Retain"
+	DeletionPolicy DatabaseDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+type MysqlDatabaseConditionType string
+
+const (
+	// MysqlDatabaseReady means the database has been created in the cluster.
+	MysqlDatabaseReady MysqlDatabaseConditionType = "Ready"
+)
+
+// MysqlDatabaseCondition defines type for database conditions.
+type MysqlDatabaseCondition struct {
+	// type of database condition, values in (\"Ready\")
+	Type MysqlDatabaseConditionType `json:"type"`
+	// Status of the condition, one of (\"True\", \"False\", \"Unknown\")
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason
+	Reason string `json:"reason,omitempty"`
+	// Message
+	Message string `json:"message,omitempty"`
+}
+
+// MysqlDatabaseStatus defines the observed state of MysqlDatabase
+type MysqlDatabaseStatus struct {
+	// ObservedGeneration is the generation of the last synced spec.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions contains the list of the database conditions fulfilled
+	Conditions []MysqlDatabaseCondition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="The name of the cluster"
+// +kubebuilder:printcolumn:name="Database",type="string",JSONPath=".spec.database",description="The name of the database"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type == 'Ready')].status",description="Whether the database is created"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// MysqlDatabase is the Schema for the mysqldatabases API
+type MysqlDatabase struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MysqlDatabaseSpec   `json:"spec,omitempty"`
+	Status MysqlDatabaseStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MysqlDatabaseList contains a list of MysqlDatabase
+type MysqlDatabaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MysqlDatabase `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MysqlDatabase{}, &MysqlDatabaseList{})
+}
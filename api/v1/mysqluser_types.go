@@ -0,0 +1,141 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MysqlUserSpec defines the desired state of MysqlUser
+type MysqlUserSpec struct {
+	// ClusterName is the name of the cluster in the same namespace.
+	ClusterName string `json:"clusterName"`
+
+	// User is the name of the account.
+	// +kubebuilder:validation:MaxLength=32
+	User string `json:"user"`
+
+	// Hosts are the hosts the user can connect from.
+	// +optional
+	// +kubebuilder:default:={"%"}
+	Hosts []string `json:"hosts,omitempty"`
+
+	// PasswordSecretRef is the key of the secret that contains the password.
+	PasswordSecretRef corev1.SecretKeySelector `json:"passwordSecretRef"`
+
+	// Permissions are the privileges granted to the user.
+	// +optional
+	Permissions []MysqlPermission `json:"permissions,omitempty"`
+
+	// ResourceLimits of the account.
+	// +optional
+	ResourceLimits *MysqlUserResourceLimits `json:"resourceLimits,omitempty"`
+}
+
+// MysqlPermission defines the privileges on the tables of a database.
+type MysqlPermission struct {
+	// Database is the name of the database, `*` for all the databases.
+	Database string `json:"database"`
+
+	// Tables are the names of the tables, `*` for all the tables.
+	// +optional
+	// +kubebuilder:default:={"*"}
+	Tables []string `json:"tables,omitempty"`
+
+	// Privileges to grant, eg: SELECT, INSERT, ALL PRIVILEGES.
+	// +kubebuilder:validation:MinItems=1
+	Privileges []string `json:"privileges"`
+}
+
+// MysqlUserResourceLimits defines the limits of the account, 0 means no limit.
+type MysqlUserResourceLimits struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxQueriesPerHour int32 `json:"maxQueriesPerHour,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxUpdatesPerHour int32 `json:"maxUpdatesPerHour,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConnectionsPerHour int32 `json:"maxConnectionsPerHour,omitempty"`
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxUserConnections int32 `json:"maxUserConnections,omitempty"`
+}
+
+type MysqlUserConditionType string
+
+const (
+	// MysqlUserReady means the account has been synced to the cluster.
+	MysqlUserReady MysqlUserConditionType = "Ready"
+)
+
+// MysqlUserCondition defines type for user conditions.
+type MysqlUserCondition struct {
+	// type of user condition, values in (\"Ready\")
+	Type MysqlUserConditionType `json:"type"`
+	// Status of the condition, one of (\"True\", \"False\", \"Unknown\")
+	Status corev1.ConditionStatus `json:"status"`
+
+	// LastTransitionTime
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// Reason
+	Reason string `json:"reason,omitempty"`
+	// Message
+	Message string `json:"message,omitempty"`
+}
+
+// MysqlUserStatus defines the observed state of MysqlUser
+type MysqlUserStatus struct {
+	// AllowedHosts are the hosts of the accounts created in the cluster.
+	AllowedHosts []string `json:"allowedHosts,omitempty"`
+
+	// ObservedGeneration is the generation of the last synced spec.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions contains the list of the user conditions fulfilled
+	Conditions []MysqlUserCondition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="The name of the cluster"
+// +kubebuilder:printcolumn:name="User",type="string",JSONPath=".spec.user",description="The name of the account"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type == 'Ready')].status",description="Whether the user is synced"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// MysqlUser is the Schema for the mysqlusers API
+type MysqlUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MysqlUserSpec   `json:"spec,omitempty"`
+	Status MysqlUserStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MysqlUserList contains a list of MysqlUser
+type MysqlUserList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MysqlUser `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MysqlUser{}, &MysqlUserList{})
+}
@@ -23,8 +23,187 @@ package v1
 import (
 	corev1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Backup) DeepCopyInto(out *Backup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Backup.
+func (in *Backup) DeepCopy() *Backup {
+	if in == nil {
+		return nil
+	}
+	out := new(Backup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Backup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupCondition) DeepCopyInto(out *BackupCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupCondition.
+func (in *BackupCondition) DeepCopy() *BackupCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupDestination) DeepCopyInto(out *BackupDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupDestination.
+func (in *BackupDestination) DeepCopy() *BackupDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupList) DeepCopyInto(out *BackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Backup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupList.
+func (in *BackupList) DeepCopy() *BackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSchedule) DeepCopyInto(out *BackupSchedule) {
+	*out = *in
+	out.Destination = in.Destination
+	if in.BackupsToKeep != nil {
+		in, out := &in.BackupsToKeep, &out.BackupsToKeep
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DaysToKeep != nil {
+		in, out := &in.DaysToKeep, &out.DaysToKeep
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSchedule.
+func (in *BackupSchedule) DeepCopy() *BackupSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
+	*out = *in
+	out.Destination = in.Destination
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
+func (in *BackupSpec) DeepCopy() *BackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]BackupCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStatus.
+func (in *BackupStatus) DeepCopy() *BackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BinlogArchive) DeepCopyInto(out *BinlogArchive) {
+	*out = *in
+	out.Destination = in.Destination
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BinlogArchive.
+func (in *BinlogArchive) DeepCopy() *BinlogArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(BinlogArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
@@ -113,6 +292,42 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	in.MetricsOpts.DeepCopyInto(&out.MetricsOpts)
 	in.PodSpec.DeepCopyInto(&out.PodSpec)
 	in.Persistence.DeepCopyInto(&out.Persistence)
+	in.DiskOpts.DeepCopyInto(&out.DiskOpts)
+	if in.BackupSchedule != nil {
+		in, out := &in.BackupSchedule, &out.BackupSchedule
+		*out = new(BackupSchedule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BinlogArchive != nil {
+		in, out := &in.BinlogArchive, &out.BinlogArchive
+		*out = new(BinlogArchive)
+		**out = **in
+	}
+	if in.RestoreFrom != nil {
+		in, out := &in.RestoreFrom, &out.RestoreFrom
+		*out = new(RestoreFrom)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpgradeBackup != nil {
+		in, out := &in.UpgradeBackup, &out.UpgradeBackup
+		*out = new(BackupDestination)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FinalBackup != nil {
+		in, out := &in.FinalBackup, &out.FinalBackup
+		*out = new(BackupDestination)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
@@ -142,6 +357,48 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastScheduledBackupTime != nil {
+		in, out := &in.LastScheduledBackupTime, &out.LastScheduledBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulBackupTime != nil {
+		in, out := &in.LastSuccessfulBackupTime, &out.LastSuccessfulBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Switchover != nil {
+		in, out := &in.Switchover, &out.Switchover
+		*out = new(SwitchoverStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialRotation != nil {
+		in, out := &in.CredentialRotation, &out.CredentialRotation
+		*out = new(CredentialRotationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastCredentialRotationTime != nil {
+		in, out := &in.LastCredentialRotationTime, &out.LastCredentialRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.MysqlConf != nil {
+		in, out := &in.MysqlConf, &out.MysqlConf
+		*out = new(MysqlConfStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
@@ -154,6 +411,84 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialRotationStatus) DeepCopyInto(out *CredentialRotationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialRotationStatus.
+func (in *CredentialRotationStatus) DeepCopy() *CredentialRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialRotationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskOpts) DeepCopyInto(out *DiskOpts) {
+	*out = *in
+	if in.ReadOnlyThreshold != nil {
+		in, out := &in.ReadOnlyThreshold, &out.ReadOnlyThreshold
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskOpts.
+func (in *DiskOpts) DeepCopy() *DiskOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskUsage) DeepCopyInto(out *DiskUsage) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeUsage, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskUsage.
+func (in *DiskUsage) DeepCopy() *DiskUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssuerReference) DeepCopyInto(out *IssuerReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssuerReference.
+func (in *IssuerReference) DeepCopy() *IssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(IssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsOpts) DeepCopyInto(out *MetricsOpts) {
 	*out = *in
@@ -192,79 +527,428 @@ func (in MysqlConf) DeepCopy() MysqlConf {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MysqlOpts) DeepCopyInto(out *MysqlOpts) {
+func (in *MysqlConfStatus) DeepCopyInto(out *MysqlConfStatus) {
 	*out = *in
-	if in.MysqlConf != nil {
-		in, out := &in.MysqlConf, &out.MysqlConf
-		*out = make(MysqlConf, len(*in))
+	if in.Static != nil {
+		in, out := &in.Static, &out.Static
+		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	in.Resources.DeepCopyInto(&out.Resources)
+	if in.PendingRestart != nil {
+		in, out := &in.PendingRestart, &out.PendingRestart
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReadOnly != nil {
+		in, out := &in.ReadOnly, &out.ReadOnly
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlOpts.
-func (in *MysqlOpts) DeepCopy() *MysqlOpts {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlConfStatus.
+func (in *MysqlConfStatus) DeepCopy() *MysqlConfStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(MysqlOpts)
+	out := new(MysqlConfStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NodeCondition) DeepCopyInto(out *NodeCondition) {
+func (in *MysqlDatabase) DeepCopyInto(out *MysqlDatabase) {
 	*out = *in
-	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCondition.
-func (in *NodeCondition) DeepCopy() *NodeCondition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabase.
+func (in *MysqlDatabase) DeepCopy() *MysqlDatabase {
 	if in == nil {
 		return nil
 	}
-	out := new(NodeCondition)
+	out := new(MysqlDatabase)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MysqlDatabase) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+func (in *MysqlDatabaseCondition) DeepCopyInto(out *MysqlDatabaseCondition) {
 	*out = *in
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]NodeCondition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
-func (in *NodeStatus) DeepCopy() *NodeStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseCondition.
+func (in *MysqlDatabaseCondition) DeepCopy() *MysqlDatabaseCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(NodeStatus)
+	out := new(MysqlDatabaseCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Persistence) DeepCopyInto(out *Persistence) {
+func (in *MysqlDatabaseList) DeepCopyInto(out *MysqlDatabaseList) {
 	*out = *in
-	if in.AccessModes != nil {
-		in, out := &in.AccessModes, &out.AccessModes
-		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
-		copy(*out, *in)
-	}
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MysqlDatabase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseList.
+func (in *MysqlDatabaseList) DeepCopy() *MysqlDatabaseList {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabaseList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MysqlDatabaseList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlDatabaseSpec) DeepCopyInto(out *MysqlDatabaseSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseSpec.
+func (in *MysqlDatabaseSpec) DeepCopy() *MysqlDatabaseSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabaseSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlDatabaseStatus) DeepCopyInto(out *MysqlDatabaseStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]MysqlDatabaseCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlDatabaseStatus.
+func (in *MysqlDatabaseStatus) DeepCopy() *MysqlDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlOpts) DeepCopyInto(out *MysqlOpts) {
+	*out = *in
+	if in.RootPasswordSecretRef != nil {
+		in, out := &in.RootPasswordSecretRef, &out.RootPasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UserPasswordSecretRef != nil {
+		in, out := &in.UserPasswordSecretRef, &out.UserPasswordSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MysqlConf != nil {
+		in, out := &in.MysqlConf, &out.MysqlConf
+		*out = make(MysqlConf, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlOpts.
+func (in *MysqlOpts) DeepCopy() *MysqlOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlPermission) DeepCopyInto(out *MysqlPermission) {
+	*out = *in
+	if in.Tables != nil {
+		in, out := &in.Tables, &out.Tables
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Privileges != nil {
+		in, out := &in.Privileges, &out.Privileges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlPermission.
+func (in *MysqlPermission) DeepCopy() *MysqlPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUser) DeepCopyInto(out *MysqlUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUser.
+func (in *MysqlUser) DeepCopy() *MysqlUser {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MysqlUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUserCondition) DeepCopyInto(out *MysqlUserCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUserCondition.
+func (in *MysqlUserCondition) DeepCopy() *MysqlUserCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUserCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUserList) DeepCopyInto(out *MysqlUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MysqlUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUserList.
+func (in *MysqlUserList) DeepCopy() *MysqlUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MysqlUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUserResourceLimits) DeepCopyInto(out *MysqlUserResourceLimits) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUserResourceLimits.
+func (in *MysqlUserResourceLimits) DeepCopy() *MysqlUserResourceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUserResourceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUserSpec) DeepCopyInto(out *MysqlUserSpec) {
+	*out = *in
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.PasswordSecretRef.DeepCopyInto(&out.PasswordSecretRef)
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]MysqlPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourceLimits != nil {
+		in, out := &in.ResourceLimits, &out.ResourceLimits
+		*out = new(MysqlUserResourceLimits)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUserSpec.
+func (in *MysqlUserSpec) DeepCopy() *MysqlUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MysqlUserStatus) DeepCopyInto(out *MysqlUserStatus) {
+	*out = *in
+	if in.AllowedHosts != nil {
+		in, out := &in.AllowedHosts, &out.AllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]MysqlUserCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MysqlUserStatus.
+func (in *MysqlUserStatus) DeepCopy() *MysqlUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MysqlUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeCondition) DeepCopyInto(out *NodeCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeCondition.
+func (in *NodeCondition) DeepCopy() *NodeCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]NodeCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DiskUsage != nil {
+		in, out := &in.DiskUsage, &out.DiskUsage
+		*out = new(DiskUsage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeStatus.
+func (in *NodeStatus) DeepCopy() *NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Persistence) DeepCopyInto(out *Persistence) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
 	if in.StorageClass != nil {
 		in, out := &in.StorageClass, &out.StorageClass
 		*out = new(string)
 		**out = **in
 	}
+	if in.Binlog != nil {
+		in, out := &in.Binlog, &out.Binlog
+		*out = new(VolumeOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RedoLog != nil {
+		in, out := &in.RedoLog, &out.RedoLog
+		*out = new(VolumeOpts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tmp != nil {
+		in, out := &in.Tmp, &out.Tmp
+		*out = new(VolumeOpts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Persistence.
@@ -299,6 +983,13 @@ func (in *PodSpec) DeepCopyInto(out *PodSpec) {
 		*out = new(corev1.Affinity)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Tolerations != nil {
 		in, out := &in.Tolerations, &out.Tolerations
 		*out = make([]corev1.Toleration, len(*in))
@@ -306,6 +997,11 @@ func (in *PodSpec) DeepCopyInto(out *PodSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 }
 
@@ -319,6 +1015,189 @@ func (in *PodSpec) DeepCopy() *PodSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyOpts) DeepCopyInto(out *ProxyOpts) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyOpts.
+func (in *ProxyOpts) DeepCopy() *ProxyOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreFrom) DeepCopyInto(out *RestoreFrom) {
+	*out = *in
+	if in.RestoreTime != nil {
+		in, out := &in.RestoreTime, &out.RestoreTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreFrom.
+func (in *RestoreFrom) DeepCopy() *RestoreFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSwitchoverFailureTime != nil {
+		in, out := &in.LastSwitchoverFailureTime, &out.LastSwitchoverFailureTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SwitchoverStatus) DeepCopyInto(out *SwitchoverStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SwitchoverStatus.
+func (in *SwitchoverStatus) DeepCopy() *SwitchoverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SwitchoverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSOpts) DeepCopyInto(out *TLSOpts) {
+	*out = *in
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(IssuerReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSOpts.
+func (in *TLSOpts) DeepCopy() *TLSOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RolloutTime != nil {
+		in, out := &in.RolloutTime, &out.RolloutTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeOpts) DeepCopyInto(out *VolumeOpts) {
+	*out = *in
+	if in.StorageClass != nil {
+		in, out := &in.StorageClass, &out.StorageClass
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeOpts.
+func (in *VolumeOpts) DeepCopy() *VolumeOpts {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeOpts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
+func (in *VolumeStatus) DeepCopy() *VolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeUsage) DeepCopyInto(out *VolumeUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeUsage.
+func (in *VolumeUsage) DeepCopy() *VolumeUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *XenonOpts) DeepCopyInto(out *XenonOpts) {
 	*out = *in
@@ -0,0 +1,134 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// ArtifactsFinalizer is the finalizer that ensures the remote artifacts of a
+// backup are deleted together with it.
+const ArtifactsFinalizer = "mysql.radondb.io/backup-artifacts"
+
+type Backup struct {
+	*apiv1.Backup
+}
+
+func New(b *apiv1.Backup) *Backup {
+	return &Backup{
+		Backup: b,
+	}
+}
+
+// Unwrap returns the api backup object
+func (b *Backup) Unwrap() *apiv1.Backup {
+	return b.Backup
+}
+
+// GetLabels returns the backup labels
+func (b *Backup) GetLabels() labels.Set {
+	return labels.Set{
+		"mysql.radondb.io/cluster":     b.Spec.ClusterName,
+		"mysql.radondb.io/backup":      b.Name,
+		"app.kubernetes.io/name":       "mysql-backup",
+		"app.kubernetes.io/managed-by": "mysql.radondb.io",
+	}
+}
+
+// GetNameForJob returns the name of the job that takes the backup.
+func (b *Backup) GetNameForJob() string {
+	return fmt.Sprintf("%s-backup", b.Name)
+}
+
+// GetNameForPurgeJob returns the name of the job that deletes the remote backup.
+func (b *Backup) GetNameForPurgeJob() string {
+	return fmt.Sprintf("%s-purge", b.Name)
+}
+
+// GetBackupName returns the name of the backup in the bucket.
+func (b *Backup) GetBackupName() string {
+	return fmt.Sprintf("%s/%s", b.Spec.ClusterName, b.Name)
+}
+
+// GetBackupURL returns the location of the backup.
+func (b *Backup) GetBackupURL() string {
+	return fmt.Sprintf("s3://%s/%s", b.Spec.Destination.Bucket, b.GetBackupName())
+}
+
+// GetBinlogURL returns the location of the binlogs archived by the backup's
+// cluster, if the binlog archive was enabled with the same bucket.
+func (b *Backup) GetBinlogURL() string {
+	return fmt.Sprintf("s3://%s/%s/%s", b.Spec.Destination.Bucket, b.Spec.ClusterName, utils.BinlogArchiveDir)
+}
+
+// IsSucceeded returns true if the backup has been completed successfully.
+func (b *Backup) IsSucceeded() bool {
+	if i, exist := b.condExists(apiv1.BackupComplete); exist {
+		return b.Status.Conditions[i].Status == corev1.ConditionTrue
+	}
+	return false
+}
+
+// UpdateStatusCondition sets the condition to a status.
+// for example Ready condition to True, or False
+func (b *Backup) UpdateStatusCondition(condType apiv1.BackupConditionType,
+	status corev1.ConditionStatus, reason, msg string) {
+	newCondition := apiv1.BackupCondition{
+		Type:    condType,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+
+	t := time.Now()
+
+	if len(b.Status.Conditions) == 0 {
+		newCondition.LastTransitionTime = metav1.NewTime(t)
+		b.Status.Conditions = []apiv1.BackupCondition{newCondition}
+	} else {
+		if i, exist := b.condExists(condType); exist {
+			cond := b.Status.Conditions[i]
+			if cond.Status != newCondition.Status {
+				newCondition.LastTransitionTime = metav1.NewTime(t)
+			} else {
+				newCondition.LastTransitionTime = cond.LastTransitionTime
+			}
+			b.Status.Conditions[i] = newCondition
+		} else {
+			newCondition.LastTransitionTime = metav1.NewTime(t)
+			b.Status.Conditions = append(b.Status.Conditions, newCondition)
+		}
+	}
+}
+
+func (b *Backup) condExists(ty apiv1.BackupConditionType) (int, bool) {
+	for i, cond := range b.Status.Conditions {
+		if cond.Type == ty {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
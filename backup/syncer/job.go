@@ -0,0 +1,225 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// log is for logging in this package.
+var log = logf.Log.WithName("backup.syncer")
+
+type jobSyncer struct {
+	cli client.Client
+
+	job     *batchv1.Job
+	cluster *cluster.Cluster
+	backup  *backup.Backup
+}
+
+// NewJobSyncer returns a syncer for the job that takes the backup.
+func NewJobSyncer(cli client.Client, c *cluster.Cluster, b *backup.Backup) syncer.Interface {
+	obj := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.GetNameForJob(),
+			Namespace: b.Namespace,
+		},
+	}
+
+	sync := &jobSyncer{
+		cli:     cli,
+		job:     obj,
+		cluster: c,
+		backup:  b,
+	}
+
+	return syncer.NewObjectSyncer("Job", b.Unwrap(), obj, cli, sync.SyncFn)
+}
+
+func (s *jobSyncer) SyncFn() error {
+	if s.backup.Status.Completed {
+		log.V(1).Info("backup already completed", "backup", s.backup.Name)
+		// skip doing anything
+		return syncer.ErrIgnore
+	}
+
+	// the job spec is immutable, only update the backup status.
+	if !s.job.CreationTimestamp.IsZero() {
+		s.updateStatus(s.job)
+		return nil
+	}
+
+	s.job.Labels = s.backup.GetLabels()
+
+	var backoffLimit int32
+	s.job.Spec.BackoffLimit = &backoffLimit
+	s.job.Spec.Template.Labels = s.backup.GetLabels()
+	s.job.Spec.Template.Spec = s.ensurePodSpec()
+	return nil
+}
+
+func (s *jobSyncer) ensurePodSpec() corev1.PodSpec {
+	sctName := s.cluster.GetNameForResource(utils.Secret)
+	host := fmt.Sprintf("%s.%s.%s", s.backup.Spec.HostName,
+		s.cluster.GetNameForResource(utils.HeadlessSVC), s.cluster.Namespace)
+
+	return corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		Containers: []corev1.Container{
+			{
+				Name:            utils.ContainerBackupName,
+				Image:           s.cluster.GetBackupImage(s.backup.Spec.Image),
+				ImagePullPolicy: s.cluster.Spec.PodSpec.ImagePullPolicy,
+				Command:         []string{"sidecar", "backup"},
+				Env: append([]corev1.EnvVar{
+					{
+						Name:  "BACKUP_HOST",
+						Value: host,
+					},
+					getEnvVarFromSecret(sctName, "BACKUP_USER", "backup-user"),
+					getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password"),
+				}, getStorageEnvVars(s.backup)...),
+				TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+			},
+		},
+	}
+}
+
+func (s *jobSyncer) updateStatus(job *batchv1.Job) {
+	s.backup.Status.BackupURL = s.backup.GetBackupURL()
+	s.backup.Status.StartTime = job.Status.StartTime
+	s.backup.Status.CompletionTime = job.Status.CompletionTime
+
+	// check for completion condition
+	if cond := jobCondition(batchv1.JobComplete, job); cond != nil {
+		s.backup.UpdateStatusCondition(apiv1.BackupComplete, cond.Status, cond.Reason, cond.Message)
+
+		if cond.Status == corev1.ConditionTrue {
+			s.backup.Status.Completed = true
+			if err := s.updateResult(job); err != nil {
+				log.Error(err, "failed to get the backup result", "backup", s.backup.Name)
+			}
+		}
+	}
+
+	// check for failed condition
+	if cond := jobCondition(batchv1.JobFailed, job); cond != nil {
+		s.backup.UpdateStatusCondition(apiv1.BackupFailed, cond.Status, cond.Reason, cond.Message)
+
+		if cond.Status == corev1.ConditionTrue {
+			s.backup.Status.Completed = true
+		}
+	}
+}
+
+// updateResult reads the backup result from the termination message of the job's pod.
+func (s *jobSyncer) updateResult(job *batchv1.Job) error {
+	pods := corev1.PodList{}
+	if err := s.cli.List(context.TODO(), &pods, &client.ListOptions{
+		Namespace:     job.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{"job-name": job.Name}),
+	}); err != nil {
+		return err
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != utils.ContainerBackupName || status.State.Terminated == nil ||
+				status.State.Terminated.ExitCode != 0 {
+				continue
+			}
+
+			result := utils.BackupResult{}
+			if err := json.Unmarshal([]byte(status.State.Terminated.Message), &result); err != nil {
+				return err
+			}
+			s.backup.Status.BackupSize = result.Size
+			s.backup.Status.GtidSet = result.GtidSet
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no succeeded pod found for job %s", job.Name)
+}
+
+func jobCondition(condType batchv1.JobConditionType, job *batchv1.Job) *batchv1.JobCondition {
+	for _, c := range job.Status.Conditions {
+		if c.Type == condType {
+			return &c
+		}
+	}
+
+	return nil
+}
+
+// getStorageEnvVars returns the env vars used by xbcloud to access the backup.
+func getStorageEnvVars(b *backup.Backup) []corev1.EnvVar {
+	dest := b.Spec.Destination
+	return []corev1.EnvVar{
+		{
+			Name:  "BACKUP_NAME",
+			Value: b.GetBackupName(),
+		},
+		{
+			Name:  "S3_ENDPOINT",
+			Value: dest.Endpoint,
+		},
+		{
+			Name:  "S3_REGION",
+			Value: dest.Region,
+		},
+		{
+			Name:  "S3_BUCKET",
+			Value: dest.Bucket,
+		},
+		getEnvVarFromSecret(dest.SecretName, "S3_ACCESS_KEY", "s3-access-key"),
+		getEnvVarFromSecret(dest.SecretName, "S3_SECRET_KEY", "s3-secret-key"),
+	}
+}
+
+func getEnvVarFromSecret(sctName, name, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: sctName,
+				},
+				Key: key,
+			},
+		},
+	}
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func newTestJobSyncer(objs ...corev1.Pod) *jobSyncer {
+	builder := fake.NewClientBuilder()
+	for i := range objs {
+		builder = builder.WithObjects(&objs[i])
+	}
+
+	c := cluster.New(&apiv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+	})
+	b := backup.New(&apiv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample-backup", Namespace: "default"},
+		Spec: apiv1.BackupSpec{
+			ClusterName: "sample",
+			HostName:    "sample-mysql-1",
+			Image:       "zhyass/sidecar:0.1",
+			Destination: apiv1.BackupDestination{
+				Endpoint:   "http://minio:9000",
+				Bucket:     "backups",
+				Region:     "us-east-1",
+				SecretName: "s3-secret",
+			},
+		},
+	})
+
+	return &jobSyncer{
+		cli:     builder.Build(),
+		job:     &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: b.GetNameForJob(), Namespace: b.Namespace}},
+		cluster: c,
+		backup:  b,
+	}
+}
+
+func TestJobSyncFn(t *testing.T) {
+	s := newTestJobSyncer()
+	if err := s.SyncFn(); err != nil {
+		t.Fatalf("SyncFn() error = %v", err)
+	}
+
+	if limit := s.job.Spec.BackoffLimit; limit == nil || *limit != 0 {
+		t.Errorf("backoffLimit = %v, want 0", limit)
+	}
+	spec := s.job.Spec.Template.Spec
+	if spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("restartPolicy = %s, want %s", spec.RestartPolicy, corev1.RestartPolicyNever)
+	}
+	if len(spec.Containers) != 1 {
+		t.Fatalf("got %d containers, want 1", len(spec.Containers))
+	}
+	container := spec.Containers[0]
+	if container.Image != "zhyass/sidecar:0.1" {
+		t.Errorf("image = %s, want zhyass/sidecar:0.1", container.Image)
+	}
+
+	env := make(map[string]corev1.EnvVar)
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	values := map[string]string{
+		"BACKUP_HOST": "sample-mysql-1.sample-mysql.default",
+		"BACKUP_NAME": "sample/sample-backup",
+		"S3_ENDPOINT": "http://minio:9000",
+		"S3_BUCKET":   "backups",
+		"S3_REGION":   "us-east-1",
+	}
+	for name, want := range values {
+		if got := env[name].Value; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	// the credentials are only read from the secrets.
+	secrets := map[string]string{
+		"BACKUP_USER":     "sample-secret",
+		"BACKUP_PASSWORD": "sample-secret",
+		"S3_ACCESS_KEY":   "s3-secret",
+		"S3_SECRET_KEY":   "s3-secret",
+	}
+	for name, want := range secrets {
+		e := env[name]
+		if len(e.Value) != 0 || e.ValueFrom == nil || e.ValueFrom.SecretKeyRef == nil {
+			t.Errorf("%s is not read from a secret", name)
+			continue
+		}
+		if got := e.ValueFrom.SecretKeyRef.Name; got != want {
+			t.Errorf("%s is read from the secret %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestJobUpdateStatus(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sample-backup-backup-x7k2p",
+			Namespace: "default",
+			Labels:    map[string]string{"job-name": "sample-backup-backup"},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name: utils.ContainerBackupName,
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Message: `{"size":1024,"gtidSet":"a8c0e06c-c8e0-11eb-9e6e-0242ac110002:1-7"}`,
+				}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		condition     batchv1.JobConditionType
+		status        corev1.ConditionStatus
+		wantCompleted bool
+		wantSize      int64
+		wantGtid      string
+		wantCondition apiv1.BackupConditionType
+	}{
+		{
+			name:          "running",
+			condition:     batchv1.JobComplete,
+			status:        corev1.ConditionFalse,
+			wantCondition: apiv1.BackupComplete,
+		},
+		{
+			name:          "complete",
+			condition:     batchv1.JobComplete,
+			status:        corev1.ConditionTrue,
+			wantCompleted: true,
+			wantSize:      1024,
+			wantGtid:      "a8c0e06c-c8e0-11eb-9e6e-0242ac110002:1-7",
+			wantCondition: apiv1.BackupComplete,
+		},
+		{
+			name:          "failed",
+			condition:     batchv1.JobFailed,
+			status:        corev1.ConditionTrue,
+			wantCompleted: true,
+			wantCondition: apiv1.BackupFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestJobSyncer(pod)
+			s.job.Status.Conditions = []batchv1.JobCondition{{Type: tt.condition, Status: tt.status}}
+			s.updateStatus(s.job)
+
+			status := s.backup.Status
+			if status.Completed != tt.wantCompleted {
+				t.Errorf("completed = %v, want %v", status.Completed, tt.wantCompleted)
+			}
+			if status.BackupURL != "s3://backups/sample/sample-backup" {
+				t.Errorf("backupURL = %s, want s3://backups/sample/sample-backup", status.BackupURL)
+			}
+			if status.BackupSize != tt.wantSize || status.GtidSet != tt.wantGtid {
+				t.Errorf("result = %d, %q, want %d, %q", status.BackupSize, status.GtidSet, tt.wantSize, tt.wantGtid)
+			}
+			if len(status.Conditions) != 1 || status.Conditions[0].Type != tt.wantCondition ||
+				status.Conditions[0].Status != tt.status {
+				t.Errorf("conditions = %v, want %s=%s", status.Conditions, tt.wantCondition, tt.status)
+			}
+		})
+	}
+}
+
+func TestIsJobFinished(t *testing.T) {
+	tests := []struct {
+		name          string
+		conditions    []batchv1.JobCondition
+		wantFinished  bool
+		wantSucceeded bool
+	}{
+		{
+			name: "running",
+		},
+		{
+			name:          "complete",
+			conditions:    []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+			wantFinished:  true,
+			wantSucceeded: true,
+		},
+		{
+			name:         "failed",
+			conditions:   []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}},
+			wantFinished: true,
+		},
+		{
+			name:       "not complete",
+			conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionFalse}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: tt.conditions}}
+			finished, succeeded := IsJobFinished(job)
+			if finished != tt.wantFinished || succeeded != tt.wantSucceeded {
+				t.Errorf("IsJobFinished() = %v, %v, want %v, %v", finished, succeeded, tt.wantFinished, tt.wantSucceeded)
+			}
+		})
+	}
+}
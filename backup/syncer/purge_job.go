@@ -0,0 +1,78 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zhyass/mysql-operator/backup"
+)
+
+// NewPurgeJobSyncer returns a syncer for the job that deletes the backup
+// from the storage with the image.
+func NewPurgeJobSyncer(cli client.Client, b *backup.Backup, image string) syncer.Interface {
+	obj := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      b.GetNameForPurgeJob(),
+			Namespace: b.Namespace,
+		},
+	}
+
+	return syncer.NewObjectSyncer("PurgeJob", b.Unwrap(), obj, cli, func() error {
+		// the job spec is immutable.
+		if !obj.CreationTimestamp.IsZero() {
+			return nil
+		}
+
+		obj.Labels = b.GetLabels()
+
+		backoffLimit := int32(3)
+		obj.Spec.BackoffLimit = &backoffLimit
+		obj.Spec.Template.Labels = b.GetLabels()
+		obj.Spec.Template.Spec = corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "purge",
+					Image:   image,
+					Command: []string{"sidecar", "purge"},
+					Env:     getStorageEnvVars(b),
+				},
+			},
+		}
+		return nil
+	})
+}
+
+// IsJobFinished returns whether the job has finished and whether it succeeded.
+func IsJobFinished(job *batchv1.Job) (finished bool, succeeded bool) {
+	if cond := jobCondition(batchv1.JobComplete, job); cond != nil && cond.Status == corev1.ConditionTrue {
+		return true, true
+	}
+	if cond := jobCondition(batchv1.JobFailed, job); cond != nil && cond.Status == corev1.ConditionTrue {
+		return true, false
+	}
+	return false, false
+}
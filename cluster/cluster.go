@@ -18,14 +18,13 @@ package cluster
 
 import (
 	"fmt"
-	"math"
 
+	"github.com/blang/semver"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	apiv1 "github.com/zhyass/mysql-operator/api/v1"
@@ -40,6 +39,13 @@ const (
 	gb
 )
 
+// DeletionFinalizer is the finalizer that retains the data or takes the final
+// backup before the cluster is deleted.
+const DeletionFinalizer = "mysql.radondb.io/cluster"
+
+// mysqlAutoUpgradeVersion is the first version that upgrades the data at startup.
+var mysqlAutoUpgradeVersion = semver.MustParse("8.0.16")
+
 type Cluster struct {
 	*apiv1.Cluster
 }
@@ -92,6 +98,25 @@ func (c *Cluster) GetSelectorLabels() labels.Set {
 	}
 }
 
+// GetProxyLabels returns the labels of the proxysql deployment.
+func (c *Cluster) GetProxyLabels() labels.Set {
+	labels := c.GetLabels()
+	labels["app.kubernetes.io/name"] = "proxysql"
+	labels["app.kubernetes.io/component"] = "proxy"
+	delete(labels, "app.kubernetes.io/version")
+	return labels
+}
+
+// GetProxySelectorLabels returns the labels selecting the proxysql pods, they
+// don't match the selector of the mysql pods.
+func (c *Cluster) GetProxySelectorLabels() labels.Set {
+	return labels.Set{
+		"mysql.radondb.io/cluster":     c.Name,
+		"app.kubernetes.io/name":       "proxysql",
+		"app.kubernetes.io/managed-by": "mysql.radondb.io",
+	}
+}
+
 // GetMySQLVersion returns the MySQL server version.
 func (c *Cluster) GetMySQLVersion() string {
 	version := c.Spec.MysqlVersion
@@ -107,6 +132,67 @@ func (c *Cluster) GetMySQLVersion() string {
 	return version
 }
 
+// GetMySQLSemVer returns the MySQL server version as semver.
+func (c *Cluster) GetMySQLSemVer() semver.Version {
+	return semver.MustParse(c.GetMySQLVersion())
+}
+
+// GetRunningMySQLVersion returns the mysql version all the pods run, which is
+// the target version until the cluster is upgraded.
+func (c *Cluster) GetRunningMySQLVersion() string {
+	if len(c.Status.MysqlVersion) != 0 {
+		return c.Status.MysqlVersion
+	}
+	return c.GetMySQLVersion()
+}
+
+// GetSidecarImage returns the sidecar image of the pods, which matches the
+// mysql version they run.
+func (c *Cluster) GetSidecarImage() string {
+	return utils.GetSidecarImage(c.Spec.PodSpec.SidecarImage, c.GetMySQLVersion())
+}
+
+// GetBackupImage returns the sidecar image of the backup jobs, which matches
+// the running mysql version.
+func (c *Cluster) GetBackupImage(image string) string {
+	return utils.GetSidecarImage(image, c.GetRunningMySQLVersion())
+}
+
+// GetUpgradingVersion returns the version the pods are being upgraded to, or
+// empty if there is none. The pods of the failed upgrade keep the new version,
+// the data upgraded by it may not be started by the former version.
+func (c *Cluster) GetUpgradingVersion() string {
+	up := c.Status.Upgrade
+	if up == nil {
+		return ""
+	}
+	if up.Phase == apiv1.UpgradeUpgrading || (up.Phase == apiv1.UpgradeFailed && up.RolloutTime != nil) {
+		return up.To
+	}
+	return ""
+}
+
+// IsTokuDBEnabled returns true if the tokudb engine should be installed, it is
+// ignored by mysql 8.0, which has no tokudb.
+func (c *Cluster) IsTokuDBEnabled() bool {
+	return c.Spec.MysqlOpts.InitTokuDB && c.GetMySQLSemVer().Major < 8
+}
+
+// NeedMysqlUpgrade returns true if the pod runs the mysql version being upgraded
+// to, but mysql_upgrade has not been run on it yet. mysql 8.0.16 and later
+// upgrade the data by the server itself.
+func (c *Cluster) NeedMysqlUpgrade(pod *corev1.Pod) bool {
+	up := c.Status.Upgrade
+	if up == nil || up.Phase != apiv1.UpgradeUpgrading {
+		return false
+	}
+	if semver.MustParse(up.To).GTE(mysqlAutoUpgradeVersion) {
+		return false
+	}
+	return pod.Labels["app.kubernetes.io/version"] == up.To &&
+		pod.Annotations[utils.UpgradedVersionAnnotation] != up.To
+}
+
 func (c *Cluster) CreatePeers() string {
 	str := ""
 	for i := 0; i < int(*c.Spec.Replicas); i++ {
@@ -135,7 +221,7 @@ func (c *Cluster) EnsureVolumes() []corev1.Volume {
 		})
 	}
 
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if c.IsTokuDBEnabled() {
 		volumes = append(volumes,
 			corev1.Volume{
 				Name: utils.SysVolumeName,
@@ -189,8 +275,25 @@ func (c *Cluster) EnsureVolumes() []corev1.Volume {
 				EmptyDir: &corev1.EmptyDirVolumeSource{},
 			},
 		},
+		corev1.Volume{
+			Name: utils.SidecarBinVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
 	)
 
+	if c.Spec.TLS != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: utils.TLSVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: c.GetTLSSecretName(),
+				},
+			},
+		})
+	}
+
 	return volumes
 }
 
@@ -199,15 +302,44 @@ func (c *Cluster) EnsureVolumeClaimTemplates(schema *runtime.Scheme) ([]corev1.P
 		return nil, nil
 	}
 
-	if c.Spec.Persistence.StorageClass != nil {
-		if *c.Spec.Persistence.StorageClass == "-" {
-			*c.Spec.Persistence.StorageClass = ""
+	claims := []corev1.PersistentVolumeClaim{
+		c.newVolumeClaimTemplate(utils.DataVolumeName, c.Spec.Persistence.Size,
+			getStorageClassName(c.Spec.Persistence.StorageClass)),
+	}
+	for _, volume := range c.GetExtraVolumes() {
+		storageClass := volume.StorageClass
+		if storageClass == nil {
+			storageClass = c.Spec.Persistence.StorageClass
+		}
+		claims = append(claims, c.newVolumeClaimTemplate(volume.Name, volume.Size, getStorageClassName(storageClass)))
+	}
+
+	for i := range claims {
+		if err := controllerutil.SetControllerReference(c.Cluster, &claims[i], schema); err != nil {
+			return nil, fmt.Errorf("failed setting controller reference: %v", err)
 		}
 	}
 
-	data := corev1.PersistentVolumeClaim{
+	return claims, nil
+}
+
+// getStorageClassName returns a copy of the storage class, "-" means the empty
+// one, which disables the dynamic provisioning. The spec is never modified.
+func getStorageClassName(storageClass *string) *string {
+	if storageClass == nil {
+		return nil
+	}
+	name := *storageClass
+	if name == "-" {
+		name = ""
+	}
+	return &name
+}
+
+func (c *Cluster) newVolumeClaimTemplate(name, size string, storageClass *string) corev1.PersistentVolumeClaim {
+	return corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      utils.DataVolumeName,
+			Name:      name,
 			Namespace: c.Namespace,
 			Labels:    c.GetLabels(),
 		},
@@ -215,18 +347,109 @@ func (c *Cluster) EnsureVolumeClaimTemplates(schema *runtime.Scheme) ([]corev1.P
 			AccessModes: c.Spec.Persistence.AccessModes,
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
-					corev1.ResourceStorage: resource.MustParse(c.Spec.Persistence.Size),
+					corev1.ResourceStorage: resource.MustParse(size),
 				},
 			},
-			StorageClassName: c.Spec.Persistence.StorageClass,
+			StorageClassName: storageClass,
 		},
 	}
+}
+
+// ExtraVolume is a separate volume of the mysql files declared by the persistence.
+type ExtraVolume struct {
+	apiv1.VolumeOpts
+	Name      string
+	MountPath string
+}
+
+// GetExtraVolumes returns the separate volumes of the binlogs, the redo logs
+// and the tmpdir, they are only supported with the persistence enabled.
+func (c *Cluster) GetExtraVolumes() []ExtraVolume {
+	if !c.Spec.Persistence.Enabled {
+		return nil
+	}
 
-	if err := controllerutil.SetControllerReference(c.Cluster, &data, schema); err != nil {
-		return nil, fmt.Errorf("failed setting controller reference: %v", err)
+	var volumes []ExtraVolume
+	if opts := c.Spec.Persistence.Binlog; opts != nil {
+		volumes = append(volumes, ExtraVolume{*opts, utils.BinlogVolumeName, utils.BinlogVolumeMountPath})
+	}
+	if opts := c.Spec.Persistence.RedoLog; opts != nil {
+		volumes = append(volumes, ExtraVolume{*opts, utils.RedoLogVolumeName, utils.RedoLogVolumeMountPath})
+	}
+	if opts := c.Spec.Persistence.Tmp; opts != nil {
+		volumes = append(volumes, ExtraVolume{*opts, utils.TmpVolumeName, utils.TmpVolumeMountPath})
 	}
+	return volumes
+}
 
-	return []corev1.PersistentVolumeClaim{data}, nil
+// GetExtraVolumeMounts returns the mounts of the separate volumes.
+func (c *Cluster) GetExtraVolumeMounts() []corev1.VolumeMount {
+	var mounts []corev1.VolumeMount
+	for _, volume := range c.GetExtraVolumes() {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: volume.MountPath,
+		})
+	}
+	return mounts
+}
+
+// GetAffinity returns the affinity of the pods, the default anti-affinity is
+// used if the affinity is not set.
+func (c *Cluster) GetAffinity() *corev1.Affinity {
+	if c.Spec.PodSpec.Affinity != nil {
+		return c.Spec.PodSpec.Affinity
+	}
+
+	selector := metav1.SetAsLabelSelector(c.GetSelectorLabels())
+	// the zones are always preferred, a required zone spreading can't be
+	// scheduled in the clusters with fewer zones than the replicas.
+	zoneTerm := corev1.WeightedPodAffinityTerm{
+		Weight: 50,
+		PodAffinityTerm: corev1.PodAffinityTerm{
+			LabelSelector: selector,
+			TopologyKey:   corev1.LabelTopologyZone,
+		},
+	}
+	nodeTerm := corev1.PodAffinityTerm{
+		LabelSelector: selector,
+		TopologyKey:   corev1.LabelHostname,
+	}
+
+	switch c.Spec.PodSpec.AntiAffinity {
+	case apiv1.AntiAffinityNone:
+		return nil
+	case apiv1.AntiAffinityHard:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution:  []corev1.PodAffinityTerm{nodeTerm},
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{zoneTerm},
+			},
+		}
+	default:
+		return &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{Weight: 100, PodAffinityTerm: nodeTerm},
+					zoneTerm,
+				},
+			},
+		}
+	}
+}
+
+// GetTopologySpreadConstraints returns the topology spread constraints of the
+// pods, the constraints without a labelSelector select the pods of the cluster.
+func (c *Cluster) GetTopologySpreadConstraints() []corev1.TopologySpreadConstraint {
+	var constraints []corev1.TopologySpreadConstraint
+	for _, constraint := range c.Spec.PodSpec.TopologySpreadConstraints {
+		constraint = *constraint.DeepCopy()
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = metav1.SetAsLabelSelector(c.GetSelectorLabels())
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
 }
 
 // GetNameForResource returns the name of a resource from above
@@ -240,36 +463,66 @@ func (c *Cluster) GetNameForResource(name utils.ResourceName) string {
 		return fmt.Sprintf("%s-follower", c.Name)
 	case utils.Secret:
 		return fmt.Sprintf("%s-secret", c.Name)
+	case utils.TLSSecret, utils.Certificate:
+		return fmt.Sprintf("%s-tls", c.Name)
+	case utils.ProxyDeployment, utils.ProxyService:
+		return fmt.Sprintf("%s-proxy", c.Name)
 	default:
 		return c.Name
 	}
 }
 
+// GetTLSSecretName returns the name of the secret that contains the certificate.
+func (c *Cluster) GetTLSSecretName() string {
+	if len(c.Spec.TLS.SecretName) != 0 {
+		return c.Spec.TLS.SecretName
+	}
+	return c.GetNameForResource(utils.TLSSecret)
+}
+
+// GetBinlogArchivePrefix returns the directory of the archived binlogs in the bucket.
+func (c *Cluster) GetBinlogArchivePrefix() string {
+	return fmt.Sprintf("%s/%s", c.Name, utils.BinlogArchiveDir)
+}
+
 func (c *Cluster) EnsureMysqlConf() {
 	if len(c.Spec.MysqlOpts.MysqlConf) == 0 {
 		c.Spec.MysqlOpts.MysqlConf = make(apiv1.MysqlConf)
 	}
 
-	var defaultSize, maxSize, innodbBufferPoolSize int64
-	innodbBufferPoolSize = 128 * mb
-	conf, ok := c.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_size"]
-	mem := c.Spec.MysqlOpts.Resources.Requests.Memory().Value()
-	cpu := c.Spec.PodSpec.Resources.Limits.Cpu().MilliValue()
-	if mem <= 1*gb {
-		defaultSize = int64(0.45 * float64(mem))
-		maxSize = int64(0.6 * float64(mem))
-	} else {
-		defaultSize = int64(0.6 * float64(mem))
-		maxSize = int64(0.8 * float64(mem))
+	c.tuneMysqlConf()
+}
+
+// GetStaticMysqlConf returns the static configs of the mysqlConf, which only
+// take effect after mysqld is restarted.
+func (c *Cluster) GetStaticMysqlConf() map[string]string {
+	c.EnsureMysqlConf()
+	conf := make(map[string]string)
+	for key, value := range c.Spec.MysqlOpts.MysqlConf {
+		if c.isStaticMysqlVariable(key) {
+			conf[key] = value.String()
+		}
 	}
+	return conf
+}
 
-	if !ok {
-		innodbBufferPoolSize = utils.Max(defaultSize, innodbBufferPoolSize)
-	} else {
-		innodbBufferPoolSize = utils.Min(utils.Max(int64(conf.IntVal), innodbBufferPoolSize), maxSize)
+// GetDynamicMysqlConf returns the configs of the mysqlConf that can be set at runtime.
+func (c *Cluster) GetDynamicMysqlConf() map[string]string {
+	c.EnsureMysqlConf()
+	conf := make(map[string]string)
+	for key, value := range c.Spec.MysqlOpts.MysqlConf {
+		if !c.isStaticMysqlVariable(key) {
+			conf[key] = value.String()
+		}
 	}
+	return conf
+}
 
-	instances := math.Max(math.Min(math.Ceil(float64(cpu)/float64(1000)), math.Floor(float64(innodbBufferPoolSize)/float64(gb))), 1)
-	c.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_size"] = intstr.FromInt(int(innodbBufferPoolSize))
-	c.Spec.MysqlOpts.MysqlConf["innodb_buffer_pool_instances"] = intstr.FromInt(int(instances))
+// isStaticMysqlVariable checks if the variable is known to be static, or has
+// been reported read-only by mysqld.
+func (c *Cluster) isStaticMysqlVariable(key string) bool {
+	if utils.IsStaticMysqlVariable(key) {
+		return true
+	}
+	return c.Status.MysqlConf != nil && utils.StringInSlice(key, c.Status.MysqlConf.ReadOnly)
 }
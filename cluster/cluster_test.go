@@ -0,0 +1,233 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+func TestGetAffinity(t *testing.T) {
+	custom := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+	tests := []struct {
+		name         string
+		affinity     *corev1.Affinity
+		antiAffinity apiv1.AntiAffinity
+		// the topology keys of the required and preferred terms.
+		wantRequired  []string
+		wantPreferred []string
+		wantNil       bool
+	}{
+		{
+			name:          "soft by default",
+			wantPreferred: []string{corev1.LabelHostname, corev1.LabelTopologyZone},
+		},
+		{
+			name:          "soft",
+			antiAffinity:  apiv1.AntiAffinitySoft,
+			wantPreferred: []string{corev1.LabelHostname, corev1.LabelTopologyZone},
+		},
+		{
+			name:          "hard",
+			antiAffinity:  apiv1.AntiAffinityHard,
+			wantRequired:  []string{corev1.LabelHostname},
+			wantPreferred: []string{corev1.LabelTopologyZone},
+		},
+		{
+			name:         "none",
+			antiAffinity: apiv1.AntiAffinityNone,
+			wantNil:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+			c.Spec.PodSpec.AntiAffinity = tt.antiAffinity
+			got := c.GetAffinity()
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("GetAffinity() = %v, want nil", got)
+				}
+				return
+			}
+
+			var required, preferred []string
+			for _, term := range got.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+				required = append(required, term.TopologyKey)
+				if !reflect.DeepEqual(term.LabelSelector, metav1.SetAsLabelSelector(c.GetSelectorLabels())) {
+					t.Errorf("selector = %v", term.LabelSelector)
+				}
+			}
+			for _, term := range got.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+				preferred = append(preferred, term.PodAffinityTerm.TopologyKey)
+			}
+			if !reflect.DeepEqual(required, tt.wantRequired) || !reflect.DeepEqual(preferred, tt.wantPreferred) {
+				t.Errorf("GetAffinity() = %v, %v, want %v, %v", required, preferred, tt.wantRequired, tt.wantPreferred)
+			}
+		})
+	}
+
+	t.Run("custom affinity", func(t *testing.T) {
+		c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+		c.Spec.PodSpec.Affinity = custom
+		c.Spec.PodSpec.AntiAffinity = apiv1.AntiAffinityHard
+		if got := c.GetAffinity(); got != custom {
+			t.Errorf("GetAffinity() = %v, want %v", got, custom)
+		}
+	})
+}
+
+func TestGetTopologySpreadConstraints(t *testing.T) {
+	c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+	own := metav1.SetAsLabelSelector(map[string]string{"app": "other"})
+	c.Spec.PodSpec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+		{MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule},
+		{MaxSkew: 2, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: own},
+	}
+
+	got := c.GetTopologySpreadConstraints()
+	if len(got) != 2 {
+		t.Fatalf("GetTopologySpreadConstraints() = %v, want 2 constraints", got)
+	}
+	if want := metav1.SetAsLabelSelector(c.GetSelectorLabels()); !reflect.DeepEqual(got[0].LabelSelector, want) {
+		t.Errorf("selector = %v, want %v", got[0].LabelSelector, want)
+	}
+	if !reflect.DeepEqual(got[1].LabelSelector, own) {
+		t.Errorf("selector = %v, want %v", got[1].LabelSelector, own)
+	}
+	if c.Spec.PodSpec.TopologySpreadConstraints[0].LabelSelector != nil {
+		t.Errorf("the spec is modified")
+	}
+}
+
+func TestEnsureVolumeClaimTemplates(t *testing.T) {
+	fast, none := "fast", "-"
+	c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+	c.Spec.Persistence = apiv1.Persistence{
+		Enabled:      true,
+		Size:         "20Gi",
+		StorageClass: &fast,
+		Binlog:       &apiv1.VolumeOpts{Size: "10Gi"},
+		Tmp:          &apiv1.VolumeOpts{Size: "5Gi", StorageClass: &none},
+	}
+
+	scheme := runtime.NewScheme()
+	_ = apiv1.AddToScheme(scheme)
+	claims, err := c.EnsureVolumeClaimTemplates(scheme)
+	if err != nil {
+		t.Fatalf("EnsureVolumeClaimTemplates() error = %v", err)
+	}
+
+	want := []struct {
+		name         string
+		size         string
+		storageClass string
+	}{
+		{"data", "20Gi", "fast"},
+		{"binlog", "10Gi", "fast"},
+		{"tmp", "5Gi", ""},
+	}
+	if len(claims) != len(want) {
+		t.Fatalf("EnsureVolumeClaimTemplates() = %d claims, want %d", len(claims), len(want))
+	}
+	for i, w := range want {
+		claim := claims[i]
+		size := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if claim.Name != w.name || size.String() != w.size || *claim.Spec.StorageClassName != w.storageClass {
+			t.Errorf("claim %d = %s, %s, %s, want %s, %s, %s", i, claim.Name, size.String(),
+				*claim.Spec.StorageClassName, w.name, w.size, w.storageClass)
+		}
+	}
+	// the spec is not modified, the claims are built again on every pass.
+	if *c.Spec.Persistence.Tmp.StorageClass != "-" {
+		t.Errorf("the storage class of the spec = %q, want -", *c.Spec.Persistence.Tmp.StorageClass)
+	}
+}
+
+func TestGetMysqlConfReadOnly(t *testing.T) {
+	c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+	c.Spec.MysqlOpts.MysqlConf = apiv1.MysqlConf{
+		"innodb_log_file_size": intstr.FromString("1073741824"),
+		"max_connections":      intstr.FromInt(1024),
+		"binlog_row_image":     intstr.FromString("minimal"),
+	}
+	c.Status.MysqlConf = &apiv1.MysqlConfStatus{ReadOnly: []string{"binlog_row_image"}}
+
+	static, dynamic := c.GetStaticMysqlConf(), c.GetDynamicMysqlConf()
+	for _, key := range []string{"innodb_log_file_size", "binlog_row_image"} {
+		if _, ok := static[key]; !ok {
+			t.Errorf("GetStaticMysqlConf() does not contain %s", key)
+		}
+		if _, ok := dynamic[key]; ok {
+			t.Errorf("GetDynamicMysqlConf() contains %s", key)
+		}
+	}
+	if dynamic["max_connections"] != "1024" {
+		t.Errorf("GetDynamicMysqlConf() max_connections = %q, want 1024", dynamic["max_connections"])
+	}
+}
+
+func TestGetUpgradingVersion(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name    string
+		upgrade *apiv1.UpgradeStatus
+		want    string
+	}{
+		{name: "no upgrade"},
+		{
+			name:    "backing up",
+			upgrade: &apiv1.UpgradeStatus{To: "8.0.25", Phase: apiv1.UpgradeBackingUp},
+		},
+		{
+			name:    "upgrading",
+			upgrade: &apiv1.UpgradeStatus{To: "8.0.25", Phase: apiv1.UpgradeUpgrading, RolloutTime: &now},
+			want:    "8.0.25",
+		},
+		{
+			name:    "failed before the rollout",
+			upgrade: &apiv1.UpgradeStatus{To: "8.0.25", Phase: apiv1.UpgradeFailed},
+		},
+		{
+			name:    "failed during the rollout",
+			upgrade: &apiv1.UpgradeStatus{To: "8.0.25", Phase: apiv1.UpgradeFailed, RolloutTime: &now},
+			want:    "8.0.25",
+		},
+		{
+			name:    "succeeded",
+			upgrade: &apiv1.UpgradeStatus{To: "8.0.25", Phase: apiv1.UpgradeSucceeded, RolloutTime: &now},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(&apiv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "sample"}})
+			c.Status.Upgrade = tt.upgrade
+			if got := c.GetUpgradingVersion(); got != tt.want {
+				t.Errorf("GetUpgradingVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+type backup struct {
+	*cluster.Cluster
+
+	name string
+}
+
+func (c *backup) getName() string {
+	return c.name
+}
+
+func (c *backup) getImage() string {
+	return c.GetSidecarImage()
+}
+
+func (c *backup) getCommand() []string {
+	return []string{"sidecar", "http"}
+}
+
+func (c *backup) getEnvVars() []corev1.EnvVar {
+	sctName := c.GetNameForResource(utils.Secret)
+	return []corev1.EnvVar{
+		getEnvVarFromSecret(sctName, "MYSQL_ROOT_PASSWORD", "root-password", false),
+		getEnvVarFromSecret(sctName, "BACKUP_USER", "backup-user", true),
+		getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password", true),
+	}
+}
+
+func (c *backup) getLifecycle() *corev1.Lifecycle {
+	return nil
+}
+
+func (c *backup) getResources() corev1.ResourceRequirements {
+	return c.Spec.PodSpec.Resources
+}
+
+func (c *backup) getPorts() []corev1.ContainerPort {
+	return []corev1.ContainerPort{
+		{
+			Name:          utils.SidecarHTTPPortName,
+			ContainerPort: utils.SidecarHTTPPort,
+		},
+	}
+}
+
+func (c *backup) getLivenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: utils.HealthPath,
+				Port: intstr.FromInt(utils.SidecarHTTPPort),
+			},
+		},
+		InitialDelaySeconds: 30,
+		TimeoutSeconds:      5,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
+func (c *backup) getReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: utils.ReadyPath,
+				Port: intstr.FromInt(utils.SidecarHTTPPort),
+			},
+		},
+		InitialDelaySeconds: 10,
+		TimeoutSeconds:      1,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
+func (c *backup) getVolumeMounts() []corev1.VolumeMount {
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      utils.ConfVolumeName,
+			MountPath: utils.ConfVolumeMountPath,
+		},
+		{
+			Name:      utils.DataVolumeName,
+			MountPath: utils.DataVolumeMountPath,
+		},
+		{
+			Name:      utils.LogsVolumeName,
+			MountPath: utils.LogsVolumeMountPath,
+		},
+	}
+	return append(mounts, c.GetExtraVolumeMounts()...)
+}
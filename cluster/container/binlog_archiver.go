@@ -0,0 +1,115 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+type binlogArchiver struct {
+	*cluster.Cluster
+
+	name string
+}
+
+func (c *binlogArchiver) getName() string {
+	return c.name
+}
+
+func (c *binlogArchiver) getImage() string {
+	return c.GetSidecarImage()
+}
+
+func (c *binlogArchiver) getCommand() []string {
+	return []string{"sidecar", "archive"}
+}
+
+func (c *binlogArchiver) getEnvVars() []corev1.EnvVar {
+	sctName := c.GetNameForResource(utils.Secret)
+	dest := c.Spec.BinlogArchive.Destination
+	return []corev1.EnvVar{
+		{
+			Name: "POD_HOSTNAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "metadata.name",
+				},
+			},
+		},
+		{
+			Name:  "ARCHIVE_PREFIX",
+			Value: c.GetBinlogArchivePrefix(),
+		},
+		{
+			Name:  "S3_ENDPOINT",
+			Value: dest.Endpoint,
+		},
+		{
+			Name:  "S3_REGION",
+			Value: dest.Region,
+		},
+		{
+			Name:  "S3_BUCKET",
+			Value: dest.Bucket,
+		},
+		getEnvVarFromSecret(sctName, "MYSQL_ROOT_PASSWORD", "root-password", false),
+		getEnvVarFromSecret(dest.SecretName, "S3_ACCESS_KEY", "s3-access-key", false),
+		getEnvVarFromSecret(dest.SecretName, "S3_SECRET_KEY", "s3-secret-key", false),
+	}
+}
+
+func (c *binlogArchiver) getLifecycle() *corev1.Lifecycle {
+	return nil
+}
+
+func (c *binlogArchiver) getResources() corev1.ResourceRequirements {
+	return c.Spec.PodSpec.Resources
+}
+
+func (c *binlogArchiver) getPorts() []corev1.ContainerPort {
+	return nil
+}
+
+func (c *binlogArchiver) getLivenessProbe() *corev1.Probe {
+	return nil
+}
+
+func (c *binlogArchiver) getReadinessProbe() *corev1.Probe {
+	return nil
+}
+
+func (c *binlogArchiver) getVolumeMounts() []corev1.VolumeMount {
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      utils.DataVolumeName,
+			MountPath: utils.DataVolumeMountPath,
+			ReadOnly:  true,
+		},
+	}
+	if c.Spec.Persistence.Binlog != nil && c.Spec.Persistence.Enabled {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      utils.BinlogVolumeName,
+			MountPath: utils.BinlogVolumeMountPath,
+			ReadOnly:  true,
+		})
+	}
+	return mounts
+}
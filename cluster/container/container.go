@@ -43,6 +43,8 @@ func EnsureContainer(name string, c *cluster.Cluster) corev1.Container {
 		ctr = &initSidecar{c, name}
 	case utils.ContainerInitMysqlName:
 		ctr = &initMysql{c, name}
+	case utils.ContainerInitCloneName:
+		ctr = &initClone{c, name}
 	case utils.ContainerMysqlName:
 		ctr = &mysql{c, name}
 	case utils.ContainerXenonName:
@@ -53,6 +55,12 @@ func EnsureContainer(name string, c *cluster.Cluster) corev1.Container {
 		ctr = &slowLog{c, name}
 	case utils.ContainerAuditLogName:
 		ctr = &auditLog{c, name}
+	case utils.ContainerBackupName:
+		ctr = &backup{c, name}
+	case utils.ContainerSidecarName:
+		ctr = &sidecar{c, name}
+	case utils.ContainerBinlogArchiverName:
+		ctr = &binlogArchiver{c, name}
 	}
 
 	return corev1.Container{
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+type initClone struct {
+	*cluster.Cluster
+
+	name string
+}
+
+func (c *initClone) getName() string {
+	return c.name
+}
+
+func (c *initClone) getImage() string {
+	return c.GetSidecarImage()
+}
+
+func (c *initClone) getCommand() []string {
+	return []string{"sidecar", "clone"}
+}
+
+func (c *initClone) getEnvVars() []corev1.EnvVar {
+	sctName := c.GetNameForResource(utils.Secret)
+	return []corev1.EnvVar{
+		{
+			Name: "POD_HOSTNAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "metadata.name",
+				},
+			},
+		},
+		{
+			Name:  "NAMESPACE",
+			Value: c.Namespace,
+		},
+		{
+			Name:  "SERVICE_NAME",
+			Value: c.GetNameForResource(utils.HeadlessSVC),
+		},
+		{
+			Name:  "LEADER_SERVICE_NAME",
+			Value: c.GetNameForResource(utils.LeaderService),
+		},
+		{
+			Name:  "FOLLOWER_SERVICE_NAME",
+			Value: c.GetNameForResource(utils.FollowerService),
+		},
+		getEnvVarFromSecret(sctName, "MYSQL_ROOT_PASSWORD", "root-password", false),
+		getEnvVarFromSecret(sctName, "MYSQL_REPL_USER", "replication-user", true),
+		getEnvVarFromSecret(sctName, "MYSQL_REPL_PASSWORD", "replication-password", true),
+		getEnvVarFromSecret(sctName, "METRICS_USER", "metrics-user", true),
+		getEnvVarFromSecret(sctName, "METRICS_PASSWORD", "metrics-password", true),
+		getEnvVarFromSecret(sctName, "OPERATOR_USER", "operator-user", true),
+		getEnvVarFromSecret(sctName, "OPERATOR_PASSWORD", "operator-password", true),
+		getEnvVarFromSecret(sctName, "BACKUP_USER", "backup-user", true),
+		getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password", true),
+	}
+}
+
+func (c *initClone) getLifecycle() *corev1.Lifecycle {
+	return nil
+}
+
+func (c *initClone) getResources() corev1.ResourceRequirements {
+	return c.Spec.PodSpec.Resources
+}
+
+func (c *initClone) getPorts() []corev1.ContainerPort {
+	return nil
+}
+
+func (c *initClone) getLivenessProbe() *corev1.Probe {
+	return nil
+}
+
+func (c *initClone) getReadinessProbe() *corev1.Probe {
+	return nil
+}
+
+func (c *initClone) getVolumeMounts() []corev1.VolumeMount {
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      utils.ConfVolumeName,
+			MountPath: utils.ConfVolumeMountPath,
+		},
+		{
+			Name:      utils.DataVolumeName,
+			MountPath: utils.DataVolumeMountPath,
+		},
+	}
+	// the redo logs of the prepared backup are moved to their volume.
+	return append(mounts, c.GetExtraVolumeMounts()...)
+}
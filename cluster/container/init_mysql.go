@@ -67,7 +67,7 @@ func (c *initMysql) getEnvVars() []corev1.EnvVar {
 		getEnvVarFromSecret(sctName, "MYSQL_PASSWORD", "mysql-password", true),
 	)
 
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if c.IsTokuDBEnabled() {
 		envs = append(envs, corev1.EnvVar{
 			Name:  "INIT_TOKUDB",
 			Value: "1",
@@ -98,7 +98,7 @@ func (c *initMysql) getReadinessProbe() *corev1.Probe {
 }
 
 func (c *initMysql) getVolumeMounts() []corev1.VolumeMount {
-	return []corev1.VolumeMount{
+	mounts := []corev1.VolumeMount{
 		{
 			Name:      utils.ConfVolumeName,
 			MountPath: utils.ConfVolumeMountPath,
@@ -116,4 +116,5 @@ func (c *initMysql) getVolumeMounts() []corev1.VolumeMount {
 			MountPath: utils.InitFileVolumeMountPath,
 		},
 	}
+	return append(mounts, c.GetExtraVolumeMounts()...)
 }
@@ -18,6 +18,7 @@ package container
 
 import (
 	"strconv"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 
@@ -36,7 +37,7 @@ func (c *initSidecar) getName() string {
 }
 
 func (c *initSidecar) getImage() string {
-	return c.Spec.PodSpec.SidecarImage
+	return c.GetSidecarImage()
 }
 
 func (c *initSidecar) getCommand() []string {
@@ -72,7 +73,7 @@ func (c *initSidecar) getEnvVars() []corev1.EnvVar {
 			Value: strconv.Itoa(int(*c.Spec.XenonOpts.ElectionTimeout)),
 		},
 		{
-			Name:  "MY_MYSQL_VERSION",
+			Name:  "MYSQL_VERSION",
 			Value: c.GetMySQLVersion(),
 		},
 		getEnvVarFromSecret(sctName, "MYSQL_ROOT_PASSWORD", "root-password", false),
@@ -80,9 +81,52 @@ func (c *initSidecar) getEnvVars() []corev1.EnvVar {
 		getEnvVarFromSecret(sctName, "MYSQL_REPL_PASSWORD", "replication-password", true),
 		getEnvVarFromSecret(sctName, "METRICS_USER", "metrics-user", true),
 		getEnvVarFromSecret(sctName, "METRICS_PASSWORD", "metrics-password", true),
+		getEnvVarFromSecret(sctName, "OPERATOR_USER", "operator-user", true),
+		getEnvVarFromSecret(sctName, "OPERATOR_PASSWORD", "operator-password", true),
 	}
 
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if restore := c.Spec.RestoreFrom; restore != nil && len(restore.BackupURL) > 0 {
+		envs = append(envs,
+			corev1.EnvVar{
+				Name:  "RESTORE_FROM",
+				Value: restore.BackupURL,
+			},
+			corev1.EnvVar{
+				Name:  "S3_ENDPOINT",
+				Value: restore.Endpoint,
+			},
+			corev1.EnvVar{
+				Name:  "S3_REGION",
+				Value: restore.Region,
+			},
+		)
+		if len(restore.BinlogURL) > 0 {
+			envs = append(envs, corev1.EnvVar{
+				Name:  "RESTORE_BINLOG_URL",
+				Value: restore.BinlogURL,
+			})
+			if restore.RestoreTime != nil {
+				envs = append(envs, corev1.EnvVar{
+					Name:  "RESTORE_TIME",
+					Value: restore.RestoreTime.UTC().Format(time.RFC3339),
+				})
+			}
+			if len(restore.RestoreGtid) > 0 {
+				envs = append(envs, corev1.EnvVar{
+					Name:  "RESTORE_GTID",
+					Value: restore.RestoreGtid,
+				})
+			}
+		}
+		if len(restore.SecretName) > 0 {
+			envs = append(envs,
+				getEnvVarFromSecret(restore.SecretName, "S3_ACCESS_KEY", "s3-access-key", false),
+				getEnvVarFromSecret(restore.SecretName, "S3_SECRET_KEY", "s3-secret-key", false),
+			)
+		}
+	}
+
+	if c.IsTokuDBEnabled() {
 		envs = append(envs, corev1.EnvVar{
 			Name:  "INIT_TOKUDB",
 			Value: "1",
@@ -134,9 +178,19 @@ func (c *initSidecar) getVolumeMounts() []corev1.VolumeMount {
 			Name:      utils.InitFileVolumeName,
 			MountPath: utils.InitFileVolumeMountPath,
 		},
+		{
+			Name:      utils.DataVolumeName,
+			MountPath: utils.DataVolumeMountPath,
+		},
+		{
+			Name:      utils.SidecarBinVolumeName,
+			MountPath: utils.SidecarBinVolumeMountPath,
+		},
 	}
+	// the separate volumes are chowned by the sidecar.
+	volumeMounts = append(volumeMounts, c.GetExtraVolumeMounts()...)
 
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if c.IsTokuDBEnabled() {
 		volumeMounts = append(volumeMounts,
 			corev1.VolumeMount{
 				Name:      utils.SysVolumeName,
@@ -145,14 +199,5 @@ func (c *initSidecar) getVolumeMounts() []corev1.VolumeMount {
 		)
 	}
 
-	if c.Spec.Persistence.Enabled {
-		volumeMounts = append(volumeMounts,
-			corev1.VolumeMount{
-				Name:      utils.DataVolumeName,
-				MountPath: utils.DataVolumeMountPath,
-			},
-		)
-	}
-
 	return volumeMounts
 }
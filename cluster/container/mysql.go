@@ -43,7 +43,7 @@ func (c *mysql) getCommand() []string {
 }
 
 func (c *mysql) getEnvVars() []corev1.EnvVar {
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if c.IsTokuDBEnabled() {
 		return []corev1.EnvVar{
 			{
 				Name:  "INIT_TOKUDB",
@@ -103,7 +103,7 @@ func (c *mysql) getReadinessProbe() *corev1.Probe {
 }
 
 func (c *mysql) getVolumeMounts() []corev1.VolumeMount {
-	return []corev1.VolumeMount{
+	mounts := []corev1.VolumeMount{
 		{
 			Name:      utils.ConfVolumeName,
 			MountPath: utils.ConfVolumeMountPath,
@@ -117,4 +117,14 @@ func (c *mysql) getVolumeMounts() []corev1.VolumeMount {
 			MountPath: utils.LogsVolumeMountPath,
 		},
 	}
+	mounts = append(mounts, c.GetExtraVolumeMounts()...)
+
+	if c.Spec.TLS != nil {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      utils.TLSVolumeName,
+			MountPath: utils.TLSVolumeMountPath,
+			ReadOnly:  true,
+		})
+	}
+	return mounts
 }
@@ -0,0 +1,122 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package container
+
+import (
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// sidecar serves the status of the node to the operator. It runs the sidecar
+// binary copied by init-sidecar in the xenon image, which ships xenoncli.
+type sidecar struct {
+	*cluster.Cluster
+
+	name string
+}
+
+func (c *sidecar) getName() string {
+	return c.name
+}
+
+func (c *sidecar) getImage() string {
+	return c.Spec.XenonOpts.Image
+}
+
+func (c *sidecar) getCommand() []string {
+	return []string{path.Join(utils.SidecarBinVolumeMountPath, "sidecar"), "serve"}
+}
+
+func (c *sidecar) getEnvVars() []corev1.EnvVar {
+	sctName := c.GetNameForResource(utils.Secret)
+	return []corev1.EnvVar{
+		// the root password may be rotated, the operator user is used instead.
+		getEnvVarFromSecret(sctName, "OPERATOR_USER", "operator-user", true),
+		getEnvVarFromSecret(sctName, "OPERATOR_PASSWORD", "operator-password", true),
+		getEnvVarFromSecret(sctName, "BACKUP_USER", "backup-user", true),
+		getEnvVarFromSecret(sctName, "BACKUP_PASSWORD", "backup-password", true),
+	}
+}
+
+func (c *sidecar) getLifecycle() *corev1.Lifecycle {
+	return nil
+}
+
+func (c *sidecar) getResources() corev1.ResourceRequirements {
+	return c.Spec.PodSpec.Resources
+}
+
+func (c *sidecar) getPorts() []corev1.ContainerPort {
+	return []corev1.ContainerPort{
+		{
+			Name:          utils.SidecarAPIPortName,
+			ContainerPort: utils.SidecarAPIPort,
+		},
+	}
+}
+
+func (c *sidecar) getLivenessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: utils.HealthPath,
+				Port: intstr.FromInt(utils.SidecarAPIPort),
+			},
+		},
+		InitialDelaySeconds: 30,
+		TimeoutSeconds:      5,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
+func (c *sidecar) getReadinessProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path: utils.HealthPath,
+				Port: intstr.FromInt(utils.SidecarAPIPort),
+			},
+		},
+		InitialDelaySeconds: 10,
+		TimeoutSeconds:      1,
+		PeriodSeconds:       10,
+		SuccessThreshold:    1,
+		FailureThreshold:    3,
+	}
+}
+
+func (c *sidecar) getVolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			// xenoncli reads the endpoint of xenon from xenon.json.
+			Name:      utils.XenonVolumeName,
+			MountPath: utils.XenonVolumeMountPath,
+		},
+		{
+			Name:      utils.SidecarBinVolumeName,
+			MountPath: utils.SidecarBinVolumeMountPath,
+			ReadOnly:  true,
+		},
+	}
+}
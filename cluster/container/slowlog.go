@@ -34,7 +34,7 @@ func (c *slowLog) getName() string {
 }
 
 func (c *slowLog) getImage() string {
-	return c.Spec.PodSpec.SidecarImage
+	return c.GetSidecarImage()
 }
 
 func (c *slowLog) getCommand() []string {
@@ -0,0 +1,76 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"fmt"
+
+	"github.com/presslabs/controller-util/syncer"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// certificateGVK is the cert-manager Certificate, which is built as unstructured
+// to avoid depending on cert-manager.
+var certificateGVK = schema.GroupVersionKind{
+	Group:   "cert-manager.io",
+	Version: "v1",
+	Kind:    "Certificate",
+}
+
+// NewCertificateSyncer returns a syncer of the cert-manager certificate issued
+// for the services and the pods of the cluster.
+func NewCertificateSyncer(cli client.Client, c *cluster.Cluster) syncer.Interface {
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetName(c.GetNameForResource(utils.Certificate))
+	cert.SetNamespace(c.Namespace)
+
+	return syncer.NewObjectSyncer("Certificate", c.Unwrap(), cert, cli, func() error {
+		cert.SetLabels(c.GetLabels())
+
+		var dnsNames []interface{}
+		for _, svc := range []string{
+			c.GetNameForResource(utils.LeaderService),
+			c.GetNameForResource(utils.FollowerService),
+		} {
+			dnsNames = append(dnsNames, svc, fmt.Sprintf("%s.%s", svc, c.Namespace),
+				fmt.Sprintf("%s.%s.svc", svc, c.Namespace))
+		}
+		headless := c.GetNameForResource(utils.HeadlessSVC)
+		dnsNames = append(dnsNames, fmt.Sprintf("*.%s.%s", headless, c.Namespace),
+			fmt.Sprintf("*.%s.%s.svc", headless, c.Namespace))
+
+		issuer := c.Spec.TLS.IssuerRef
+		spec := map[string]interface{}{
+			"secretName": c.GetTLSSecretName(),
+			"commonName": c.GetNameForResource(utils.LeaderService),
+			"dnsNames":   dnsNames,
+			"usages":     []interface{}{"server auth", "client auth"},
+			"issuerRef": map[string]interface{}{
+				"name":  issuer.Name,
+				"kind":  issuer.Kind,
+				"group": issuer.Group,
+			},
+		}
+		return unstructured.SetNestedMap(cert.Object, spec, "spec")
+	})
+}
@@ -19,6 +19,7 @@ package syncer
 import (
 	"bytes"
 	"fmt"
+	"path"
 	"sort"
 
 	"github.com/go-ini/ini"
@@ -67,13 +68,24 @@ func buildMysqlConf(c *cluster.Cluster) (string, error) {
 
 	c.EnsureMysqlConf()
 
-	addKVConfigsToSection(sec, convertMapToKVConfig(mysqlSysConfigs), convertMapToKVConfig(mysqlCommonConfigs),
-		convertMapToKVConfig(mysqlStaticConfigs), c.Spec.MysqlOpts.MysqlConf)
+	versionCommonConfigs, versionStaticConfigs := mysql57CommonConfigs, mysql57StaticConfigs
+	if c.GetMySQLSemVer().Major == 8 {
+		versionCommonConfigs, versionStaticConfigs = mysql80CommonConfigs, mysql80StaticConfigs
+	}
+
+	// the paths of the separate volumes overwrite the defaults of mysqlSysConfigs.
+	addKVConfigsToSection(sec, convertMapToKVConfig(mysqlSysConfigs), convertMapToKVConfig(buildVolumeConfigs(c)),
+		convertMapToKVConfig(mysqlCommonConfigs), convertMapToKVConfig(versionCommonConfigs),
+		convertMapToKVConfig(mysqlStaticConfigs), convertMapToKVConfig(versionStaticConfigs), c.Spec.MysqlOpts.MysqlConf)
 
-	if c.Spec.MysqlOpts.InitTokuDB {
+	if c.IsTokuDBEnabled() {
 		addKVConfigsToSection(sec, convertMapToKVConfig(mysqlTokudbConfigs))
 	}
 
+	if c.Spec.TLS != nil {
+		addKVConfigsToSection(sec, convertMapToKVConfig(buildTLSConfigs(c)))
+	}
+
 	for _, key := range mysqlBooleanConfigs {
 		if _, err := sec.NewBooleanKey(key); err != nil {
 			log.Error(err, "failed to add boolean key to config section", "key", key)
@@ -88,6 +100,37 @@ func buildMysqlConf(c *cluster.Cluster) (string, error) {
 	return data, nil
 }
 
+// buildTLSConfigs returns the configs of the certificate mounted from the secret.
+func buildTLSConfigs(c *cluster.Cluster) map[string]string {
+	configs := map[string]string{
+		"ssl-ca":   path.Join(utils.TLSVolumeMountPath, "ca.crt"),
+		"ssl-cert": path.Join(utils.TLSVolumeMountPath, "tls.crt"),
+		"ssl-key":  path.Join(utils.TLSVolumeMountPath, "tls.key"),
+	}
+	if c.Spec.TLS.RequireSecureTransport {
+		configs["require_secure_transport"] = "ON"
+	}
+	return configs
+}
+
+// buildVolumeConfigs returns the paths of the files moved to the separate volumes.
+func buildVolumeConfigs(c *cluster.Cluster) map[string]string {
+	configs := make(map[string]string)
+	for _, volume := range c.GetExtraVolumes() {
+		switch volume.Name {
+		case utils.BinlogVolumeName:
+			configs["log-bin"] = path.Join(volume.MountPath, "mysql-bin")
+			configs["relay_log"] = path.Join(volume.MountPath, "mysql-relay-bin")
+			configs["relay_log_index"] = path.Join(volume.MountPath, "mysql-relay-bin.index")
+		case utils.RedoLogVolumeName:
+			configs["innodb_log_group_home_dir"] = volume.MountPath
+		case utils.TmpVolumeName:
+			configs["tmpdir"] = volume.MountPath
+		}
+	}
+	return configs
+}
+
 // addKVConfigsToSection add a map[string]string to a ini.Section
 func addKVConfigsToSection(s *ini.Section, extraMysqld ...map[string]intstr.IntOrString) {
 	for _, extra := range extraMysqld {
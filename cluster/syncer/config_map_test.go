@@ -0,0 +1,150 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/go-ini/ini"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+func TestBuildMysqlConf(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     string
+		initTokuDB  bool
+		tls         *apiv1.TLSOpts
+		persistence apiv1.Persistence
+		want        map[string]string
+		absent      []string
+	}{
+		{
+			name:    "mysql 5.7",
+			version: "5.7",
+			want: map[string]string{
+				"expire_logs_days":          "7",
+				"query_cache_type":          "OFF",
+				"innodb_log_files_in_group": "2",
+			},
+			absent: []string{"binlog_expire_logs_seconds", "default_authentication_plugin", "loose_tokudb_directio"},
+		},
+		{
+			name:       "mysql 5.7 with tokudb",
+			version:    "5.7",
+			initTokuDB: true,
+			want: map[string]string{
+				"loose_tokudb_directio": "ON",
+			},
+		},
+		{
+			name:    "mysql 8.0",
+			version: "8.0",
+			want: map[string]string{
+				"binlog_expire_logs_seconds":    "604800",
+				"default_authentication_plugin": "mysql_native_password",
+			},
+			absent: []string{"expire_logs_days", "query_cache_size", "query_cache_type", "innodb_log_files_in_group"},
+		},
+		{
+			name:       "mysql 8.0 ignores tokudb",
+			version:    "8.0",
+			initTokuDB: true,
+			absent:     []string{"loose_tokudb_directio"},
+		},
+		{
+			name:    "tls",
+			version: "5.7",
+			tls:     &apiv1.TLSOpts{SecretName: "certs"},
+			want: map[string]string{
+				"ssl-ca":   "/etc/mysql-tls/ca.crt",
+				"ssl-cert": "/etc/mysql-tls/tls.crt",
+				"ssl-key":  "/etc/mysql-tls/tls.key",
+			},
+			absent: []string{"require_secure_transport"},
+		},
+		{
+			name:    "tls required",
+			version: "8.0",
+			tls:     &apiv1.TLSOpts{SecretName: "certs", RequireSecureTransport: true},
+			want: map[string]string{
+				"ssl-ca":                   "/etc/mysql-tls/ca.crt",
+				"require_secure_transport": "ON",
+			},
+		},
+		{
+			name:    "separate volumes",
+			version: "5.7",
+			persistence: apiv1.Persistence{
+				Enabled: true,
+				Binlog:  &apiv1.VolumeOpts{Size: "10Gi"},
+				RedoLog: &apiv1.VolumeOpts{Size: "10Gi"},
+				Tmp:     &apiv1.VolumeOpts{Size: "10Gi"},
+			},
+			want: map[string]string{
+				"log-bin":                   "/var/lib/mysql-binlog/mysql-bin",
+				"relay_log":                 "/var/lib/mysql-binlog/mysql-relay-bin",
+				"innodb_log_group_home_dir": "/var/lib/mysql-redo",
+				"tmpdir":                    "/var/lib/mysql-tmp",
+			},
+		},
+		{
+			name:    "separate volumes without persistence",
+			version: "5.7",
+			persistence: apiv1.Persistence{
+				Binlog: &apiv1.VolumeOpts{Size: "10Gi"},
+			},
+			want: map[string]string{
+				"log-bin": "/var/lib/mysql/mysql-bin",
+				"tmpdir":  "/var/lib/mysql",
+			},
+			absent: []string{"innodb_log_group_home_dir"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			c.Spec.MysqlVersion = tt.version
+			c.Spec.MysqlOpts.InitTokuDB = tt.initTokuDB
+			c.Spec.TLS = tt.tls
+			c.Spec.Persistence = tt.persistence
+
+			data, err := buildMysqlConf(c)
+			if err != nil {
+				t.Fatalf("buildMysqlConf() error = %v", err)
+			}
+			cfg, err := ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(data))
+			if err != nil {
+				t.Fatalf("failed to parse my.cnf: %v", err)
+			}
+			sec := cfg.Section("mysqld")
+
+			for key, want := range tt.want {
+				if got := sec.Key(key).String(); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			for _, key := range tt.absent {
+				if sec.HasKey(key) {
+					t.Errorf("%s is set for mysql %s", key, tt.version)
+				}
+			}
+		})
+	}
+}
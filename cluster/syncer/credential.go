@@ -0,0 +1,270 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// pendingPrefix is the prefix of the secret keys that keep the new passwords
+// until they are applied, so an interrupted rotation is resumed with them.
+const pendingPrefix = "pending-"
+
+// rotatedPasswords are the secret keys of the rotated passwords.
+var rotatedPasswords = []string{"root-password", "replication-password", "metrics-password"}
+
+// CredentialSyncer rotates the passwords of the root, replication and metrics
+// users when the rotate-credentials annotation changes. The replication and
+// metrics users are altered on the leader and replicated to the followers,
+// then the secret is updated and the pods are rolled out by the RolloutSyncer.
+// The root user is local to each pod, it is altered by the init-file when the
+// pod is restarted, so mysql and xenon never see a mismatched password.
+type CredentialSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewCredentialSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *CredentialSyncer {
+	return &CredentialSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *CredentialSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *CredentialSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *CredentialSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *CredentialSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *CredentialSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	trigger := s.Annotations[utils.RotateCredentialsAnnotation]
+	rotation := s.Status.CredentialRotation
+
+	if rotation != nil && rotation.Phase == apiv1.CredentialRotationRotating {
+		return s.rotate(ctx)
+	}
+
+	if len(trigger) == 0 || (rotation != nil && rotation.Trigger == trigger) {
+		return result, nil
+	}
+
+	now := metav1.Now()
+	s.Status.CredentialRotation = &apiv1.CredentialRotationStatus{
+		Trigger:   trigger,
+		Phase:     apiv1.CredentialRotationRotating,
+		Message:   "rotating the passwords",
+		StartTime: &now,
+	}
+	s.setEvent(&result, corev1.EventTypeNormal, "CredentialRotationStarted", "rotating the passwords of the internal users")
+	return result, nil
+}
+
+// rotate applies the new passwords on the leader, and saves them to the secret
+// once all the followers have replicated them.
+func (s *CredentialSyncer) rotate(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	rotation := s.Status.CredentialRotation
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return result, err
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	// once the pending passwords are saved, the leader may be using the new
+	// metrics password, which the health check relies on, so it is skipped.
+	started := true
+	for _, key := range rotatedPasswords {
+		if _, ok := secret.Data[pendingPrefix+key]; !ok {
+			started = false
+		}
+	}
+	if !started {
+		if msg := s.checkReady(pods.Items); len(msg) != 0 {
+			rotation.Message = msg
+			return result, nil
+		}
+
+		// keep the new passwords in the secret before applying them.
+		for _, key := range rotatedPasswords {
+			if err := addRandomPassword(secret.Data, pendingPrefix+key); err != nil {
+				return result, err
+			}
+		}
+		if err := s.cli.Update(ctx, secret); err != nil {
+			return result, err
+		}
+	}
+
+	// the users are altered only once, every ALTER USER adds a transaction the
+	// followers would have to catch up with again. The gtid set is kept in the
+	// status, so the later passes only wait for the followers to reach it.
+	if len(rotation.Gtid) == 0 {
+		var leader *corev1.Pod
+		for i := range pods.Items {
+			if pods.Items[i].Labels["role"] == "leader" {
+				leader = &pods.Items[i]
+			}
+		}
+		if leader == nil {
+			rotation.Message = "waiting for the leader"
+			return result, nil
+		}
+
+		gtid, err := s.alterUsers(secret, leader)
+		if err != nil {
+			s.log.Error(err, "failed to alter the users", "pod", leader.Name)
+			rotation.Message = fmt.Sprintf("failed to alter the users on %s: %s", leader.Name, err)
+			return result, nil
+		}
+		rotation.Gtid = gtid
+	}
+
+	// the leader may have changed since the users were altered, so all the
+	// pods are checked.
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		ok, err := s.checkReplicated(secret, pod, rotation.Gtid)
+		if err != nil {
+			s.log.Error(err, "failed to check the gtid", "pod", pod.Name)
+		}
+		if !ok {
+			rotation.Message = fmt.Sprintf("waiting for %s to replicate the new passwords", pod.Name)
+			return result, nil
+		}
+	}
+
+	for _, key := range rotatedPasswords {
+		secret.Data[key] = secret.Data[pendingPrefix+key]
+		delete(secret.Data, pendingPrefix+key)
+	}
+	if _, ok := secret.Data["data-source"]; ok {
+		secret.Data["data-source"] = buildDataSource(s.Cluster, secret.Data["metrics-password"])
+	}
+	if err := s.cli.Update(ctx, secret); err != nil {
+		return result, err
+	}
+
+	now := metav1.Now()
+	rotation.Phase = apiv1.CredentialRotationSucceeded
+	rotation.Message = "the new passwords are saved, restarting the pods"
+	rotation.CompletionTime = &now
+	s.Status.LastCredentialRotationTime = &now
+	s.setEvent(&result, corev1.EventTypeNormal, "CredentialRotationSucceeded", rotation.Message)
+	return result, nil
+}
+
+// checkReady returns the reason to wait before the rotation, or empty if
+// nothing else is changing the pods and all of them are healthy.
+func (s *CredentialSyncer) checkReady(pods []corev1.Pod) string {
+	if up := s.Status.Upgrade; up != nil && (up.Phase == apiv1.UpgradeBackingUp || up.Phase == apiv1.UpgradeUpgrading) {
+		return "waiting for the upgrade"
+	}
+	if rollout := s.Status.Rollout; rollout != nil && rollout.Phase == apiv1.RolloutUpdating {
+		return "waiting for the rolling update"
+	}
+	if sw := s.Status.Switchover; sw != nil && sw.Phase == apiv1.SwitchoverInProgress {
+		return "waiting for the switchover"
+	}
+	if int32(len(pods)) < *s.Spec.Replicas {
+		return "waiting for all the pods to be created"
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Labels["healthy"] != "yes" {
+			return fmt.Sprintf("waiting for %s to be healthy", pod.Name)
+		}
+	}
+	return ""
+}
+
+// alterUsers changes the passwords of the replication and metrics users on the
+// leader, and returns the gtid set executed by the leader afterwards.
+func (s *CredentialSyncer) alterUsers(secret *corev1.Secret, leader *corev1.Pod) (string, error) {
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, leader)
+	if err != nil {
+		return "", err
+	}
+	defer runner.Close()
+
+	query := "ALTER USER ?@'%' IDENTIFIED BY ?;\nALTER USER ?@'%' IDENTIFIED BY ?;"
+	if err = runner.RunQuery(query,
+		utils.ReplicationUser, utils.BytesToString(secret.Data[pendingPrefix+"replication-password"]),
+		utils.MetricsUser, utils.BytesToString(secret.Data[pendingPrefix+"metrics-password"]),
+	); err != nil {
+		return "", err
+	}
+
+	var gtid string
+	if err = runner.GetGlobalVariable("gtid_executed", &gtid); err != nil {
+		return "", err
+	}
+	return gtid, nil
+}
+
+// checkReplicated checks whether the pod has executed the gtid set.
+func (s *CredentialSyncer) checkReplicated(secret *corev1.Secret, pod *corev1.Pod, gtid string) (bool, error) {
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, pod)
+	if err != nil {
+		return false, err
+	}
+	defer runner.Close()
+	return runner.CheckGtidSubset(gtid)
+}
+
+func (s *CredentialSyncer) setEvent(result *syncer.SyncResult, eventType, reason, msg string) {
+	// the event is recorded only if the operation is not none.
+	result.Operation = controllerutil.OperationResultUpdated
+	result.SetEventData(eventType, reason, msg)
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestCredentialSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		trigger  string
+		rotation *apiv1.CredentialRotationStatus
+		rollout  *apiv1.RolloutStatus
+		pods     []testRolloutPod
+		// the pending passwords saved before the sync.
+		pending    bool
+		wantPhase  apiv1.CredentialRotationPhase
+		wantReason string
+		wantMsg    string
+		// whether the pending passwords are saved after the sync.
+		wantPending bool
+	}{
+		{
+			name: "no request",
+		},
+		{
+			name:       "rotation requested",
+			trigger:    "1",
+			wantPhase:  apiv1.CredentialRotationRotating,
+			wantReason: "CredentialRotationStarted",
+			wantMsg:    "rotating the passwords",
+		},
+		{
+			name:      "trigger already handled",
+			trigger:   "1",
+			rotation:  &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationSucceeded},
+			wantPhase: apiv1.CredentialRotationSucceeded,
+		},
+		{
+			name:       "new trigger after a rotation",
+			trigger:    "2",
+			rotation:   &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationSucceeded},
+			wantPhase:  apiv1.CredentialRotationRotating,
+			wantReason: "CredentialRotationStarted",
+			wantMsg:    "rotating the passwords",
+		},
+		{
+			name:      "waiting for the rolling update",
+			trigger:   "1",
+			rotation:  &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			rollout:   &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating},
+			pods:      []testRolloutPod{{"leader", "yes", ""}, {"follower", "yes", ""}},
+			wantPhase: apiv1.CredentialRotationRotating,
+			wantMsg:   "waiting for the rolling update",
+		},
+		{
+			name:      "waiting for the pods",
+			trigger:   "1",
+			rotation:  &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			pods:      []testRolloutPod{{"leader", "yes", ""}},
+			wantPhase: apiv1.CredentialRotationRotating,
+			wantMsg:   "waiting for all the pods to be created",
+		},
+		{
+			name:      "waiting for a healthy pod",
+			trigger:   "1",
+			rotation:  &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			pods:      []testRolloutPod{{"leader", "yes", ""}, {"follower", "no", ""}},
+			wantPhase: apiv1.CredentialRotationRotating,
+			wantMsg:   "waiting for sample-mysql-1 to be healthy",
+		},
+		{
+			name:        "pending passwords saved before altering",
+			trigger:     "1",
+			rotation:    &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			pods:        []testRolloutPod{{"follower", "yes", ""}, {"follower", "yes", ""}},
+			wantPhase:   apiv1.CredentialRotationRotating,
+			wantMsg:     "waiting for the leader",
+			wantPending: true,
+		},
+		{
+			name:        "started rotation skips the health check",
+			trigger:     "1",
+			rotation:    &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			pods:        []testRolloutPod{{"follower", "no", ""}, {"follower", "no", ""}},
+			pending:     true,
+			wantPhase:   apiv1.CredentialRotationRotating,
+			wantMsg:     "waiting for the leader",
+			wantPending: true,
+		},
+		{
+			name:        "users altered on the leader",
+			trigger:     "1",
+			rotation:    &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating},
+			pods:        []testRolloutPod{{"leader", "yes", ""}, {"follower", "yes", ""}},
+			pending:     true,
+			wantPhase:   apiv1.CredentialRotationRotating,
+			wantMsg:     "failed to alter the users on sample-mysql-0: failed to get the operator user from the secret",
+			wantPending: true,
+		},
+		{
+			name:    "altered users wait for the followers",
+			trigger: "1",
+			rotation: &apiv1.CredentialRotationStatus{Trigger: "1", Phase: apiv1.CredentialRotationRotating,
+				Gtid: "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-10"},
+			pods:        []testRolloutPod{{"leader", "yes", ""}, {"follower", "yes", ""}},
+			pending:     true,
+			wantPhase:   apiv1.CredentialRotationRotating,
+			wantMsg:     "waiting for sample-mysql-0 to replicate the new passwords",
+			wantPending: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			replicas := int32(2)
+			c.Spec.Replicas = &replicas
+			if len(tt.trigger) != 0 {
+				c.Annotations[utils.RotateCredentialsAnnotation] = tt.trigger
+			}
+			c.Status.CredentialRotation = tt.rotation
+			c.Status.Rollout = tt.rollout
+
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: c.GetNameForResource(utils.Secret), Namespace: c.Namespace},
+				Data: map[string][]byte{
+					"root-password":        []byte("root"),
+					"replication-password": []byte("repl"),
+					"metrics-password":     []byte("metrics"),
+				},
+			}
+			if tt.pending {
+				for _, key := range rotatedPasswords {
+					secret.Data[pendingPrefix+key] = []byte("new")
+				}
+			}
+			objs := []client.Object{secret}
+			for i, p := range tt.pods {
+				objs = append(objs, newTestPod(c, i, p.role, p.healthy))
+			}
+			cli := newFakeClient(c, objs...)
+
+			result, err := NewCredentialSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			var phase apiv1.CredentialRotationPhase
+			var msg string
+			if rotation := c.Status.CredentialRotation; rotation != nil {
+				phase, msg = rotation.Phase, rotation.Message
+				if rotation.Trigger != tt.trigger {
+					t.Errorf("trigger = %q, want %q", rotation.Trigger, tt.trigger)
+				}
+			}
+			if phase != tt.wantPhase || msg != tt.wantMsg {
+				t.Errorf("rotation = %q, %q, want %q, %q", phase, msg, tt.wantPhase, tt.wantMsg)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+
+			got := &corev1.Secret{}
+			if err := cli.Get(context.TODO(), client.ObjectKeyFromObject(secret), got); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range rotatedPasswords {
+				pending, ok := got.Data[pendingPrefix+key]
+				if ok != tt.wantPending {
+					t.Errorf("%s saved = %v, want %v", pendingPrefix+key, ok, tt.wantPending)
+				}
+				if ok && !tt.pending && string(pending) == string(secret.Data[key]) {
+					t.Errorf("%s is not changed", key)
+				}
+				if string(got.Data[key]) != string(secret.Data[key]) {
+					t.Errorf("%s changed before the rotation succeeded", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// defaultDiskWarningThreshold is used if the warning threshold is not defaulted by the webhook.
+const defaultDiskWarningThreshold = 80
+
+// DiskSyncer protects the cluster by the disk usage reported by the status
+// updater. The DiskPressure condition is set if a volume is above the warning
+// threshold, then the binlogs applied on all the followers are purged, and
+// the cluster is set read-only if a volume of the leader is above the
+// read-only threshold.
+type DiskSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewDiskSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *DiskSyncer {
+	return &DiskSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *DiskSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *DiskSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *DiskSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *DiskSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *DiskSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	threshold := s.Spec.DiskOpts.WarningThreshold
+	if threshold <= 0 {
+		threshold = defaultDiskWarningThreshold
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	var leader *corev1.Pod
+	// leaderUsed is the max used percentage of the volumes of the leader, -1 if unknown.
+	leaderUsed := int32(-1)
+	var pressures []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		usage := s.getDiskUsage(pod)
+		if pod.Labels["role"] == "leader" {
+			leader = pod
+		}
+		if usage == nil {
+			continue
+		}
+
+		for _, volume := range usage.Volumes {
+			if volume.UsedPercent >= threshold {
+				pressures = append(pressures, fmt.Sprintf("%s of %s", volume.Name, pod.Name))
+			}
+			if leader == pod && volume.UsedPercent > leaderUsed {
+				leaderUsed = volume.UsedPercent
+			}
+		}
+	}
+
+	if len(pressures) > 0 {
+		msg := fmt.Sprintf("the used space of %s is above %d%%", strings.Join(pressures, ", "), threshold)
+		if s.Status.DiskPressure != msg {
+			s.Status.DiskPressure = msg
+			s.updateDiskPressureCondition(corev1.ConditionTrue, "VolumeAboveThreshold", msg)
+			s.setEvent(&result, corev1.EventTypeWarning, "DiskPressure", msg)
+		}
+	} else if len(s.Status.DiskPressure) != 0 {
+		s.Status.DiskPressure = ""
+		s.updateDiskPressureCondition(corev1.ConditionFalse, "VolumeBelowThreshold", "")
+		s.setEvent(&result, corev1.EventTypeNormal, "DiskPressureRelieved",
+			fmt.Sprintf("the used space of all the volumes is below %d%%", threshold))
+	}
+
+	if leader == nil || leaderUsed < 0 {
+		return result, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return result, err
+	}
+
+	// purging the binlogs not archived yet would break the point-in-time recovery.
+	if s.Spec.DiskOpts.PurgeBinlogs && s.Spec.BinlogArchive == nil && leaderUsed >= threshold {
+		binlog, err := s.purgeBinlogs(secret, leader, pods.Items)
+		if err != nil {
+			s.log.Error(err, "failed to purge the binlogs", "leader", leader.Name)
+		} else if len(binlog) > 0 {
+			s.setEvent(&result, corev1.EventTypeNormal, "BinlogsPurged",
+				fmt.Sprintf("the binlogs of %s before %s are purged", leader.Name, binlog))
+		}
+	}
+
+	readOnly := s.Status.ReadOnly
+	if limit := s.Spec.DiskOpts.ReadOnlyThreshold; limit != nil && leaderUsed >= *limit {
+		readOnly = true
+	} else if limit == nil || leaderUsed < threshold {
+		readOnly = false
+	}
+	if err := s.setLeaderReadOnly(secret, leader, readOnly); err != nil {
+		return result, err
+	}
+
+	if readOnly != s.Status.ReadOnly {
+		s.Status.ReadOnly = readOnly
+		if readOnly {
+			s.setEvent(&result, corev1.EventTypeWarning, "ClusterReadOnly",
+				fmt.Sprintf("the cluster is set read-only, the used space of %s is %d%%", leader.Name, leaderUsed))
+		} else {
+			s.setEvent(&result, corev1.EventTypeNormal, "ClusterWritable",
+				fmt.Sprintf("the cluster is writable again, the used space of %s is %d%%", leader.Name, leaderUsed))
+		}
+	}
+	return result, nil
+}
+
+// getDiskUsage returns the disk usage of the ready pod reported by the status updater.
+func (s *DiskSyncer) getDiskUsage(pod *corev1.Pod) *apiv1.DiskUsage {
+	ready := false
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.ContainersReady && cond.Status == corev1.ConditionTrue {
+			ready = true
+		}
+	}
+	if !ready {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", pod.Name, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+	for _, node := range s.Status.Nodes {
+		if node.Name == name {
+			return node.DiskUsage
+		}
+	}
+	return nil
+}
+
+// purgeBinlogs purges the binlogs of the leader before the oldest one being
+// applied by the followers, it returns the first binlog kept or an empty
+// string if nothing is purged. The binlogs are kept unless all the followers
+// are healthy and replicating from the leader.
+func (s *DiskSyncer) purgeBinlogs(secret *corev1.Secret, leader *corev1.Pod, pods []corev1.Pod) (string, error) {
+	if int32(len(pods)) != *s.Spec.Replicas {
+		return "", nil
+	}
+
+	var oldest string
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Name == leader.Name {
+			continue
+		}
+		if pod.Labels["role"] != "follower" || pod.Labels["healthy"] != "yes" {
+			return "", nil
+		}
+
+		host, binlog, err := getReplicationSource(secret, s.Cluster, pod)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(host, leader.Name+".") || len(binlog) == 0 {
+			return "", nil
+		}
+		if len(oldest) == 0 || binlog < oldest {
+			oldest = binlog
+		}
+	}
+	if len(oldest) == 0 {
+		return "", nil
+	}
+
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, leader)
+	if err != nil {
+		return "", err
+	}
+	defer runner.Close()
+
+	binlogs, err := runner.GetBinaryLogs()
+	if err != nil {
+		return "", err
+	}
+	if len(binlogs) == 0 || binlogs[0] >= oldest {
+		return "", nil
+	}
+
+	if err = runner.RunQuery("PURGE BINARY LOGS TO ?", oldest); err != nil {
+		return "", err
+	}
+	return oldest, nil
+}
+
+// getReplicationSource returns the master and the binlog being applied by the pod.
+func getReplicationSource(secret *corev1.Secret, c *cluster.Cluster, pod *corev1.Pod) (string, string, error) {
+	runner, err := newOperatorSQLRunner(secret, c, pod)
+	if err != nil {
+		return "", "", err
+	}
+	defer runner.Close()
+
+	return runner.GetReplicationSource()
+}
+
+// setLeaderReadOnly makes the leader read-only or writable as expected, the
+// writes are also enabled by the status updater once the flag is cleared.
+func (s *DiskSyncer) setLeaderReadOnly(secret *corev1.Secret, leader *corev1.Pod, readOnly bool) error {
+	// the leader is writable unless it has been set read-only.
+	if !readOnly && !s.Status.ReadOnly {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", leader.Name, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+	for i := range s.Status.Nodes {
+		node := &s.Status.Nodes[i]
+		if node.Name != name {
+			continue
+		}
+		if cond := getNodeCondition(node, apiv1.NodeConditionReadOnly); cond != nil {
+			if (readOnly && cond.Status == corev1.ConditionTrue) || (!readOnly && cond.Status == corev1.ConditionFalse) {
+				return nil
+			}
+		}
+	}
+
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, leader)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+
+	if readOnly {
+		// super_read_only implies read_only, and blocks the users with SUPER too.
+		return runner.SetGlobalVariable("super_read_only", "ON")
+	}
+	// turning off read_only turns off super_read_only too.
+	return runner.SetGlobalVariable("read_only", "OFF")
+}
+
+func (s *DiskSyncer) updateDiskPressureCondition(status corev1.ConditionStatus, reason, msg string) {
+	s.Status.Conditions = append(s.Status.Conditions, apiv1.ClusterCondition{
+		Type:               apiv1.ClusterDiskPressure,
+		Status:             status,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            msg,
+	})
+	if len(s.Status.Conditions) > maxStatusesQuantity {
+		s.Status.Conditions = s.Status.Conditions[len(s.Status.Conditions)-maxStatusesQuantity:]
+	}
+}
+
+func (s *DiskSyncer) setEvent(result *syncer.SyncResult, eventType, reason, msg string) {
+	// the event is recorded only if the operation is not none.
+	result.Operation = controllerutil.OperationResultUpdated
+	result.SetEventData(eventType, reason, msg)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// testDiskPod describes a pod of the disk tests.
+type testDiskPod struct {
+	role  string
+	ready bool
+	// the used percentage of the data volume, -1 if not reported.
+	used int32
+	// the read_only of the node.
+	readOnly corev1.ConditionStatus
+}
+
+// reverseConditions reverses the conditions of the node, which must be found by the type.
+func reverseConditions(node *apiv1.NodeStatus) {
+	for i, j := 0, len(node.Conditions)-1; i < j; i, j = i+1, j-1 {
+		node.Conditions[i], node.Conditions[j] = node.Conditions[j], node.Conditions[i]
+	}
+}
+
+func TestDiskSync(t *testing.T) {
+	tests := []struct {
+		name string
+		pods []testDiskPod
+		// the read-only threshold, 0 if not set.
+		readOnlyThreshold int32
+		readOnly          bool
+		// the reported disk pressure, empty if relieved.
+		pressure string
+		// the capped history of the conditions has no DiskPressure left.
+		trimmed bool
+		// the conditions of the nodes are not in the order of the status updater.
+		reversed     bool
+		wantPressure string
+		wantReadOnly bool
+		wantReason   string
+	}{
+		{
+			name: "below the threshold",
+			pods: []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", true, 60, corev1.ConditionTrue}},
+		},
+		{
+			name:         "follower above the threshold",
+			pods:         []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", true, 85, corev1.ConditionTrue}},
+			wantPressure: "the used space of data of sample-mysql-1 is above 80%",
+			wantReason:   "DiskPressure",
+		},
+		{
+			name:         "pressure already reported",
+			pods:         []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", true, 85, corev1.ConditionTrue}},
+			pressure:     "the used space of data of sample-mysql-1 is above 80%",
+			wantPressure: "the used space of data of sample-mysql-1 is above 80%",
+		},
+		{
+			name:         "pressure trimmed from the conditions",
+			pods:         []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", true, 85, corev1.ConditionTrue}},
+			pressure:     "the used space of data of sample-mysql-1 is above 80%",
+			trimmed:      true,
+			wantPressure: "the used space of data of sample-mysql-1 is above 80%",
+		},
+		{
+			name:       "pressure relieved",
+			pods:       []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", true, 60, corev1.ConditionTrue}},
+			pressure:   "the used space of data of sample-mysql-1 is above 80%",
+			wantReason: "DiskPressureRelieved",
+		},
+		{
+			name: "usage of the pods not ready is ignored",
+			pods: []testDiskPod{{"leader", true, 50, corev1.ConditionFalse}, {"follower", false, 95, corev1.ConditionTrue}},
+		},
+		{
+			name:              "leader above the read-only threshold",
+			pods:              []testDiskPod{{"leader", true, 95, corev1.ConditionTrue}, {"follower", true, 60, corev1.ConditionTrue}},
+			readOnlyThreshold: 90,
+			wantPressure:      "the used space of data of sample-mysql-0 is above 80%",
+			wantReadOnly:      true,
+			wantReason:        "ClusterReadOnly",
+		},
+		{
+			name:              "read-only found by the condition type",
+			pods:              []testDiskPod{{"leader", true, 95, corev1.ConditionTrue}, {"follower", true, 60, corev1.ConditionTrue}},
+			readOnlyThreshold: 90,
+			readOnly:          true,
+			pressure:          "the used space of data of sample-mysql-0 is above 80%",
+			reversed:          true,
+			wantPressure:      "the used space of data of sample-mysql-0 is above 80%",
+			wantReadOnly:      true,
+		},
+		{
+			name:              "read-only kept above the warning threshold",
+			pods:              []testDiskPod{{"leader", true, 85, corev1.ConditionTrue}, {"follower", true, 60, corev1.ConditionTrue}},
+			readOnlyThreshold: 90,
+			readOnly:          true,
+			pressure:          "the used space of data of sample-mysql-0 is above 80%",
+			wantPressure:      "the used space of data of sample-mysql-0 is above 80%",
+			wantReadOnly:      true,
+		},
+		{
+			name:              "writable below the warning threshold",
+			pods:              []testDiskPod{{"leader", true, 70, corev1.ConditionFalse}, {"follower", true, 60, corev1.ConditionTrue}},
+			readOnlyThreshold: 90,
+			readOnly:          true,
+			wantReason:        "ClusterWritable",
+		},
+		{
+			name:         "writable without the read-only threshold",
+			pods:         []testDiskPod{{"leader", true, 95, corev1.ConditionFalse}, {"follower", true, 60, corev1.ConditionTrue}},
+			readOnly:     true,
+			wantPressure: "the used space of data of sample-mysql-0 is above 80%",
+			wantReason:   "ClusterWritable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			replicas := int32(len(tt.pods))
+			c.Spec.Replicas = &replicas
+			c.Spec.DiskOpts.WarningThreshold = 80
+			if tt.readOnlyThreshold > 0 {
+				c.Spec.DiskOpts.ReadOnlyThreshold = &tt.readOnlyThreshold
+			}
+			c.Status.ReadOnly = tt.readOnly
+			c.Status.DiskPressure = tt.pressure
+			if tt.trimmed {
+				for i := 0; i < maxStatusesQuantity; i++ {
+					c.Status.Conditions = append(c.Status.Conditions, apiv1.ClusterCondition{
+						Type:   apiv1.ClusterSwitchover,
+						Status: corev1.ConditionFalse,
+					})
+				}
+			}
+
+			objs := []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: c.GetNameForResource(utils.Secret), Namespace: c.Namespace},
+			}}
+			for i, p := range tt.pods {
+				pod := newTestPod(c, i, p.role, "yes")
+				if p.ready {
+					pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.ContainersReady, Status: corev1.ConditionTrue}}
+				}
+				objs = append(objs, pod)
+
+				node := newTestNode(c, i, p.readOnly)
+				node.DiskUsage = &apiv1.DiskUsage{Volumes: []apiv1.VolumeUsage{{Name: "data", UsedPercent: p.used}}}
+				if tt.reversed {
+					reverseConditions(&node)
+				}
+				c.Status.Nodes = append(c.Status.Nodes, node)
+			}
+			cli := newFakeClient(c, objs...)
+
+			result, err := NewDiskSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			if c.Status.DiskPressure != tt.wantPressure {
+				t.Errorf("DiskPressure = %q, want %q", c.Status.DiskPressure, tt.wantPressure)
+			}
+			if len(tt.wantReason) != 0 && strings.HasPrefix(tt.wantReason, "DiskPressure") {
+				last := c.Status.Conditions[len(c.Status.Conditions)-1]
+				if last.Type != apiv1.ClusterDiskPressure || (last.Status == corev1.ConditionTrue) != (len(tt.wantPressure) != 0) {
+					t.Errorf("last condition = %s %s, want DiskPressure", last.Type, last.Status)
+				}
+			}
+			if c.Status.ReadOnly != tt.wantReadOnly {
+				t.Errorf("read-only = %v, want %v", c.Status.ReadOnly, tt.wantReadOnly)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+		})
+	}
+}
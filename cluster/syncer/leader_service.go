@@ -45,13 +45,18 @@ func NewLeaderSVCSyncer(cli client.Client, c *cluster.Cluster) syncer.Interface
 		service.Spec.Selector = c.GetSelectorLabels()
 		service.Spec.Selector["role"] = "leader"
 
-		if len(service.Spec.Ports) != 1 {
-			service.Spec.Ports = make([]corev1.ServicePort, 1)
+		if len(service.Spec.Ports) != 2 {
+			service.Spec.Ports = make([]corev1.ServicePort, 2)
 		}
 
 		service.Spec.Ports[0].Name = utils.MysqlPortName
 		service.Spec.Ports[0].Port = utils.MysqlPort
 		service.Spec.Ports[0].TargetPort = intstr.FromInt(utils.MysqlPort)
+
+		// used by the new members to clone the data.
+		service.Spec.Ports[1].Name = utils.SidecarHTTPPortName
+		service.Spec.Ports[1].Port = utils.SidecarHTTPPort
+		service.Spec.Ports[1].TargetPort = intstr.FromInt(utils.SidecarHTTPPort)
 		return nil
 	})
 }
@@ -0,0 +1,243 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-sql-driver/mysql"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// variableRegexp matches the names of the variables, which can't be passed as
+// the query arguments.
+var variableRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// errReadOnlyVariable is the error number of setting a read only variable.
+const errReadOnlyVariable = 1238
+
+// MysqlConfSyncer applies the mysqlConf to the running pods. The dynamic
+// configs are set with SET GLOBAL on every pod, the static ones are applied by
+// restarting the pods, which is driven by the hash on the pod template, and are
+// reported as pending restart until all the pods are restarted.
+type MysqlConfSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewMysqlConfSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *MysqlConfSyncer {
+	return &MysqlConfSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *MysqlConfSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *MysqlConfSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *MysqlConfSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *MysqlConfSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *MysqlConfSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	if s.Status.MysqlConf == nil {
+		s.Status.MysqlConf = &apiv1.MysqlConfStatus{}
+	}
+	status := s.Status.MysqlConf
+
+	// the spec is modified by EnsureMysqlConf, work on a copy.
+	c := cluster.New(s.Unwrap().DeepCopy())
+	static := c.GetStaticMysqlConf()
+	staticHash := utils.HashConfigs(static)
+	dynamic := c.GetDynamicMysqlConf()
+	dynamicHash := utils.HashConfigs(dynamic)
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	restarted := int32(len(pods.Items)) == *s.Spec.Replicas
+	for _, pod := range pods.Items {
+		if pod.Annotations[utils.StaticConfigHashAnnotation] != staticHash {
+			restarted = false
+		}
+	}
+	if restarted {
+		status.Static = static
+	}
+	status.PendingRestart = pendingRestart(status.Static, static)
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return result, err
+	}
+
+	var applied, failures []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels["healthy"] != "yes" || pod.Annotations[utils.DynamicConfigHashAnnotation] == dynamicHash {
+			continue
+		}
+
+		readOnly, err := s.applyDynamicConf(secret, pod, dynamic)
+		if err != nil {
+			s.log.Error(err, "failed to apply the mysqlConf", "pod", pod.Name)
+			failures = append(failures, fmt.Sprintf("%s: %s", pod.Name, err))
+			continue
+		}
+		// the read only configs move to the static ones, which changes the
+		// hash on the pod template and restarts the pods.
+		for _, key := range readOnly {
+			if !utils.StringInSlice(key, status.ReadOnly) {
+				s.log.Info("the config is read only, restart to apply it", "key", key)
+				status.ReadOnly = append(status.ReadOnly, key)
+				status.PendingRestart = append(status.PendingRestart, key)
+			}
+		}
+
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[utils.DynamicConfigHashAnnotation] = dynamicHash
+		if err := s.cli.Patch(ctx, pod, patch); err != nil {
+			return result, err
+		}
+		applied = append(applied, pod.Name)
+	}
+	status.Message = strings.Join(failures, "; ")
+	sort.Strings(status.ReadOnly)
+	sort.Strings(status.PendingRestart)
+
+	if len(applied) > 0 {
+		// the event is recorded only if the operation is not none.
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeNormal, "MysqlConfApplied",
+			fmt.Sprintf("the dynamic configs are applied to %s", strings.Join(applied, ", ")))
+	}
+	return result, nil
+}
+
+// applyDynamicConf sets the dynamic configs on the pod, and returns the configs
+// which are read only.
+func (s *MysqlConfSyncer) applyDynamicConf(secret *corev1.Secret, pod *corev1.Pod, conf map[string]string) ([]string, error) {
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, pod)
+	if err != nil {
+		return nil, err
+	}
+	defer runner.Close()
+
+	keys := make([]string, 0, len(conf))
+	for key := range conf {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var readOnly []string
+	for _, key := range keys {
+		err := setGlobalVariable(runner, key, conf[key])
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == errReadOnlyVariable {
+			readOnly = append(readOnly, key)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to set %s: %s", key, err)
+		}
+	}
+	return readOnly, nil
+}
+
+// setGlobalVariable converts the value of the option file to the one accepted by SET GLOBAL.
+func setGlobalVariable(runner *internal.SQLRunner, key, value string) error {
+	name := utils.NormalizeMysqlVariable(key)
+	// the loose options are ignored by mysqld if they are unknown.
+	loose := strings.HasPrefix(name, "loose_")
+	name = strings.TrimPrefix(name, "loose_")
+	if !variableRegexp.MatchString(name) {
+		return fmt.Errorf("invalid variable name")
+	}
+
+	var arg interface{} = value
+	if i, ok := utils.ParseMysqlSize(value); ok {
+		arg = i
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		// eg: long_query_time, which is refused as a string.
+		arg = f
+	}
+
+	err := runner.SetGlobalVariable(name, arg)
+	if err != nil && loose && strings.Contains(err.Error(), "Unknown system variable") {
+		return nil
+	}
+	return err
+}
+
+// pendingRestart returns the static configs different from the running ones.
+func pendingRestart(running, desired map[string]string) []string {
+	if running == nil {
+		return nil
+	}
+
+	var keys []string
+	for key, value := range desired {
+		if old, ok := running[key]; !ok || old != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range running {
+		if _, ok := desired[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
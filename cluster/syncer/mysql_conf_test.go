@@ -0,0 +1,73 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPendingRestart(t *testing.T) {
+	tests := []struct {
+		name    string
+		running map[string]string
+		desired map[string]string
+		want    []string
+	}{
+		{
+			name:    "not running yet",
+			desired: map[string]string{"innodb_log_file_size": "1073741824"},
+		},
+		{
+			name:    "unchanged",
+			running: map[string]string{"innodb_log_file_size": "1073741824"},
+			desired: map[string]string{"innodb_log_file_size": "1073741824"},
+		},
+		{
+			name:    "changed",
+			running: map[string]string{"innodb_log_file_size": "1073741824", "thread_cache_size": "64"},
+			desired: map[string]string{"innodb_log_file_size": "2147483648", "thread_cache_size": "64"},
+			want:    []string{"innodb_log_file_size"},
+		},
+		{
+			name:    "added",
+			running: map[string]string{},
+			desired: map[string]string{"performance_schema": "0"},
+			want:    []string{"performance_schema"},
+		},
+		{
+			name:    "removed",
+			running: map[string]string{"performance_schema": "0"},
+			desired: map[string]string{},
+			want:    []string{"performance_schema"},
+		},
+		{
+			name:    "sorted",
+			running: map[string]string{"thread_cache_size": "64", "back_log": "80"},
+			desired: map[string]string{"thread_cache_size": "128", "innodb_log_file_size": "1073741824"},
+			want:    []string{"back_log", "innodb_log_file_size", "thread_cache_size"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pendingRestart(tt.running, tt.desired); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pendingRestart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
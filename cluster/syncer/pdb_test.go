@@ -0,0 +1,77 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestPDBSyncer(t *testing.T) {
+	tests := []struct {
+		name           string
+		maxUnavailable *intstr.IntOrString
+		want           intstr.IntOrString
+	}{
+		{
+			name: "default",
+			want: intstr.FromInt(1),
+		},
+		{
+			name:           "percentage",
+			maxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"},
+			want:           intstr.FromString("50%"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			c.Spec.PodSpec.MaxUnavailable = tt.maxUnavailable
+			cli := newFakeClient(c)
+
+			if _, err := NewPDBSyncer(cli, c).Sync(context.TODO()); err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			pdb := &policyv1beta1.PodDisruptionBudget{}
+			if err := cli.Get(context.TODO(), client.ObjectKey{
+				Namespace: c.Namespace,
+				Name:      c.GetNameForResource(utils.PodDisruptionBudget),
+			}, pdb); err != nil {
+				t.Fatal(err)
+			}
+			if pdb.Spec.MaxUnavailable == nil || *pdb.Spec.MaxUnavailable != tt.want {
+				t.Errorf("maxUnavailable = %v, want %v", pdb.Spec.MaxUnavailable, tt.want)
+			}
+			if pdb.Spec.MinAvailable != nil {
+				t.Errorf("minAvailable = %v, want nil", pdb.Spec.MinAvailable)
+			}
+			if want := metav1.SetAsLabelSelector(c.GetSelectorLabels()); !reflect.DeepEqual(pdb.Spec.Selector, want) {
+				t.Errorf("selector = %v, want %v", pdb.Spec.Selector, want)
+			}
+		})
+	}
+}
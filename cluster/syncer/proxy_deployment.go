@@ -0,0 +1,219 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/imdario/mergo"
+	"github.com/presslabs/controller-util/mergo/transformers"
+	"github.com/presslabs/controller-util/syncer"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// proxyWriterHostgroup is the hostgroup of the leader.
+	proxyWriterHostgroup = 10
+	// proxyReaderHostgroup is the hostgroup of the healthy followers.
+	proxyReaderHostgroup = 20
+)
+
+// NewProxyDeploymentSyncer returns a deployment syncer of proxysql. The
+// servers and users are configured at runtime by the ProxySQLSyncer.
+func NewProxyDeploymentSyncer(cli client.Client, c *cluster.Cluster) syncer.Interface {
+	obj := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      c.GetNameForResource(utils.ProxyDeployment),
+			Namespace: c.Namespace,
+			Labels:    c.GetProxyLabels(),
+		},
+	}
+
+	return syncer.NewObjectSyncer("ProxyDeployment", c.Unwrap(), obj, cli, func() error {
+		secret := &corev1.Secret{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: c.Namespace,
+			Name:      c.GetNameForResource(utils.Secret),
+		}, secret); err != nil {
+			return err
+		}
+
+		obj.Spec.Replicas = c.Spec.Proxy.Replicas
+		obj.Spec.Selector = metav1.SetAsLabelSelector(c.GetProxySelectorLabels())
+
+		obj.Spec.Template.ObjectMeta.Labels = c.GetProxyLabels()
+		// restart the pods to apply the changed proxysql.cnf.
+		obj.Spec.Template.ObjectMeta.Annotations = map[string]string{
+			utils.ProxyConfigFileHashAnnotation: utils.HashConfigs(map[string]string{
+				"proxysql.cnf": utils.BytesToString(secret.Data["proxysql.cnf"]),
+			}),
+		}
+
+		err := mergo.Merge(&obj.Spec.Template.Spec, ensureProxyPodSpec(c), mergo.WithTransformers(transformers.PodSpec))
+		if err != nil {
+			return err
+		}
+		// mergo will add new keys for Tolerations and keep the others instead of removing them
+		obj.Spec.Template.Spec.Tolerations = c.Spec.PodSpec.Tolerations
+		return nil
+	})
+}
+
+func ensureProxyPodSpec(c *cluster.Cluster) corev1.PodSpec {
+	return corev1.PodSpec{
+		Containers: []corev1.Container{ensureProxySQLContainer(c)},
+		Volumes: []corev1.Volume{
+			{
+				Name: utils.ProxyConfVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: c.GetNameForResource(utils.Secret),
+						Items: []corev1.KeyToPath{
+							{Key: "proxysql.cnf", Path: "proxysql.cnf"},
+						},
+					},
+				},
+			},
+			{
+				Name: utils.ProxyDataVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+		},
+		Affinity: &corev1.Affinity{
+			PodAntiAffinity: &corev1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{
+						Weight: 100,
+						PodAffinityTerm: corev1.PodAffinityTerm{
+							LabelSelector: metav1.SetAsLabelSelector(c.GetProxySelectorLabels()),
+							TopologyKey:   corev1.LabelHostname,
+						},
+					},
+				},
+			},
+		},
+		PriorityClassName: c.Spec.PodSpec.PriorityClassName,
+		Tolerations:       c.Spec.PodSpec.Tolerations,
+	}
+}
+
+func ensureProxySQLContainer(c *cluster.Cluster) corev1.Container {
+	return corev1.Container{
+		Name:            utils.ContainerProxySQLName,
+		Image:           c.Spec.Proxy.Image,
+		ImagePullPolicy: c.Spec.PodSpec.ImagePullPolicy,
+		// --initial drops the database of the previous run, the servers and
+		// users are configured again by the operator.
+		Command: []string{"proxysql", "-f", "--initial", "-c", utils.ProxyConfVolumeMountPath + "/proxysql.cnf",
+			"-D", utils.ProxyDataVolumeMountPath},
+		Resources: c.Spec.Proxy.Resources,
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          utils.MysqlPortName,
+				ContainerPort: utils.MysqlPort,
+			},
+			{
+				Name:          utils.ProxyAdminPortName,
+				ContainerPort: utils.ProxyAdminPort,
+			},
+		},
+		LivenessProbe: &corev1.Probe{
+			Handler: corev1.Handler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(utils.ProxyAdminPort)},
+			},
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       10,
+		},
+		ReadinessProbe: &corev1.Probe{
+			Handler: corev1.Handler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(utils.MysqlPort)},
+			},
+			InitialDelaySeconds: 5,
+			PeriodSeconds:       5,
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      utils.ProxyConfVolumeName,
+				MountPath: utils.ProxyConfVolumeMountPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      utils.ProxyDataVolumeName,
+				MountPath: utils.ProxyDataVolumeMountPath,
+			},
+		},
+	}
+}
+
+// buildProxySQLConf returns the proxysql.cnf. The monitor is disabled, the
+// servers are moved between the hostgroups by the operator following the role
+// and healthy labels of the pods.
+func buildProxySQLConf(c *cluster.Cluster, adminPassword []byte) []byte {
+	conf := fmt.Sprintf(`datadir="%s"
+
+admin_variables=
+{
+    admin_credentials="admin:admin;%s:%s"
+    mysql_ifaces="0.0.0.0:%d"
+}
+
+mysql_variables=
+{
+    threads=2
+    max_connections=2048
+    interfaces="0.0.0.0:%d"
+    server_version="%s"
+    monitor_enabled=false
+    default_query_timeout=36000000
+}
+
+mysql_query_rules=
+(
+    {
+        rule_id=1
+        active=1
+        match_digest="^SELECT.*FOR UPDATE"
+        destination_hostgroup=%d
+        apply=1
+    },
+    {
+        rule_id=2
+        active=1
+        match_digest="^SELECT"
+        destination_hostgroup=%d
+        apply=1
+    }
+)
+`, utils.ProxyDataVolumeMountPath, utils.ProxyAdminUser, utils.BytesToString(adminPassword), utils.ProxyAdminPort,
+		utils.MysqlPort, c.GetMySQLVersion(), proxyWriterHostgroup, proxyReaderHostgroup)
+	return utils.StringToBytes(conf)
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// proxyExcludedUsers are not served by the proxy.
+var proxyExcludedUsers = []string{"root", utils.ReplicationUser, utils.MetricsUser, utils.OperatorUser, utils.BackupUser}
+
+// ProxySQLSyncer configures the servers and users of the proxysql pods. The
+// leader is the writer, the healthy followers are the readers, or the leader
+// if there is none. The users are copied from the leader with their password
+// hashes. The applied configs are recorded on the pods, with the restart
+// count of proxysql which loses them when restarted.
+type ProxySQLSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewProxySQLSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *ProxySQLSyncer {
+	return &ProxySQLSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *ProxySQLSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *ProxySQLSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *ProxySQLSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *ProxySQLSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *ProxySQLSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	if s.Spec.Proxy == nil {
+		return result, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return result, err
+	}
+
+	proxies := corev1.PodList{}
+	if err := s.cli.List(ctx, &proxies, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetProxySelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+	if len(proxies.Items) == 0 {
+		return result, nil
+	}
+
+	servers, users, err := s.getProxyConfigs(ctx, secret)
+	if err != nil {
+		return result, err
+	}
+	serversHash := utils.HashConfigs(servers)
+	usersHash := ""
+	if users != nil {
+		usersHash = utils.HashConfigs(users)
+	}
+
+	var synced []string
+	for i := range proxies.Items {
+		pod := &proxies.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || len(pod.Status.PodIP) == 0 {
+			continue
+		}
+
+		restarts := int32(0)
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == utils.ContainerProxySQLName {
+				restarts = status.RestartCount
+			}
+		}
+		// proxysql loses the configs when restarted.
+		wantServers := fmt.Sprintf("%s-%d", serversHash, restarts)
+		wantUsers := fmt.Sprintf("%s-%d", usersHash, restarts)
+		syncServers := pod.Annotations[utils.ProxyServersHashAnnotation] != wantServers
+		syncUsers := users != nil && pod.Annotations[utils.ProxyUsersHashAnnotation] != wantUsers
+		if !syncServers && !syncUsers {
+			continue
+		}
+
+		if err := s.applyProxyConfigs(secret, pod, servers, users, syncServers, syncUsers); err != nil {
+			s.log.Error(err, "failed to configure the proxysql", "pod", pod.Name)
+			continue
+		}
+
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		if syncServers {
+			pod.Annotations[utils.ProxyServersHashAnnotation] = wantServers
+		}
+		if syncUsers {
+			pod.Annotations[utils.ProxyUsersHashAnnotation] = wantUsers
+		}
+		if err := s.cli.Patch(ctx, pod, patch); err != nil {
+			return result, err
+		}
+		synced = append(synced, pod.Name)
+	}
+
+	if len(synced) > 0 {
+		// the event is recorded only if the operation is not none.
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeNormal, "ProxySQLConfigured",
+			fmt.Sprintf("the servers and users of %s are configured", strings.Join(synced, ", ")))
+	}
+	return result, nil
+}
+
+// getProxyConfigs returns the hostgroups of the servers and the password hashes
+// of the users. The users are nil if they can't be read from the leader.
+func (s *ProxySQLSyncer) getProxyConfigs(ctx context.Context, secret *corev1.Secret) (map[string]string, map[string]string, error) {
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	servers := make(map[string]string)
+	var leader *corev1.Pod
+	readers := 0
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		host := fmt.Sprintf("%s.%s.%s", pod.Name, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+		if pod.Labels["role"] == "leader" {
+			leader = pod
+			servers[host] = fmt.Sprint(proxyWriterHostgroup)
+		} else if pod.Labels["role"] == "follower" && pod.Labels["healthy"] == "yes" {
+			servers[host] = fmt.Sprint(proxyReaderHostgroup)
+			readers++
+		}
+	}
+	if leader == nil {
+		return servers, nil, nil
+	}
+	// the leader serves the reads if there is no healthy follower.
+	if readers == 0 {
+		host := fmt.Sprintf("%s.%s.%s", leader.Name, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+		servers[host] = fmt.Sprintf("%d,%d", proxyWriterHostgroup, proxyReaderHostgroup)
+	}
+
+	runner, err := newOperatorSQLRunner(secret, s.Cluster, leader)
+	if err != nil {
+		s.log.Error(err, "failed to connect the leader", "pod", leader.Name)
+		return servers, nil, nil
+	}
+	defer runner.Close()
+
+	users, err := runner.GetNativePasswordUsers()
+	if err != nil {
+		s.log.Error(err, "failed to get the users", "pod", leader.Name)
+		return servers, nil, nil
+	}
+	for _, user := range proxyExcludedUsers {
+		delete(users, user)
+	}
+	return servers, users, nil
+}
+
+// applyProxyConfigs replaces the servers and users of the proxysql through the admin interface.
+func (s *ProxySQLSyncer) applyProxyConfigs(secret *corev1.Secret, pod *corev1.Pod, servers, users map[string]string,
+	syncServers, syncUsers bool) error {
+	password, ok := secret.Data["proxy-admin-password"]
+	if !ok {
+		return fmt.Errorf("failed to get the proxy admin password from the secret")
+	}
+	runner, err := internal.NewSQLRunner(utils.ProxyAdminUser, utils.BytesToString(password),
+		pod.Status.PodIP, utils.ProxyAdminPort)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+
+	useSSL := 0
+	if s.Spec.TLS != nil {
+		useSSL = 1
+	}
+
+	if syncServers {
+		if err := runner.RunQuery("DELETE FROM mysql_servers"); err != nil {
+			return err
+		}
+		for _, host := range sortedKeys(servers) {
+			for _, hostgroup := range strings.Split(servers[host], ",") {
+				if err := runner.RunQuery("INSERT INTO mysql_servers (hostgroup_id, hostname, port, use_ssl) VALUES (?, ?, ?, ?)",
+					hostgroup, host, utils.MysqlPort, useSSL); err != nil {
+					return err
+				}
+			}
+		}
+		if err := runner.RunQuery("LOAD MYSQL SERVERS TO RUNTIME"); err != nil {
+			return err
+		}
+	}
+
+	if syncUsers {
+		if err := runner.RunQuery("DELETE FROM mysql_users"); err != nil {
+			return err
+		}
+		for _, user := range sortedKeys(users) {
+			if err := runner.RunQuery("INSERT INTO mysql_users (username, password, default_hostgroup) VALUES (?, ?, ?)",
+				user, users[user], proxyWriterHostgroup); err != nil {
+				return err
+			}
+		}
+		if err := runner.RunQuery("LOAD MYSQL USERS TO RUNTIME"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
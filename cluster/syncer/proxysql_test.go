@@ -0,0 +1,97 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetProxyConfigs(t *testing.T) {
+	tests := []struct {
+		name string
+		pods []testRolloutPod
+		want map[string]string
+	}{
+		{
+			name: "leader and followers",
+			pods: []testRolloutPod{{"leader", "yes", ""}, {"follower", "yes", ""}, {"follower", "no", ""}},
+			want: map[string]string{
+				"sample-mysql-0.sample-mysql.default": "10",
+				"sample-mysql-1.sample-mysql.default": "20",
+			},
+		},
+		{
+			name: "leader serves the reads without healthy followers",
+			pods: []testRolloutPod{{"leader", "yes", ""}, {"follower", "no", ""}},
+			want: map[string]string{
+				"sample-mysql-0.sample-mysql.default": "10,20",
+			},
+		},
+		{
+			name: "no leader",
+			pods: []testRolloutPod{{"follower", "yes", ""}, {"candidate", "no", ""}},
+			want: map[string]string{
+				"sample-mysql-0.sample-mysql.default": "20",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			var objs []client.Object
+			for i, p := range tt.pods {
+				objs = append(objs, newTestPod(c, i, p.role, p.healthy))
+			}
+			cli := newFakeClient(c, objs...)
+
+			// the users can't be read without the operator user.
+			servers, users, err := NewProxySQLSyncer(testLog, cli, c).getProxyConfigs(context.TODO(), &corev1.Secret{})
+			if err != nil {
+				t.Fatalf("getProxyConfigs() error = %v", err)
+			}
+			if !reflect.DeepEqual(servers, tt.want) {
+				t.Errorf("servers = %v, want %v", servers, tt.want)
+			}
+			if users != nil {
+				t.Errorf("users = %v, want nil", users)
+			}
+		})
+	}
+}
+
+func TestBuildProxySQLConf(t *testing.T) {
+	c := newTestCluster()
+	c.Spec.MysqlVersion = "8.0"
+	conf := string(buildProxySQLConf(c, []byte("secret")))
+
+	for _, want := range []string{
+		`admin_credentials="admin:admin;radmin:secret"`,
+		`server_version="8.0.25"`,
+		`monitor_enabled=false`,
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("proxysql.cnf does not contain %s:\n%s", want, conf)
+		}
+	}
+}
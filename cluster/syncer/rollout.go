@@ -0,0 +1,311 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// switchoverBackoff is the wait before retrying a failed switchover of the
+	// outdated leader, it doubles after every failure up to maxSwitchoverBackoff.
+	switchoverBackoff    = time.Minute
+	maxSwitchoverBackoff = 16 * time.Minute
+)
+
+// RolloutSyncer rolls the statefulset out pod by pod. The statefulset uses the
+// OnDelete strategy, the outdated followers are deleted one by one once all
+// the pods are healthy, and the leader is switched over before being deleted.
+type RolloutSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewRolloutSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *RolloutSyncer {
+	return &RolloutSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *RolloutSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *RolloutSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *RolloutSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *RolloutSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *RolloutSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+
+	sts := &appsv1.StatefulSet{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.StatefulSet),
+	}, sts); err != nil {
+		if errors.IsNotFound(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	revision := sts.Status.UpdateRevision
+	if len(revision) == 0 {
+		return result, nil
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	var updated int32
+	var outdated []*corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels[appsv1.ControllerRevisionHashLabelKey] == revision {
+			updated++
+		} else {
+			outdated = append(outdated, pod)
+		}
+	}
+
+	if len(outdated) == 0 {
+		if s.Status.Rollout != nil && s.Status.Rollout.Phase == apiv1.RolloutUpdating {
+			s.updateRollout(apiv1.RolloutCompleted, revision, updated, "", "all the pods are updated")
+			s.setEvent(&result, corev1.EventTypeNormal, "RolloutCompleted",
+				fmt.Sprintf("all the pods are updated to %s", revision))
+		}
+		return result, nil
+	}
+
+	if s.Status.Rollout == nil || s.Status.Rollout.Phase != apiv1.RolloutUpdating ||
+		s.Status.Rollout.UpdateRevision != revision {
+		s.updateRollout(apiv1.RolloutUpdating, revision, updated, "", "")
+		s.setEvent(&result, corev1.EventTypeNormal, "RolloutStarted",
+			fmt.Sprintf("updating %d pods to %s", len(outdated), revision))
+	}
+
+	// update only one pod at a time, and only if all the pods are healthy.
+	if int32(len(pods.Items)) < *s.Spec.Replicas {
+		s.updateRollout(apiv1.RolloutUpdating, revision, updated, s.Status.Rollout.Current, "waiting for all the pods to be created")
+		return result, nil
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Labels["healthy"] != "yes" {
+			s.updateRollout(apiv1.RolloutUpdating, revision, updated, s.Status.Rollout.Current,
+				fmt.Sprintf("waiting for %s to be healthy", pod.Name))
+			return result, nil
+		}
+	}
+	for i := range pods.Items {
+		if pod := &pods.Items[i]; s.NeedMysqlUpgrade(pod) {
+			s.updateRollout(apiv1.RolloutUpdating, revision, updated, s.Status.Rollout.Current,
+				fmt.Sprintf("waiting for mysql_upgrade on %s", pod.Name))
+			return result, nil
+		}
+	}
+	if sw := s.Status.Switchover; sw != nil && sw.Phase == apiv1.SwitchoverInProgress {
+		s.updateRollout(apiv1.RolloutUpdating, revision, updated, s.Status.Rollout.Current,
+			"waiting for the switchover")
+		return result, nil
+	}
+
+	// update the pods with the higher ordinal first, the same as the statefulset controller.
+	sort.Slice(outdated, func(i, j int) bool {
+		return outdated[i].Name > outdated[j].Name
+	})
+
+	var leader *corev1.Pod
+	for _, pod := range outdated {
+		if pod.Labels["role"] == "leader" {
+			leader = pod
+			continue
+		}
+		return result, s.deletePod(ctx, pod, revision, updated)
+	}
+
+	// only the leader is outdated, move the leadership to a follower which has
+	// caught up with it.
+	if *s.Spec.Replicas > 1 {
+		return result, s.switchLeader(ctx, &result, leader, pods.Items, revision, updated)
+	}
+
+	return result, s.deletePod(ctx, leader, revision, updated)
+}
+
+// switchLeader requests a switchover from the outdated leader to a follower
+// which has executed all the transactions of the leader. The failed
+// switchovers are counted and retried after a backoff.
+func (s *RolloutSyncer) switchLeader(ctx context.Context, result *syncer.SyncResult, leader *corev1.Pod,
+	pods []corev1.Pod, revision string, updated int32) error {
+	rollout := s.Status.Rollout
+	if _, ok := s.Annotations[utils.SwitchoverAnnotation]; ok {
+		s.updateRollout(apiv1.RolloutUpdating, revision, updated, leader.Name, "waiting for the switchover")
+		return nil
+	}
+
+	// the requested switchover is done, but the leader is still outdated.
+	if len(rollout.SwitchoverTarget) != 0 {
+		now := metav1.Now()
+		rollout.SwitchoverFailures++
+		rollout.LastSwitchoverFailureTime = &now
+		s.setEvent(result, corev1.EventTypeWarning, "RolloutSwitchoverFailed",
+			fmt.Sprintf("failed to switch over from %s to %s", leader.Name, rollout.SwitchoverTarget))
+		rollout.SwitchoverTarget = ""
+	}
+	if rollout.SwitchoverFailures > 0 && rollout.LastSwitchoverFailureTime != nil {
+		backoff := switchoverBackoff << (rollout.SwitchoverFailures - 1)
+		if backoff <= 0 || backoff > maxSwitchoverBackoff {
+			backoff = maxSwitchoverBackoff
+		}
+		if wait := backoff - time.Since(rollout.LastSwitchoverFailureTime.Time); wait > 0 {
+			s.updateRollout(apiv1.RolloutUpdating, revision, updated, leader.Name,
+				fmt.Sprintf("%d switchovers failed, retrying in %s", rollout.SwitchoverFailures, wait.Round(time.Second)))
+			return nil
+		}
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return err
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Labels["role"] != "follower" {
+			continue
+		}
+		ok, err := caughtUp(secret, s.Cluster, leader, pod)
+		if err != nil {
+			s.log.Error(err, "failed to check the gtid", "pod", pod.Name)
+		}
+		if ok {
+			rollout.SwitchoverTarget = pod.Name
+			s.updateRollout(apiv1.RolloutUpdating, revision, updated, leader.Name,
+				fmt.Sprintf("switching over from %s to %s", leader.Name, pod.Name))
+			return s.requestSwitchover(ctx, pod.Name)
+		}
+	}
+
+	s.updateRollout(apiv1.RolloutUpdating, revision, updated, leader.Name,
+		fmt.Sprintf("waiting for a follower to catch up with %s", leader.Name))
+	return nil
+}
+
+func (s *RolloutSyncer) deletePod(ctx context.Context, pod *corev1.Pod, revision string, updated int32) error {
+	s.log.Info("deleting the outdated pod", "pod", pod.Name, "revision", revision)
+	s.updateRollout(apiv1.RolloutUpdating, revision, updated, pod.Name, fmt.Sprintf("updating %s", pod.Name))
+	return client.IgnoreNotFound(s.cli.Delete(ctx, pod))
+}
+
+// requestSwitchover annotates the cluster to switch the leadership over to the pod.
+func (s *RolloutSyncer) requestSwitchover(ctx context.Context, target string) error {
+	// patch a copy, the response would overwrite the status being updated.
+	obj := s.Unwrap().DeepCopy()
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[utils.SwitchoverAnnotation] = target
+	if err := s.cli.Patch(ctx, obj, client.MergeFrom(s.Unwrap())); err != nil {
+		return err
+	}
+	s.Annotations = obj.Annotations
+	s.ResourceVersion = obj.ResourceVersion
+	return nil
+}
+
+func (s *RolloutSyncer) updateRollout(phase apiv1.RolloutPhase, revision string, updated int32, current, msg string) {
+	if s.Status.Rollout == nil {
+		s.Status.Rollout = &apiv1.RolloutStatus{}
+	}
+	rollout := s.Status.Rollout
+	if rollout.Phase != phase || rollout.UpdateRevision != revision {
+		now := metav1.Now()
+		rollout.LastTransitionTime = &now
+		rollout.SwitchoverTarget = ""
+		rollout.SwitchoverFailures = 0
+		rollout.LastSwitchoverFailureTime = nil
+	}
+	rollout.Phase = phase
+	rollout.UpdateRevision = revision
+	rollout.UpdatedReplicas = updated
+	rollout.Current = current
+	rollout.Message = msg
+}
+
+func (s *RolloutSyncer) setEvent(result *syncer.SyncResult, eventType, reason, msg string) {
+	// the event is recorded only if the operation is not none.
+	result.Operation = controllerutil.OperationResultUpdated
+	result.SetEventData(eventType, reason, msg)
+}
+
+// caughtUp returns true if the follower has executed all the transactions of
+// the leader, it is a variable to be replaced in the tests.
+var caughtUp = func(secret *corev1.Secret, c *cluster.Cluster, leader, follower *corev1.Pod) (bool, error) {
+	runner, err := newOperatorSQLRunner(secret, c, leader)
+	if err != nil {
+		return false, err
+	}
+	defer runner.Close()
+
+	var gtid string
+	if err = runner.GetGlobalVariable("gtid_executed", &gtid); err != nil {
+		return false, err
+	}
+
+	followerRunner, err := newOperatorSQLRunner(secret, c, follower)
+	if err != nil {
+		return false, err
+	}
+	defer followerRunner.Close()
+	return followerRunner.CheckGtidSubset(gtid)
+}
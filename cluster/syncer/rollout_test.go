@@ -0,0 +1,322 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// testRolloutPod describes a pod of the rollout tests.
+type testRolloutPod struct {
+	role     string
+	healthy  string
+	revision string
+}
+
+func TestRolloutSync(t *testing.T) {
+	tests := []struct {
+		name       string
+		replicas   int32
+		pods       []testRolloutPod
+		rollout    *apiv1.RolloutStatus
+		switchover *apiv1.SwitchoverStatus
+		// the switchover requested on the cluster.
+		annotation string
+		// the followers which have caught up with the leader.
+		caughtUp     []string
+		wantPhase    apiv1.RolloutPhase
+		wantFailures int32
+		wantReason   string
+		wantMsg      string
+		// the pod expected to be deleted.
+		wantDeleted string
+		// the target of the requested switchover.
+		wantSwitchover string
+	}{
+		{
+			name:     "up to date",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-2"},
+				{"follower", "yes", "rev-2"},
+			},
+		},
+		{
+			name:     "completed",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-2"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:    &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			wantPhase:  apiv1.RolloutCompleted,
+			wantReason: "RolloutCompleted",
+			wantMsg:    "all the pods are updated",
+		},
+		{
+			name:     "followers first, the higher ordinal first",
+			replicas: 3,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-1"},
+				{"follower", "yes", "rev-1"},
+			},
+			wantPhase:   apiv1.RolloutUpdating,
+			wantReason:  "RolloutStarted",
+			wantMsg:     "updating sample-mysql-2",
+			wantDeleted: "sample-mysql-2",
+		},
+		{
+			name:     "waiting for the pods to be healthy",
+			replicas: 3,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-1"},
+				{"follower", "no", "rev-2"},
+			},
+			rollout:   &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			wantPhase: apiv1.RolloutUpdating,
+			wantMsg:   "waiting for sample-mysql-2 to be healthy",
+		},
+		{
+			name:     "waiting for the pods to be created",
+			replicas: 3,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:   &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			wantPhase: apiv1.RolloutUpdating,
+			wantMsg:   "waiting for all the pods to be created",
+		},
+		{
+			name:     "waiting for the switchover",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:    &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			switchover: &apiv1.SwitchoverStatus{Target: "sample-mysql-1", Phase: apiv1.SwitchoverInProgress},
+			wantPhase:  apiv1.RolloutUpdating,
+			wantMsg:    "waiting for the switchover",
+		},
+		{
+			name:     "leader last",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:        &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			caughtUp:       []string{"sample-mysql-1"},
+			wantPhase:      apiv1.RolloutUpdating,
+			wantMsg:        "switching over from sample-mysql-0 to sample-mysql-1",
+			wantSwitchover: "sample-mysql-1",
+		},
+		{
+			name:     "switch over to a follower which caught up",
+			replicas: 3,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:        &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			caughtUp:       []string{"sample-mysql-2"},
+			wantPhase:      apiv1.RolloutUpdating,
+			wantMsg:        "switching over from sample-mysql-0 to sample-mysql-2",
+			wantSwitchover: "sample-mysql-2",
+		},
+		{
+			name:     "waiting for a follower to catch up",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout:   &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2"},
+			wantPhase: apiv1.RolloutUpdating,
+			wantMsg:   "waiting for a follower to catch up with sample-mysql-0",
+		},
+		{
+			name:     "waiting for the requested switchover",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout: &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2",
+				SwitchoverTarget: "sample-mysql-1"},
+			annotation:     "sample-mysql-1",
+			caughtUp:       []string{"sample-mysql-1"},
+			wantPhase:      apiv1.RolloutUpdating,
+			wantMsg:        "waiting for the switchover",
+			wantSwitchover: "sample-mysql-1",
+		},
+		{
+			name:     "switchover failed",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout: &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2",
+				SwitchoverTarget: "sample-mysql-1"},
+			switchover:   &apiv1.SwitchoverStatus{Target: "sample-mysql-1", Phase: apiv1.SwitchoverFailed},
+			caughtUp:     []string{"sample-mysql-1"},
+			wantPhase:    apiv1.RolloutUpdating,
+			wantFailures: 1,
+			wantReason:   "RolloutSwitchoverFailed",
+			wantMsg:      "1 switchovers failed, retrying in 1m0s",
+		},
+		{
+			name:     "switchover retried after the backoff",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout: &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-2",
+				SwitchoverFailures: 2, LastSwitchoverFailureTime: timeAgo(3 * time.Minute)},
+			switchover:     &apiv1.SwitchoverStatus{Target: "sample-mysql-1", Phase: apiv1.SwitchoverFailed},
+			caughtUp:       []string{"sample-mysql-1"},
+			wantPhase:      apiv1.RolloutUpdating,
+			wantFailures:   2,
+			wantMsg:        "switching over from sample-mysql-0 to sample-mysql-1",
+			wantSwitchover: "sample-mysql-1",
+		},
+		{
+			name:     "new revision resets the failures",
+			replicas: 2,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+				{"follower", "yes", "rev-2"},
+			},
+			rollout: &apiv1.RolloutStatus{Phase: apiv1.RolloutUpdating, UpdateRevision: "rev-1",
+				SwitchoverFailures: 3, LastSwitchoverFailureTime: timeAgo(time.Minute)},
+			caughtUp:       []string{"sample-mysql-1"},
+			wantPhase:      apiv1.RolloutUpdating,
+			wantReason:     "RolloutStarted",
+			wantMsg:        "switching over from sample-mysql-0 to sample-mysql-1",
+			wantSwitchover: "sample-mysql-1",
+		},
+		{
+			name:     "single replica",
+			replicas: 1,
+			pods: []testRolloutPod{
+				{"leader", "yes", "rev-1"},
+			},
+			wantPhase:   apiv1.RolloutUpdating,
+			wantReason:  "RolloutStarted",
+			wantMsg:     "updating sample-mysql-0",
+			wantDeleted: "sample-mysql-0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			c.Spec.Replicas = &tt.replicas
+			c.Status.Rollout = tt.rollout
+			c.Status.Switchover = tt.switchover
+			if len(tt.annotation) != 0 {
+				c.Annotations[utils.SwitchoverAnnotation] = tt.annotation
+			}
+			stubCaughtUp(t, tt.caughtUp...)
+
+			objs := []client.Object{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: c.GetNameForResource(utils.StatefulSet), Namespace: c.Namespace},
+				Status:     appsv1.StatefulSetStatus{UpdateRevision: "rev-2"},
+			}, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: c.GetNameForResource(utils.Secret), Namespace: c.Namespace},
+			}}
+			for i, p := range tt.pods {
+				pod := newTestPod(c, i, p.role, p.healthy)
+				pod.Labels[appsv1.ControllerRevisionHashLabelKey] = p.revision
+				objs = append(objs, pod)
+			}
+			cli := newFakeClient(c, objs...)
+
+			result, err := NewRolloutSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			var phase apiv1.RolloutPhase
+			var msg string
+			var failures int32
+			if c.Status.Rollout != nil {
+				phase, msg, failures = c.Status.Rollout.Phase, c.Status.Rollout.Message, c.Status.Rollout.SwitchoverFailures
+			}
+			if phase != tt.wantPhase || msg != tt.wantMsg {
+				t.Errorf("rollout = %q, %q, want %q, %q", phase, msg, tt.wantPhase, tt.wantMsg)
+			}
+			if failures != tt.wantFailures {
+				t.Errorf("switchover failures = %d, want %d", failures, tt.wantFailures)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+			for i := range tt.pods {
+				name := newTestPod(c, i, "", "").Name
+				err := cli.Get(context.TODO(), client.ObjectKey{Namespace: c.Namespace, Name: name}, &corev1.Pod{})
+				if deleted := errors.IsNotFound(err); deleted != (name == tt.wantDeleted) {
+					t.Errorf("%s deleted = %v, want %v", name, deleted, !deleted)
+				}
+			}
+			if got := getSwitchoverAnnotation(t, cli, c); got != tt.wantSwitchover {
+				t.Errorf("switchover = %q, want %q", got, tt.wantSwitchover)
+			}
+		})
+	}
+}
+
+// getSwitchoverAnnotation returns the switchover requested on the cluster.
+func getSwitchoverAnnotation(t *testing.T, cli client.Client, c *cluster.Cluster) string {
+	obj := &apiv1.Cluster{}
+	if err := cli.Get(context.TODO(), client.ObjectKey{Namespace: c.Namespace, Name: c.Name}, obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj.Annotations[utils.SwitchoverAnnotation]
+}
+
+// stubCaughtUp reports only the followers as caught up with the leader.
+func stubCaughtUp(t *testing.T, followers ...string) {
+	old := caughtUp
+	caughtUp = func(secret *corev1.Secret, c *cluster.Cluster, leader, follower *corev1.Pod) (bool, error) {
+		for _, name := range followers {
+			if follower.Name == name {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	t.Cleanup(func() { caughtUp = old })
+}
@@ -17,12 +17,14 @@ limitations under the License.
 package syncer
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/presslabs/controller-util/rand"
 	"github.com/presslabs/controller-util/syncer"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/zhyass/mysql-operator/cluster"
@@ -56,8 +58,7 @@ func NewSecretSyncer(cli client.Client, c *cluster.Cluster) syncer.Interface {
 		}
 
 		if c.Spec.MetricsOpts.Enabled {
-			dataSource := fmt.Sprintf("%s:%s@(localhost:3306)/", utils.MetricsUser, utils.BytesToString(secret.Data["metrics-password"]))
-			secret.Data["data-source"] = []byte(dataSource)
+			secret.Data["data-source"] = buildDataSource(c, secret.Data["metrics-password"])
 		}
 
 		secret.Data["replication-user"] = []byte(utils.ReplicationUser)
@@ -65,15 +66,88 @@ func NewSecretSyncer(cli client.Client, c *cluster.Cluster) syncer.Interface {
 			return err
 		}
 
-		secret.Data["root-password"] = []byte(c.Spec.MysqlOpts.RootPassword)
+		secret.Data["operator-user"] = []byte(utils.OperatorUser)
+		if err := addRandomPassword(secret.Data, "operator-password"); err != nil {
+			return err
+		}
+
+		if c.Spec.Proxy != nil {
+			if err := addRandomPassword(secret.Data, "proxy-admin-password"); err != nil {
+				return err
+			}
+			secret.Data["proxysql.cnf"] = buildProxySQLConf(c, secret.Data["proxy-admin-password"])
+		}
+
+		secret.Data["backup-user"] = []byte(utils.BackupUser)
+		if err := addRandomPassword(secret.Data, "backup-password"); err != nil {
+			return err
+		}
+
+		// the passwords are only applied when mysql is initialized, and the root
+		// password may have been rotated, so they are not changed once set.
+		if err := addPassword(cli, c, secret.Data, "root-password",
+			c.Spec.MysqlOpts.RootPassword, c.Spec.MysqlOpts.RootPasswordSecretRef); err != nil {
+			return err
+		}
 
 		secret.Data["mysql-user"] = []byte(c.Spec.MysqlOpts.User)
-		secret.Data["mysql-password"] = []byte(c.Spec.MysqlOpts.Password)
+		if err := addPassword(cli, c, secret.Data, "mysql-password",
+			c.Spec.MysqlOpts.Password, c.Spec.MysqlOpts.UserPasswordSecretRef); err != nil {
+			return err
+		}
 		secret.Data["mysql-database"] = []byte(c.Spec.MysqlOpts.Database)
 		return nil
 	})
 }
 
+// buildDataSource returns the dsn of the metrics exporter.
+func buildDataSource(c *cluster.Cluster, password []byte) []byte {
+	dsn := fmt.Sprintf("%s:%s@(localhost:3306)/", utils.MetricsUser, utils.BytesToString(password))
+	// the exporter connects through tcp, which may require tls.
+	if c.Spec.TLS != nil {
+		dsn += "?tls=skip-verify"
+	}
+	return []byte(dsn)
+}
+
+// addPassword registers the password for the key if it does not exist. The
+// password is read from the referenced secret, or the deprecated plaintext
+// field, or generated randomly.
+func addPassword(cli client.Client, c *cluster.Cluster, data map[string][]byte, key, password string,
+	ref *corev1.SecretKeySelector) error {
+	if _, ok := data[key]; ok {
+		return nil
+	}
+
+	if ref != nil {
+		secret := &corev1.Secret{}
+		if err := cli.Get(context.TODO(), types.NamespacedName{
+			Namespace: c.Namespace,
+			Name:      ref.Name,
+		}, secret); err != nil {
+			return fmt.Errorf("failed to get the secret %s: %s", ref.Name, err)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok || len(value) == 0 {
+			return fmt.Errorf("the key %s of the secret %s is empty", ref.Key, ref.Name)
+		}
+		if !utils.IsAlphaNumeric(utils.BytesToString(value)) {
+			return fmt.Errorf("the key %s of the secret %s must be alpha-numeric", ref.Key, ref.Name)
+		}
+		data[key] = value
+		return nil
+	}
+
+	if len(password) != 0 {
+		if !utils.IsAlphaNumeric(password) {
+			return fmt.Errorf("the password for %s must be alpha-numeric", key)
+		}
+		data[key] = []byte(password)
+		return nil
+	}
+	return addRandomPassword(data, key)
+}
+
 // addRandomPassword checks if a key exists and if not registers a random string for that key
 func addRandomPassword(data map[string][]byte, key string) error {
 	if len(data[key]) == 0 {
@@ -0,0 +1,119 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAddPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		password string
+		ref      *corev1.SecretKeySelector
+		want     string
+		// the password is generated randomly.
+		wantRandom bool
+		wantErr    bool
+	}{
+		{
+			name:     "existing password is kept",
+			existing: "old",
+			password: "new",
+			want:     "old",
+		},
+		{
+			name: "from the secret",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "passwords"},
+				Key:                  "root",
+			},
+			want: "fromSecret",
+		},
+		{
+			name: "empty key of the secret",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "passwords"},
+				Key:                  "empty",
+			},
+			wantErr: true,
+		},
+		{
+			name: "non alpha-numeric key of the secret",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "passwords"},
+				Key:                  "quoted",
+			},
+			wantErr: true,
+		},
+		{
+			name: "secret not found",
+			ref: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+				Key:                  "root",
+			},
+			wantErr: true,
+		},
+		{
+			name:     "from the plaintext field",
+			password: "plain",
+			want:     "plain",
+		},
+		{
+			name:     "non alpha-numeric plaintext field",
+			password: "pass'word",
+			wantErr:  true,
+		},
+		{
+			name:       "generated",
+			wantRandom: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			cli := newFakeClient(c, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "passwords", Namespace: c.Namespace},
+				Data:       map[string][]byte{"root": []byte("fromSecret"), "empty": {}, "quoted": []byte("pass'word")},
+			})
+			data := map[string][]byte{}
+			if len(tt.existing) != 0 {
+				data["root-password"] = []byte(tt.existing)
+			}
+
+			err := addPassword(cli, c, data, "root-password", tt.password, tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("addPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := string(data["root-password"])
+			if tt.wantRandom {
+				if len(got) != rStrLen {
+					t.Errorf("addPassword() = %q, want a random password", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("addPassword() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,7 +17,11 @@ limitations under the License.
 package syncer
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"time"
 
 	"github.com/imdario/mergo"
 	"github.com/presslabs/controller-util/mergo/transformers"
@@ -25,6 +29,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/zhyass/mysql-operator/cluster"
@@ -48,6 +53,10 @@ func NewStatefulSetSyncer(cli client.Client, c *cluster.Cluster) syncer.Interfac
 		obj.Spec.ServiceName = c.GetNameForResource(utils.StatefulSet)
 		obj.Spec.Replicas = c.Spec.Replicas
 		obj.Spec.Selector = metav1.SetAsLabelSelector(c.GetSelectorLabels())
+		// the pods are restarted by the operator, followers first and the leader last.
+		obj.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+			Type: appsv1.OnDeleteStatefulSetStrategyType,
+		}
 
 		obj.Spec.Template.ObjectMeta.Labels = c.GetLabels()
 		for k, v := range c.Spec.PodSpec.Labels {
@@ -56,14 +65,30 @@ func NewStatefulSetSyncer(cli client.Client, c *cluster.Cluster) syncer.Interfac
 		obj.Spec.Template.ObjectMeta.Labels["role"] = "candidate"
 		obj.Spec.Template.ObjectMeta.Labels["healthy"] = "no"
 
-		obj.Spec.Template.Annotations = c.Spec.PodSpec.Annotations
-		if len(obj.Spec.Template.ObjectMeta.Annotations) == 0 {
-			obj.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		// copy the annotations, the ones added below must not leak into the spec.
+		obj.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
+		for k, v := range c.Spec.PodSpec.Annotations {
+			obj.Spec.Template.ObjectMeta.Annotations[k] = v
 		}
 		if c.Spec.MetricsOpts.Enabled {
 			obj.Spec.Template.ObjectMeta.Annotations["prometheus.io/scrape"] = "true"
 			obj.Spec.Template.ObjectMeta.Annotations["prometheus.io/port"] = fmt.Sprintf("%d", utils.MetricsPort)
 		}
+		// restart the pods to apply the static configs.
+		obj.Spec.Template.ObjectMeta.Annotations[utils.StaticConfigHashAnnotation] = utils.HashConfigs(c.GetStaticMysqlConf())
+
+		// restart the pods to use the renewed certificate.
+		if c.Spec.TLS != nil {
+			hash, err := getTLSHash(cli, c)
+			if err != nil {
+				return err
+			}
+			obj.Spec.Template.ObjectMeta.Annotations[utils.TLSHashAnnotation] = hash
+		}
+		// restart the pods to use the rotated passwords.
+		if t := c.Status.LastCredentialRotationTime; t != nil {
+			obj.Spec.Template.ObjectMeta.Annotations[utils.CredentialsRotatedAnnotation] = t.UTC().Format(time.RFC3339)
+		}
 
 		err := mergo.Merge(&obj.Spec.Template.Spec, ensurePodSpec(c), mergo.WithTransformers(transformers.PodSpec))
 		if err != nil {
@@ -72,7 +97,8 @@ func NewStatefulSetSyncer(cli client.Client, c *cluster.Cluster) syncer.Interfac
 		// mergo will add new keys for Tolerations and keep the others instead of removing them
 		obj.Spec.Template.Spec.Tolerations = c.Spec.PodSpec.Tolerations
 
-		if c.Spec.Persistence.Enabled {
+		// the templates are immutable, the expansion recreates the statefulset.
+		if c.Spec.Persistence.Enabled && obj.CreationTimestamp.IsZero() {
 			if obj.Spec.VolumeClaimTemplates, err = c.EnsureVolumeClaimTemplates(cli.Scheme()); err != nil {
 				return err
 			}
@@ -82,13 +108,17 @@ func NewStatefulSetSyncer(cli client.Client, c *cluster.Cluster) syncer.Interfac
 }
 
 func ensurePodSpec(c *cluster.Cluster) corev1.PodSpec {
+	// the clone must finish before init-sidecar, which enables the restore.sql of the cloned data.
+	initClone := container.EnsureContainer(utils.ContainerInitCloneName, c)
 	initSidecar := container.EnsureContainer(utils.ContainerInitSidecarName, c)
 	initMysql := container.EnsureContainer(utils.ContainerInitMysqlName, c)
-	initContainers := []corev1.Container{initSidecar, initMysql}
+	initContainers := []corev1.Container{initClone, initSidecar, initMysql}
 
 	mysql := container.EnsureContainer(utils.ContainerMysqlName, c)
 	xenon := container.EnsureContainer(utils.ContainerXenonName, c)
-	containers := []corev1.Container{mysql, xenon}
+	backup := container.EnsureContainer(utils.ContainerBackupName, c)
+	sidecar := container.EnsureContainer(utils.ContainerSidecarName, c)
+	containers := []corev1.Container{mysql, xenon, backup, sidecar}
 	if c.Spec.MetricsOpts.Enabled {
 		containers = append(containers, container.EnsureContainer(utils.ContainerMetricsName, c))
 	}
@@ -98,15 +128,40 @@ func ensurePodSpec(c *cluster.Cluster) corev1.PodSpec {
 	if c.Spec.PodSpec.SlowLogTail {
 		containers = append(containers, container.EnsureContainer(utils.ContainerAuditLogName, c))
 	}
+	if c.Spec.BinlogArchive != nil {
+		containers = append(containers, container.EnsureContainer(utils.ContainerBinlogArchiverName, c))
+	}
 
 	return corev1.PodSpec{
-		InitContainers:     initContainers,
-		Containers:         containers,
-		Volumes:            c.EnsureVolumes(),
-		SchedulerName:      c.Spec.PodSpec.SchedulerName,
-		ServiceAccountName: c.GetNameForResource(utils.ServiceAccount),
-		Affinity:           c.Spec.PodSpec.Affinity,
-		PriorityClassName:  c.Spec.PodSpec.PriorityClassName,
-		Tolerations:        c.Spec.PodSpec.Tolerations,
+		InitContainers:            initContainers,
+		Containers:                containers,
+		Volumes:                   c.EnsureVolumes(),
+		SchedulerName:             c.Spec.PodSpec.SchedulerName,
+		ServiceAccountName:        c.GetNameForResource(utils.ServiceAccount),
+		Affinity:                  c.GetAffinity(),
+		TopologySpreadConstraints: c.GetTopologySpreadConstraints(),
+		PriorityClassName:         c.Spec.PodSpec.PriorityClassName,
+		Tolerations:               c.Spec.PodSpec.Tolerations,
+	}
+}
+
+// getTLSHash returns the hash of the certificate in the secret.
+func getTLSHash(cli client.Client, c *cluster.Cluster) (string, error) {
+	secret := &corev1.Secret{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{
+		Namespace: c.Namespace,
+		Name:      c.GetTLSSecretName(),
+	}, secret); err != nil {
+		return "", fmt.Errorf("failed to get the tls secret %s: %s", c.GetTLSSecretName(), err)
+	}
+
+	hash := sha256.New()
+	for _, key := range []string{"ca.crt", "tls.crt", "tls.key"} {
+		data, ok := secret.Data[key]
+		if !ok {
+			return "", fmt.Errorf("the key %s of the tls secret %s is empty", key, c.GetTLSSecretName())
+		}
+		hash.Write(data)
 	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
 }
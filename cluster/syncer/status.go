@@ -161,6 +161,9 @@ func (s *StatusUpdater) updateNodeStatus(ctx context.Context, cli client.Client,
 	if !ok {
 		return fmt.Errorf("failed to get the password: %s", password)
 	}
+	// the sidecar is authenticated by the backup user.
+	backupUser := utils.BytesToString(secret.Data["backup-user"])
+	backupPassword := utils.BytesToString(secret.Data["backup-password"])
 
 	for _, pod := range pods {
 		podName := pod.Name
@@ -169,49 +172,48 @@ func (s *StatusUpdater) updateNodeStatus(ctx context.Context, cli client.Client,
 		node := &s.Status.Nodes[index]
 		node.Message = ""
 
-		isLeader, err := checkRole(nameSpace, podName)
+		byExec := false
+		api := internal.NewSidecarClient(backupUser, backupPassword, host, utils.SidecarAPIPort)
+		state, err := api.GetNodeState()
 		if err != nil {
-			s.log.Error(err, "failed to check the node role", "node", node.Name)
-			node.Message = err.Error()
+			// the pods created by the old versions have no sidecar api.
+			s.log.V(1).Info("failed to request the sidecar api, fall back to exec", "node", node.Name, "error", err.Error())
+			state = s.checkNodeByExec(podName, host, port, user, password)
+			byExec = true
+		} else if len(state.Message) != 0 {
+			s.log.Info("failed to check the node", "node", node.Name, "error", state.Message)
 		}
-		// update apiv1.NodeConditionLeader.
-		s.updateNodeCondition(node, 1, isLeader)
-
-		isLagged, isReplicating, isReadOnly := corev1.ConditionUnknown, corev1.ConditionUnknown, corev1.ConditionUnknown
-		runner, err := internal.NewSQLRunner(utils.BytesToString(user), utils.BytesToString(password), host, port)
-		if err != nil {
-			s.log.Error(err, "failed to connect the mysql", "node", node.Name)
-			node.Message = err.Error()
-		} else {
-			isLagged, isReplicating, err = runner.CheckSlaveStatusWithRetry(checkNodeStatusRetry)
-			if err != nil {
-				s.log.Error(err, "failed to check slave status", "node", node.Name)
-				node.Message = err.Error()
-			}
+		node.Message = state.Message
 
-			isReadOnly, err = runner.CheckReadOnly()
-			if err != nil {
-				s.log.Error(err, "failed to check read only", "node", node.Name)
-				node.Message = err.Error()
-			}
-		}
-		if runner != nil {
-			runner.Close()
+		sidecar := internal.NewSidecarClient(backupUser, backupPassword, host, utils.SidecarHTTPPort)
+		if node.DiskUsage, err = sidecar.GetDiskUsage(); err != nil {
+			// the sidecar may not be ready yet, eg: during the rollout.
+			s.log.V(1).Info("failed to get the disk usage", "node", node.Name, "error", err.Error())
 		}
 
-		if isLeader == corev1.ConditionTrue && isReadOnly != corev1.ConditionFalse {
+		// the leader is kept read-only by the disk protection.
+		if state.Leader == corev1.ConditionTrue && state.ReadOnly != corev1.ConditionFalse && !s.Status.ReadOnly {
 			s.log.V(1).Info("try to correct the leader writeable", "node", node.Name)
-			correctLeaderReadOnly(nameSpace, podName)
+			if byExec {
+				err = correctLeaderReadOnly(nameSpace, podName)
+			} else {
+				err = api.SetLeaderWritable()
+			}
+			if err != nil {
+				s.log.Error(err, "failed to correct the leader writeable", "node", node.Name)
+			}
 		}
 
 		// update apiv1.NodeConditionLagged.
-		s.updateNodeCondition(node, 0, isLagged)
+		s.updateNodeCondition(node, 0, state.Lagged)
+		// update apiv1.NodeConditionLeader.
+		s.updateNodeCondition(node, 1, state.Leader)
 		// update apiv1.NodeConditionReplicating.
-		s.updateNodeCondition(node, 3, isReplicating)
+		s.updateNodeCondition(node, 3, state.Replicating)
 		// update apiv1.NodeConditionReadOnly.
-		s.updateNodeCondition(node, 2, isReadOnly)
+		s.updateNodeCondition(node, 2, state.ReadOnly)
 
-		if err = setPodHealthy(ctx, cli, &pod, node); err != nil {
+		if err = setPodHealthy(ctx, cli, &pod, node, s.Status.ReadOnly); err != nil {
 			s.log.Error(err, "cannot update pod", "name", podName, "namespace", pod.Namespace)
 		}
 	}
@@ -219,6 +221,41 @@ func (s *StatusUpdater) updateNodeStatus(ctx context.Context, cli client.Client,
 	return nil
 }
 
+// checkNodeByExec checks the role by running xenoncli in the xenon container,
+// and the replication by connecting the mysql with the metrics user.
+func (s *StatusUpdater) checkNodeByExec(podName, host string, port int, user, password []byte) *internal.NodeState {
+	state := &internal.NodeState{
+		Leader:      corev1.ConditionUnknown,
+		ReadOnly:    corev1.ConditionUnknown,
+		Replicating: corev1.ConditionUnknown,
+		Lagged:      corev1.ConditionUnknown,
+	}
+
+	var err error
+	if state.Leader, err = checkRole(s.Namespace, podName); err != nil {
+		s.log.Error(err, "failed to check the node role", "node", host)
+		state.Message = err.Error()
+	}
+
+	runner, err := internal.NewSQLRunner(utils.BytesToString(user), utils.BytesToString(password), host, port)
+	if err != nil {
+		s.log.Error(err, "failed to connect the mysql", "node", host)
+		state.Message = err.Error()
+		return state
+	}
+	defer runner.Close()
+
+	if state.Lagged, state.Replicating, err = runner.CheckSlaveStatusWithRetry(checkNodeStatusRetry); err != nil {
+		s.log.Error(err, "failed to check slave status", "node", host)
+		state.Message = err.Error()
+	}
+	if state.ReadOnly, err = runner.CheckReadOnly(); err != nil {
+		s.log.Error(err, "failed to check read only", "node", host)
+		state.Message = err.Error()
+	}
+	return state
+}
+
 func (s *StatusUpdater) getNodeStatusIndex(name string) int {
 	len := len(s.Status.Nodes)
 	for i := 0; i < len; i++ {
@@ -257,6 +294,16 @@ func (s *StatusUpdater) getNodeStatusIndex(name string) int {
 	return len
 }
 
+// getNodeCondition returns the condition of the node by the type, nil if not found.
+func getNodeCondition(node *apiv1.NodeStatus, condType apiv1.NodeConditionType) *apiv1.NodeCondition {
+	for i := range node.Conditions {
+		if node.Conditions[i].Type == condType {
+			return &node.Conditions[i]
+		}
+	}
+	return nil
+}
+
 func (s *StatusUpdater) updateNodeCondition(node *apiv1.NodeStatus, idx int, status corev1.ConditionStatus) {
 	if node.Conditions[idx].Status != status {
 		t := time.Now()
@@ -314,7 +361,14 @@ func correctLeaderReadOnly(namespace, podName string) error {
 	return executor.SetGlobalSysVar(namespace, podName, "SET GLOBAL super_read_only=off")
 }
 
-func setPodHealthy(ctx context.Context, cli client.Client, pod *corev1.Pod, node *apiv1.NodeStatus) error {
+// setPodHealthy labels the pod healthy if its conditions match its role, the
+// leader is expected to be read-only if the cluster is set read-only.
+func setPodHealthy(ctx context.Context, cli client.Client, pod *corev1.Pod, node *apiv1.NodeStatus, readOnly bool) error {
+	leaderReadOnly := corev1.ConditionFalse
+	if readOnly {
+		leaderReadOnly = corev1.ConditionTrue
+	}
+
 	healthy := "no"
 	if node.Conditions[0].Status == corev1.ConditionFalse {
 		if node.Conditions[1].Status == corev1.ConditionFalse &&
@@ -322,7 +376,7 @@ func setPodHealthy(ctx context.Context, cli client.Client, pod *corev1.Pod, node
 			node.Conditions[3].Status == corev1.ConditionTrue {
 			healthy = "yes"
 		} else if node.Conditions[1].Status == corev1.ConditionTrue &&
-			node.Conditions[2].Status == corev1.ConditionFalse &&
+			node.Conditions[2].Status == leaderReadOnly &&
 			node.Conditions[3].Status == corev1.ConditionFalse {
 			healthy = "yes"
 		}
@@ -336,3 +390,18 @@ func setPodHealthy(ctx context.Context, cli client.Client, pod *corev1.Pod, node
 	}
 	return nil
 }
+
+// newOperatorSQLRunner connects the pod with the operator user, whose password is not rotated.
+func newOperatorSQLRunner(secret *corev1.Secret, c *cluster.Cluster, pod *corev1.Pod) (*internal.SQLRunner, error) {
+	user, ok := secret.Data["operator-user"]
+	if !ok {
+		return nil, fmt.Errorf("failed to get the operator user from the secret")
+	}
+	password, ok := secret.Data["operator-password"]
+	if !ok {
+		return nil, fmt.Errorf("failed to get the operator password from the secret")
+	}
+
+	host := fmt.Sprintf("%s.%s.%s", pod.Name, c.GetNameForResource(utils.HeadlessSVC), c.Namespace)
+	return internal.NewSQLRunner(utils.BytesToString(user), utils.BytesToString(password), host, utils.MysqlPort)
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// switchoverTimeout is the max duration to wait for the target to become a writable leader.
+const switchoverTimeout = 2 * time.Minute
+
+// SwitchoverSyncer moves the leadership to the pod requested by the
+// switchover annotation or the spec.leader.
+type SwitchoverSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewSwitchoverSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *SwitchoverSyncer {
+	return &SwitchoverSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *SwitchoverSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *SwitchoverSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *SwitchoverSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *SwitchoverSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+// Sync drives the switchover one step further. The old leader is disabled in
+// raft so that the target wins the election triggered by `raft trytoleader`,
+// and is enabled again once the target became a writable leader.
+func (s *SwitchoverSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+
+	target, byAnnotation := s.Annotations[utils.SwitchoverAnnotation], true
+	if len(target) == 0 {
+		target, byAnnotation = s.Spec.Leader, false
+	}
+
+	last := s.Status.Switchover
+	inProgress := last != nil && last.Phase == apiv1.SwitchoverInProgress
+	if len(target) == 0 && !inProgress {
+		return result, nil
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	var leader, targetPod *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels["role"] == "leader" {
+			leader = pod
+		}
+		if pod.Name == target {
+			targetPod = pod
+		}
+	}
+
+	if inProgress {
+		return s.checkSwitchover(ctx, leader)
+	}
+
+	// the spec.leader is retried only after it was changed.
+	if !byAnnotation && last != nil && last.Target == target && last.Phase == apiv1.SwitchoverFailed {
+		return result, nil
+	}
+	// the rolling update moves the leadership away before restarting the leader.
+	if !byAnnotation && s.Status.Rollout != nil && s.Status.Rollout.Phase == apiv1.RolloutUpdating {
+		return result, nil
+	}
+
+	if leader != nil && leader.Name == target {
+		// nothing to switch.
+		return result, s.removeAnnotation(ctx)
+	}
+
+	if targetPod == nil || leader == nil || targetPod.Labels["role"] != "follower" || targetPod.Labels["healthy"] != "yes" {
+		if !byAnnotation {
+			// wait for the target to be ready.
+			return result, nil
+		}
+		s.Status.Switchover = &apiv1.SwitchoverStatus{Target: target}
+		s.finishSwitchover(apiv1.SwitchoverFailed, fmt.Sprintf("%s is not a healthy follower", target))
+		s.setEvent(&result, corev1.EventTypeWarning, "SwitchoverFailed", s.Status.Switchover.Message)
+		return result, s.removeAnnotation(ctx)
+	}
+
+	now := metav1.Now()
+	s.Status.Switchover = &apiv1.SwitchoverStatus{
+		From:      leader.Name,
+		Target:    target,
+		Phase:     apiv1.SwitchoverInProgress,
+		StartTime: &now,
+	}
+	s.updateSwitchoverCondition(corev1.ConditionTrue, string(apiv1.SwitchoverInProgress),
+		fmt.Sprintf("switching over from %s to %s", leader.Name, target))
+	s.setEvent(&result, corev1.EventTypeNormal, "SwitchoverStarted",
+		fmt.Sprintf("switching over from %s to %s", leader.Name, target))
+
+	if err := raftCommand(s.Namespace, leader.Name, "disable"); err != nil {
+		return result, err
+	}
+	return result, raftCommand(s.Namespace, target, "trytoleader")
+}
+
+// checkSwitchover finishes the switchover if the target became a writable
+// leader, or it failed to in time.
+func (s *SwitchoverSyncer) checkSwitchover(ctx context.Context, leader *corev1.Pod) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	sw := s.Status.Switchover
+
+	if leader != nil && leader.Name == sw.Target && s.isWritable(sw.Target) {
+		s.enableRaft(sw.From)
+		s.finishSwitchover(apiv1.SwitchoverSucceeded, fmt.Sprintf("%s is the leader now", sw.Target))
+		s.setEvent(&result, corev1.EventTypeNormal, "SwitchoverSucceeded", sw.Message)
+		return result, s.removeAnnotation(ctx)
+	}
+
+	if sw.StartTime != nil && time.Since(sw.StartTime.Time) < switchoverTimeout {
+		s.log.V(1).Info("waiting for the switchover", "target", sw.Target)
+		return result, nil
+	}
+
+	s.enableRaft(sw.From)
+	s.finishSwitchover(apiv1.SwitchoverFailed,
+		fmt.Sprintf("%s did not become a writable leader in %s", sw.Target, switchoverTimeout))
+	s.setEvent(&result, corev1.EventTypeWarning, "SwitchoverFailed", sw.Message)
+	return result, s.removeAnnotation(ctx)
+}
+
+// enableRaft lets the old leader take part in the election again.
+func (s *SwitchoverSyncer) enableRaft(podName string) {
+	if err := raftCommand(s.Namespace, podName, "enable"); err != nil {
+		s.log.Error(err, "failed to enable raft", "node", podName)
+	}
+}
+
+// isWritable returns true if the node is not read only.
+func (s *SwitchoverSyncer) isWritable(podName string) bool {
+	host := fmt.Sprintf("%s.%s.%s", podName, s.GetNameForResource(utils.HeadlessSVC), s.Namespace)
+	for i := range s.Status.Nodes {
+		if node := &s.Status.Nodes[i]; node.Name == host {
+			cond := getNodeCondition(node, apiv1.NodeConditionReadOnly)
+			return cond != nil && cond.Status == corev1.ConditionFalse
+		}
+	}
+	return false
+}
+
+func (s *SwitchoverSyncer) finishSwitchover(phase apiv1.SwitchoverPhase, msg string) {
+	now := metav1.Now()
+	s.Status.Switchover.Phase = phase
+	s.Status.Switchover.Message = msg
+	s.Status.Switchover.CompletionTime = &now
+	s.updateSwitchoverCondition(corev1.ConditionFalse, string(phase), msg)
+}
+
+func (s *SwitchoverSyncer) updateSwitchoverCondition(status corev1.ConditionStatus, reason, msg string) {
+	s.Status.Conditions = append(s.Status.Conditions, apiv1.ClusterCondition{
+		Type:               apiv1.ClusterSwitchover,
+		Status:             status,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            msg,
+	})
+	if len(s.Status.Conditions) > maxStatusesQuantity {
+		s.Status.Conditions = s.Status.Conditions[len(s.Status.Conditions)-maxStatusesQuantity:]
+	}
+}
+
+func (s *SwitchoverSyncer) setEvent(result *syncer.SyncResult, eventType, reason, msg string) {
+	// the event is recorded only if the operation is not none.
+	result.Operation = controllerutil.OperationResultUpdated
+	result.SetEventData(eventType, reason, msg)
+}
+
+// removeAnnotation removes the handled switchover request.
+func (s *SwitchoverSyncer) removeAnnotation(ctx context.Context) error {
+	if _, ok := s.Annotations[utils.SwitchoverAnnotation]; !ok {
+		return nil
+	}
+
+	// patch a copy, the response would overwrite the status being updated.
+	obj := s.Unwrap().DeepCopy()
+	delete(obj.Annotations, utils.SwitchoverAnnotation)
+	if err := s.cli.Patch(ctx, obj, client.MergeFrom(s.Unwrap())); err != nil {
+		return err
+	}
+	s.Annotations = obj.Annotations
+	s.ResourceVersion = obj.ResourceVersion
+	return nil
+}
+
+// raftCommand runs `xenoncli raft <action>` in the xenon container of the pod,
+// it is a variable to be replaced in the tests.
+var raftCommand = func(namespace, podName, action string) error {
+	command := []string{"xenoncli", "raft", action}
+	executor, err := internal.NewPodExecutor()
+	if err != nil {
+		return err
+	}
+
+	_, stderr, err := executor.Exec(namespace, podName, "xenon", command...)
+	if err != nil {
+		return err
+	}
+
+	if len(stderr) != 0 {
+		return fmt.Errorf("run command %s in xenon failed: %s", command, stderr)
+	}
+	return nil
+}
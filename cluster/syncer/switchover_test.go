@@ -0,0 +1,272 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+var testLog = logf.Log.WithName("test")
+
+// newTestCluster returns a cluster named sample in the default namespace.
+func newTestCluster() *cluster.Cluster {
+	return cluster.New(&apiv1.Cluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: apiv1.GroupVersion.String(), Kind: "Cluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", Annotations: map[string]string{}},
+	})
+}
+
+// newTestPod returns a pod of the cluster with the role and healthy labels.
+func newTestPod(c *cluster.Cluster, ordinal int, role, healthy string) *corev1.Pod {
+	labels := c.GetSelectorLabels()
+	labels["role"] = role
+	labels["healthy"] = healthy
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", c.GetNameForResource(utils.StatefulSet), ordinal),
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+// newTestNode returns the status of the node of the pod.
+func newTestNode(c *cluster.Cluster, ordinal int, readOnly corev1.ConditionStatus) apiv1.NodeStatus {
+	return apiv1.NodeStatus{
+		Name: fmt.Sprintf("%s-%d.%s.%s", c.GetNameForResource(utils.StatefulSet), ordinal,
+			c.GetNameForResource(utils.HeadlessSVC), c.Namespace),
+		Conditions: []apiv1.NodeCondition{
+			{Type: apiv1.NodeConditionLagged, Status: corev1.ConditionFalse},
+			{Type: apiv1.NodeConditionLeader, Status: corev1.ConditionFalse},
+			{Type: apiv1.NodeConditionReadOnly, Status: readOnly},
+			{Type: apiv1.NodeConditionReplicating, Status: corev1.ConditionTrue},
+		},
+	}
+}
+
+// newFakeClient returns a fake client with the cluster and the objects.
+func newFakeClient(c *cluster.Cluster, objs ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiv1.AddToScheme(scheme)
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(objs, c.Unwrap().DeepCopy())...).Build()
+}
+
+// stubRaftCommand records the raft commands instead of running them.
+func stubRaftCommand(t *testing.T) *[]string {
+	var commands []string
+	old := raftCommand
+	raftCommand = func(namespace, podName, action string) error {
+		commands = append(commands, fmt.Sprintf("%s %s", podName, action))
+		return nil
+	}
+	t.Cleanup(func() { raftCommand = old })
+	return &commands
+}
+
+func TestSwitchoverSync(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+		leader     string
+		last       *apiv1.SwitchoverStatus
+		readOnly   corev1.ConditionStatus
+		// the conditions of the node are not in the order of the status updater.
+		reversed     bool
+		wantPhase    apiv1.SwitchoverPhase
+		wantReason   string
+		wantCommands []string
+		// whether the annotation is kept.
+		wantAnnotation bool
+	}{
+		{
+			name: "no request",
+		},
+		{
+			name:       "target is the leader",
+			annotation: "sample-mysql-0",
+		},
+		{
+			name:       "target is not healthy",
+			annotation: "sample-mysql-2",
+			wantPhase:  apiv1.SwitchoverFailed,
+			wantReason: "SwitchoverFailed",
+		},
+		{
+			name:       "target does not exist",
+			annotation: "sample-mysql-5",
+			wantPhase:  apiv1.SwitchoverFailed,
+			wantReason: "SwitchoverFailed",
+		},
+		{
+			name:   "spec.leader waits for the target",
+			leader: "sample-mysql-2",
+		},
+		{
+			name:   "failed spec.leader is not retried",
+			leader: "sample-mysql-1",
+			last: &apiv1.SwitchoverStatus{
+				Target: "sample-mysql-1",
+				Phase:  apiv1.SwitchoverFailed,
+			},
+			wantPhase: apiv1.SwitchoverFailed,
+		},
+		{
+			name:           "started",
+			annotation:     "sample-mysql-1",
+			wantPhase:      apiv1.SwitchoverInProgress,
+			wantReason:     "SwitchoverStarted",
+			wantCommands:   []string{"sample-mysql-0 disable", "sample-mysql-1 trytoleader"},
+			wantAnnotation: true,
+		},
+		{
+			name:       "waiting for the target",
+			annotation: "sample-mysql-1",
+			last: &apiv1.SwitchoverStatus{
+				From:      "sample-mysql-0",
+				Target:    "sample-mysql-1",
+				Phase:     apiv1.SwitchoverInProgress,
+				StartTime: &metav1.Time{Time: time.Now()},
+			},
+			wantPhase:      apiv1.SwitchoverInProgress,
+			wantAnnotation: true,
+		},
+		{
+			name:       "target is a read only leader",
+			annotation: "sample-mysql-0",
+			readOnly:   corev1.ConditionTrue,
+			last: &apiv1.SwitchoverStatus{
+				From:      "sample-mysql-1",
+				Target:    "sample-mysql-0",
+				Phase:     apiv1.SwitchoverInProgress,
+				StartTime: &metav1.Time{Time: time.Now()},
+			},
+			wantPhase:      apiv1.SwitchoverInProgress,
+			wantAnnotation: true,
+		},
+		{
+			name:       "read only found by the condition type",
+			annotation: "sample-mysql-0",
+			readOnly:   corev1.ConditionTrue,
+			reversed:   true,
+			last: &apiv1.SwitchoverStatus{
+				From:      "sample-mysql-1",
+				Target:    "sample-mysql-0",
+				Phase:     apiv1.SwitchoverInProgress,
+				StartTime: &metav1.Time{Time: time.Now()},
+			},
+			wantPhase:      apiv1.SwitchoverInProgress,
+			wantAnnotation: true,
+		},
+		{
+			name:       "succeeded",
+			annotation: "sample-mysql-0",
+			readOnly:   corev1.ConditionFalse,
+			last: &apiv1.SwitchoverStatus{
+				From:      "sample-mysql-1",
+				Target:    "sample-mysql-0",
+				Phase:     apiv1.SwitchoverInProgress,
+				StartTime: &metav1.Time{Time: time.Now()},
+			},
+			wantPhase:    apiv1.SwitchoverSucceeded,
+			wantReason:   "SwitchoverSucceeded",
+			wantCommands: []string{"sample-mysql-1 enable"},
+		},
+		{
+			name:       "timed out",
+			annotation: "sample-mysql-1",
+			last: &apiv1.SwitchoverStatus{
+				From:      "sample-mysql-0",
+				Target:    "sample-mysql-1",
+				Phase:     apiv1.SwitchoverInProgress,
+				StartTime: &metav1.Time{Time: time.Now().Add(-switchoverTimeout)},
+			},
+			wantPhase:    apiv1.SwitchoverFailed,
+			wantReason:   "SwitchoverFailed",
+			wantCommands: []string{"sample-mysql-0 enable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := stubRaftCommand(t)
+
+			c := newTestCluster()
+			if len(tt.annotation) > 0 {
+				c.Annotations[utils.SwitchoverAnnotation] = tt.annotation
+			}
+			c.Spec.Leader = tt.leader
+			c.Status.Switchover = tt.last
+			readOnly := tt.readOnly
+			if len(readOnly) == 0 {
+				readOnly = corev1.ConditionTrue
+			}
+			c.Status.Nodes = []apiv1.NodeStatus{newTestNode(c, 0, readOnly)}
+			if tt.reversed {
+				reverseConditions(&c.Status.Nodes[0])
+			}
+			cli := newFakeClient(c,
+				newTestPod(c, 0, "leader", "yes"),
+				newTestPod(c, 1, "follower", "yes"),
+				newTestPod(c, 2, "follower", "no"),
+			)
+
+			result, err := NewSwitchoverSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			var phase apiv1.SwitchoverPhase
+			if c.Status.Switchover != nil {
+				phase = c.Status.Switchover.Phase
+			}
+			if phase != tt.wantPhase {
+				t.Errorf("phase = %q, want %q", phase, tt.wantPhase)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+			if !reflect.DeepEqual(*commands, tt.wantCommands) {
+				t.Errorf("raft commands = %v, want %v", *commands, tt.wantCommands)
+			}
+
+			obj := &apiv1.Cluster{}
+			if err = cli.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "sample"}, obj); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := obj.Annotations[utils.SwitchoverAnnotation]; ok != (tt.wantAnnotation && len(tt.annotation) > 0) {
+				t.Errorf("annotation kept = %v, want %v", ok, tt.wantAnnotation)
+			}
+		})
+	}
+}
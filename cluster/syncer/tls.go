@@ -0,0 +1,121 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// ReplicationTLSSyncer makes the replication channels of the followers use
+// ssl. The channels are set up by xenon without ssl, so they are changed
+// after every switch of the leader.
+type ReplicationTLSSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewReplicationTLSSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *ReplicationTLSSyncer {
+	return &ReplicationTLSSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *ReplicationTLSSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *ReplicationTLSSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *ReplicationTLSSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *ReplicationTLSSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *ReplicationTLSSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	if s.Spec.TLS == nil {
+		return result, nil
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	secret := &corev1.Secret{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return result, err
+	}
+
+	var changed []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels["role"] != "follower" {
+			continue
+		}
+
+		runner, err := newOperatorSQLRunner(secret, s.Cluster, pod)
+		if err != nil {
+			s.log.Error(err, "failed to connect the mysql", "pod", pod.Name)
+			continue
+		}
+		ok, err := runner.EnableReplicationSSL()
+		runner.Close()
+		if err != nil {
+			s.log.Error(err, "failed to enable the replication ssl", "pod", pod.Name)
+			continue
+		}
+		if ok {
+			changed = append(changed, pod.Name)
+		}
+	}
+
+	if len(changed) > 0 {
+		// the event is recorded only if the operation is not none.
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeNormal, "ReplicationTLSEnabled",
+			fmt.Sprintf("the replication of %s uses ssl", strings.Join(changed, ", ")))
+	}
+	return result, nil
+}
@@ -0,0 +1,347 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// upgradeRetryDelay is the delay before retrying the failed upgrade, it
+	// is doubled on every retry up to maxUpgradeRetryDelay.
+	upgradeRetryDelay    = time.Minute
+	maxUpgradeRetryDelay = 30 * time.Minute
+	// upgradePodTimeout is the time allowed to upgrade each pod, the upgrade
+	// fails if the pods are not upgraded in time.
+	upgradePodTimeout = 10 * time.Minute
+)
+
+// UpgradeSyncer drives the upgrade of the mysql version. The upgrade path is
+// validated and a backup is taken first if configured, then the new version
+// is rolled out by the RolloutSyncer, followers first and the leader last.
+type UpgradeSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewUpgradeSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *UpgradeSyncer {
+	return &UpgradeSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *UpgradeSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *UpgradeSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *UpgradeSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *UpgradeSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *UpgradeSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	current := s.Status.MysqlVersion
+	target := s.GetMySQLVersion()
+	up := s.Status.Upgrade
+
+	// the pods partly run the version being upgraded to, another version
+	// waits for the upgrade to finish unless the version is set back.
+	upgrading := s.GetUpgradingVersion()
+	if len(upgrading) != 0 && target != current {
+		target = upgrading
+	}
+
+	if up != nil && up.Phase == apiv1.UpgradeUpgrading && up.To == target {
+		return s.checkUpgrade(ctx)
+	}
+
+	// the version of a new cluster.
+	if len(current) == 0 {
+		s.Status.MysqlVersion = target
+		return result, nil
+	}
+
+	if current == target {
+		// setting the version back cancels the upgrade, or dismisses the failed
+		// one. The upgraded pods are rolled back to the running version.
+		if up != nil && up.Phase != apiv1.UpgradeSucceeded {
+			if up.Phase != apiv1.UpgradeFailed {
+				s.setEvent(&result, corev1.EventTypeWarning, "UpgradeCancelled",
+					fmt.Sprintf("the upgrade to %s is cancelled, keeping %s", up.To, current))
+			}
+			s.Status.Upgrade = nil
+		}
+		return result, nil
+	}
+
+	var retries int32
+	if up != nil && up.To == target {
+		if up.Phase == apiv1.UpgradeBackingUp {
+			return s.checkBackup(ctx)
+		}
+		if up.Phase != apiv1.UpgradeFailed || !shouldRetryUpgrade(up) {
+			return result, nil
+		}
+		retries = up.Retries + 1
+		s.setEvent(&result, corev1.EventTypeNormal, "UpgradeRetrying",
+			fmt.Sprintf("retrying the upgrade to %s, attempt %d", target, retries))
+	}
+
+	now := metav1.Now()
+	s.Status.Upgrade = &apiv1.UpgradeStatus{
+		From:      current,
+		To:        target,
+		StartTime: &now,
+		Retries:   retries,
+	}
+	if err := utils.ValidateMysqlUpgrade(current, target); err != nil {
+		s.finishUpgrade(apiv1.UpgradeFailed, err.Error())
+		s.setEvent(&result, corev1.EventTypeWarning, "UpgradeRefused", err.Error())
+		return result, nil
+	}
+
+	// the retried upgrade is not backed up again once the pods were upgraded.
+	if s.Spec.UpgradeBackup == nil || len(upgrading) != 0 {
+		s.startUpgrade(&result)
+		return result, nil
+	}
+
+	b, err := s.createBackup(ctx)
+	if err != nil {
+		return result, err
+	}
+	s.Status.Upgrade.Phase = apiv1.UpgradeBackingUp
+	s.Status.Upgrade.BackupName = b.Name
+	s.Status.Upgrade.Message = fmt.Sprintf("waiting for the backup %s", b.Name)
+	s.setEvent(&result, corev1.EventTypeNormal, "UpgradeBackupStarted",
+		fmt.Sprintf("taking the backup %s before upgrading to %s", b.Name, target))
+	return result, nil
+}
+
+// checkBackup starts the upgrade once the backup succeeded.
+func (s *UpgradeSyncer) checkBackup(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	up := s.Status.Upgrade
+
+	b := backup.New(&apiv1.Backup{})
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      up.BackupName,
+	}, b.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			s.finishUpgrade(apiv1.UpgradeFailed, fmt.Sprintf("backup %s not found", up.BackupName))
+			s.setEvent(&result, corev1.EventTypeWarning, "UpgradeFailed", up.Message)
+			return result, nil
+		}
+		return result, err
+	}
+
+	if !b.Status.Completed {
+		return result, nil
+	}
+
+	if !b.IsSucceeded() {
+		s.finishUpgrade(apiv1.UpgradeFailed, fmt.Sprintf("backup %s failed", up.BackupName))
+		s.setEvent(&result, corev1.EventTypeWarning, "UpgradeFailed", up.Message)
+		return result, nil
+	}
+
+	s.startUpgrade(&result)
+	return result, nil
+}
+
+// checkUpgrade runs mysql_upgrade on the upgraded pods, and finishes the
+// upgrade once all the pods run the new version. Only the 5.7 targets need
+// mysql_upgrade, the supported 8.0 versions upgrade the data at startup. The
+// upgrade fails if the pods are not upgraded in time, eg: a pod never becomes
+// healthy with the new version, or mysql_upgrade keeps failing.
+func (s *UpgradeSyncer) checkUpgrade(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	up := s.Status.Upgrade
+	// the upgrades started by the former versions have no rollout time.
+	if up.RolloutTime == nil {
+		now := metav1.Now()
+		up.RolloutTime = &now
+	}
+
+	pods := corev1.PodList{}
+	if err := s.cli.List(ctx, &pods, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	var waiting string
+	if int32(len(pods.Items)) != *s.Spec.Replicas {
+		waiting = "waiting for all the pods to be created"
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Labels["app.kubernetes.io/version"] != up.To || pod.Labels["healthy"] != "yes" {
+			if len(waiting) == 0 {
+				waiting = fmt.Sprintf("waiting for %s to run %s and be healthy", pod.Name, up.To)
+			}
+			continue
+		}
+
+		if s.NeedMysqlUpgrade(pod) {
+			if err := s.runMysqlUpgrade(ctx, pod); err != nil {
+				s.log.Error(err, "failed to run mysql_upgrade", "pod", pod.Name)
+				waiting = fmt.Sprintf("failed to run mysql_upgrade on %s: %s", pod.Name, err)
+				break
+			}
+			if len(waiting) == 0 {
+				waiting = fmt.Sprintf("waiting for %s to be marked upgraded", pod.Name)
+			}
+		}
+	}
+
+	if len(waiting) == 0 {
+		s.Status.MysqlVersion = up.To
+		s.finishUpgrade(apiv1.UpgradeSucceeded, fmt.Sprintf("all the pods are upgraded to %s", up.To))
+		s.setEvent(&result, corev1.EventTypeNormal, "UpgradeSucceeded", up.Message)
+		return result, nil
+	}
+
+	up.Message = waiting
+	timeout := upgradePodTimeout * time.Duration(*s.Spec.Replicas)
+	if time.Since(up.RolloutTime.Time) > timeout {
+		s.finishUpgrade(apiv1.UpgradeFailed, fmt.Sprintf("the pods are not upgraded to %s in %s, %s", up.To, timeout, waiting))
+		s.setEvent(&result, corev1.EventTypeWarning, "UpgradeFailed", up.Message)
+	}
+	return result, nil
+}
+
+// runMysqlUpgrade upgrades the system tables of the pod and marks it upgraded.
+func (s *UpgradeSyncer) runMysqlUpgrade(ctx context.Context, pod *corev1.Pod) error {
+	s.log.Info("running mysql_upgrade", "pod", pod.Name, "version", s.Status.Upgrade.To)
+	executor, err := internal.NewPodExecutor()
+	if err != nil {
+		return err
+	}
+
+	// the followers must not write binlogs, or they would have errant transactions.
+	command := []string{"sh", "-c", "MYSQL_PWD=${MYSQL_ROOT_PASSWORD} mysql_upgrade -uroot --skip-write-binlog"}
+	_, stderr, err := executor.Exec(s.Namespace, pod.Name, utils.ContainerMysqlName, command...)
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, stderr)
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[utils.UpgradedVersionAnnotation] = s.Status.Upgrade.To
+	return s.cli.Patch(ctx, pod, patch)
+}
+
+// createBackup creates the Backup object taken before the upgrade.
+func (s *UpgradeSyncer) createBackup(ctx context.Context) (*apiv1.Backup, error) {
+	b := &apiv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       fmt.Sprintf("%s-upgrade-%s", s.Name, time.Now().UTC().Format("20060102150405")),
+			Namespace:  s.Namespace,
+			Labels:     labels.Set{"mysql.radondb.io/cluster": s.Name},
+			Finalizers: []string{backup.ArtifactsFinalizer},
+		},
+		Spec: apiv1.BackupSpec{
+			ClusterName: s.Name,
+			Image:       s.Spec.PodSpec.SidecarImage,
+			Destination: *s.Spec.UpgradeBackup,
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(s.Unwrap(), b, s.cli.Scheme()); err != nil {
+		return nil, err
+	}
+
+	if err := s.cli.Create(ctx, b); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return b, nil
+}
+
+// shouldRetryUpgrade returns true if the failed upgrade can be retried and
+// has waited for the backoff. The refused upgrade paths are never retried.
+func shouldRetryUpgrade(up *apiv1.UpgradeStatus) bool {
+	if utils.ValidateMysqlUpgrade(up.From, up.To) != nil {
+		return false
+	}
+	if up.CompletionTime == nil {
+		return true
+	}
+
+	delay := maxUpgradeRetryDelay
+	if up.Retries < 5 {
+		delay = upgradeRetryDelay << uint(up.Retries)
+	}
+	return time.Since(up.CompletionTime.Time) >= delay
+}
+
+func (s *UpgradeSyncer) startUpgrade(result *syncer.SyncResult) {
+	now := metav1.Now()
+	up := s.Status.Upgrade
+	up.Phase = apiv1.UpgradeUpgrading
+	up.RolloutTime = &now
+	up.Message = fmt.Sprintf("upgrading from %s to %s", up.From, up.To)
+	s.setEvent(result, corev1.EventTypeNormal, "UpgradeStarted", up.Message)
+}
+
+func (s *UpgradeSyncer) finishUpgrade(phase apiv1.UpgradePhase, msg string) {
+	now := metav1.Now()
+	up := s.Status.Upgrade
+	up.Phase = phase
+	up.Message = msg
+	up.CompletionTime = &now
+}
+
+func (s *UpgradeSyncer) setEvent(result *syncer.SyncResult, eventType, reason, msg string) {
+	// the event is recorded only if the operation is not none.
+	result.Operation = controllerutil.OperationResultUpdated
+	result.SetEventData(eventType, reason, msg)
+}
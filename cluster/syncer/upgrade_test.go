@@ -0,0 +1,271 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+func TestUpgradeSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		current  string
+		upgrade  *apiv1.UpgradeStatus
+		backup   *apiv1.BackupDestination
+		backupOK *bool
+		// the version label of the pods.
+		podVersions []string
+		wantVersion string
+		wantPhase   apiv1.UpgradePhase
+		wantReason  string
+		wantBackup  bool
+		wantRetries int32
+	}{
+		{
+			name:        "new cluster",
+			version:     "5.7",
+			wantVersion: "5.7.33",
+		},
+		{
+			name:        "up to date",
+			version:     "5.7",
+			current:     "5.7.33",
+			wantVersion: "5.7.33",
+		},
+		{
+			name:        "failed upgrade is dismissed",
+			version:     "5.7",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeFailed},
+			wantVersion: "5.7.33",
+		},
+		{
+			name:        "downgrade is refused",
+			version:     "5.7",
+			current:     "8.0.25",
+			wantVersion: "8.0.25",
+			wantPhase:   apiv1.UpgradeFailed,
+			wantReason:  "UpgradeRefused",
+		},
+		{
+			name:        "refused upgrade is not retried",
+			version:     "5.7",
+			current:     "8.0.25",
+			upgrade:     &apiv1.UpgradeStatus{From: "8.0.25", To: "5.7.33", Phase: apiv1.UpgradeFailed},
+			wantVersion: "8.0.25",
+			wantPhase:   apiv1.UpgradeFailed,
+		},
+		{
+			name:    "failed upgrade waits for the backoff",
+			version: "8.0",
+			current: "5.7.33",
+			upgrade: &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeFailed,
+				CompletionTime: timeAgo(time.Second), Retries: 1},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeFailed,
+		},
+		{
+			name:    "failed upgrade is retried after the backoff",
+			version: "8.0",
+			current: "5.7.33",
+			upgrade: &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeFailed,
+				CompletionTime: timeAgo(3 * time.Minute), Retries: 1},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeUpgrading,
+			wantReason:  "UpgradeStarted",
+			wantRetries: 2,
+		},
+		{
+			name:        "upgrade without backup",
+			version:     "8.0",
+			current:     "5.7.33",
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeUpgrading,
+			wantReason:  "UpgradeStarted",
+		},
+		{
+			name:        "upgrade with backup",
+			version:     "8.0",
+			current:     "5.7.33",
+			backup:      &apiv1.BackupDestination{Endpoint: "http://minio:9000", Bucket: "backups"},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeBackingUp,
+			wantReason:  "UpgradeBackupStarted",
+			wantBackup:  true,
+		},
+		{
+			name:        "backup not found",
+			version:     "8.0",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeBackingUp, BackupName: "pre-upgrade"},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeFailed,
+			wantReason:  "UpgradeFailed",
+		},
+		{
+			name:        "backup failed",
+			version:     "8.0",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeBackingUp, BackupName: "pre-upgrade"},
+			backupOK:    boolPtr(false),
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeFailed,
+			wantReason:  "UpgradeFailed",
+		},
+		{
+			name:        "backup succeeded",
+			version:     "8.0",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeBackingUp, BackupName: "pre-upgrade"},
+			backupOK:    boolPtr(true),
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeUpgrading,
+			wantReason:  "UpgradeStarted",
+		},
+		{
+			name:        "waiting for the pods",
+			version:     "8.0",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeUpgrading},
+			podVersions: []string{"5.7.33", "8.0.25"},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeUpgrading,
+		},
+		{
+			name:    "pods not upgraded in time",
+			version: "8.0",
+			current: "5.7.33",
+			upgrade: &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeUpgrading,
+				RolloutTime: timeAgo(time.Hour)},
+			podVersions: []string{"5.7.33", "8.0.25"},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeFailed,
+			wantReason:  "UpgradeFailed",
+		},
+		{
+			name:    "version set back while upgrading",
+			version: "5.7",
+			current: "5.7.33",
+			upgrade: &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeUpgrading,
+				RolloutTime: timeAgo(time.Minute)},
+			podVersions: []string{"5.7.33", "8.0.25"},
+			wantVersion: "5.7.33",
+			wantReason:  "UpgradeCancelled",
+		},
+		{
+			name:    "failed rollout is retried without a backup",
+			version: "8.0",
+			current: "5.7.33",
+			backup:  &apiv1.BackupDestination{Endpoint: "http://minio:9000", Bucket: "backups"},
+			upgrade: &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeFailed,
+				RolloutTime: timeAgo(time.Hour), CompletionTime: timeAgo(3 * time.Minute), Retries: 1},
+			wantVersion: "5.7.33",
+			wantPhase:   apiv1.UpgradeUpgrading,
+			wantReason:  "UpgradeStarted",
+			wantRetries: 2,
+		},
+		{
+			name:        "all the pods upgraded",
+			version:     "8.0",
+			current:     "5.7.33",
+			upgrade:     &apiv1.UpgradeStatus{From: "5.7.33", To: "8.0.25", Phase: apiv1.UpgradeUpgrading},
+			podVersions: []string{"8.0.25", "8.0.25"},
+			wantVersion: "8.0.25",
+			wantPhase:   apiv1.UpgradeSucceeded,
+			wantReason:  "UpgradeSucceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			replicas := int32(2)
+			c.Spec.Replicas = &replicas
+			c.Spec.MysqlVersion = tt.version
+			c.Spec.UpgradeBackup = tt.backup
+			c.Status.MysqlVersion = tt.current
+			c.Status.Upgrade = tt.upgrade
+
+			var objs []client.Object
+			for i, v := range tt.podVersions {
+				pod := newTestPod(c, i, "follower", "yes")
+				pod.Labels["app.kubernetes.io/version"] = v
+				objs = append(objs, pod)
+			}
+			if tt.backupOK != nil {
+				status := corev1.ConditionFalse
+				if *tt.backupOK {
+					status = corev1.ConditionTrue
+				}
+				objs = append(objs, &apiv1.Backup{
+					ObjectMeta: metav1.ObjectMeta{Name: "pre-upgrade", Namespace: c.Namespace},
+					Status: apiv1.BackupStatus{
+						Completed:  true,
+						Conditions: []apiv1.BackupCondition{{Type: apiv1.BackupComplete, Status: status}},
+					},
+				})
+			}
+			cli := newFakeClient(c, objs...)
+
+			result, err := NewUpgradeSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			if c.Status.MysqlVersion != tt.wantVersion {
+				t.Errorf("version = %q, want %q", c.Status.MysqlVersion, tt.wantVersion)
+			}
+			var phase apiv1.UpgradePhase
+			if c.Status.Upgrade != nil {
+				phase = c.Status.Upgrade.Phase
+			}
+			if phase != tt.wantPhase {
+				t.Errorf("phase = %q, want %q", phase, tt.wantPhase)
+			}
+			if tt.wantRetries != 0 && c.Status.Upgrade.Retries != tt.wantRetries {
+				t.Errorf("retries = %d, want %d", c.Status.Upgrade.Retries, tt.wantRetries)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+
+			backups := apiv1.BackupList{}
+			if err := cli.List(context.TODO(), &backups, client.InNamespace(c.Namespace)); err != nil {
+				t.Fatal(err)
+			}
+			created := len(backups.Items) > 0 && tt.backupOK == nil
+			if created != tt.wantBackup {
+				t.Errorf("backup created = %v, want %v", created, tt.wantBackup)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func timeAgo(d time.Duration) *metav1.Time {
+	t := metav1.NewTime(time.Now().Add(-d))
+	return &t
+}
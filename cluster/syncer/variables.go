@@ -50,7 +50,7 @@ var mysqlSysConfigs = map[string]string{
 	"tmp_table_size":                     "32M",
 	"tmpdir":                             "/var/lib/mysql",
 	"audit_log_file":                     "/var/log/mysql/mysql-audit.log",
-	"audit_log_exclude_accounts":         "\"root@localhost,root@127.0.0.1," + utils.ReplicationUser + "@%," + utils.MetricsUser + "@%\"",
+	"audit_log_exclude_accounts":         "\"root@localhost,root@127.0.0.1," + utils.ReplicationUser + "@%," + utils.MetricsUser + "@%," + utils.OperatorUser + "@%\"",
 	"audit_log_buffer_size":              "16M",
 }
 
@@ -58,19 +58,15 @@ var mysqlCommonConfigs = map[string]string{
 	"character_set_server":                            "utf8mb4",
 	"interactive_timeout":                             "3600",
 	"default-time-zone":                               "+08:00",
-	"expire_logs_days":                                "7",
 	"key_buffer_size":                                 "33554432",
 	"log_bin_trust_function_creators":                 "1",
 	"long_query_time":                                 "3",
 	"binlog_cache_size":                               "32768",
 	"binlog_stmt_cache_size":                          "32768",
-	"max_connections":                                 "1024",
 	"max_connect_errors":                              "655360",
-	"query_cache_size":                                "0",
 	"sync_master_info":                                "1000",
 	"sync_relay_log":                                  "1000",
 	"sync_relay_log_info":                             "1000",
-	"table_open_cache":                                "2000",
 	"thread_cache_size":                               "128",
 	"wait_timeout":                                    "3600",
 	"group_concat_max_len":                            "1024",
@@ -96,21 +92,39 @@ var mysqlStaticConfigs = map[string]string{
 	"back_log":                    "2048",
 	"ft_min_word_len":             "4",
 	"lower_case_table_names":      "0",
-	"query_cache_type":            "OFF",
 	"innodb_ft_max_token_size":    "84",
 	"innodb_ft_min_token_size":    "3",
 	"sql_mode":                    "STRICT_TRANS_TABLES,NO_ENGINE_SUBSTITUTION",
 	"slave_parallel_workers":      "8",
 	"slave_pending_jobs_size_max": "1073741824",
-	"innodb_log_buffer_size":      "16777216",
-	"innodb_log_file_size":        "1073741824",
-	"innodb_log_files_in_group":   "2",
 	"innodb_flush_method":         "O_DIRECT",
 	"innodb_use_native_aio":       "1",
 	"innodb_autoinc_lock_mode":    "2",
 	"performance_schema":          "1",
 }
 
+// mysql57CommonConfigs are the common configs removed in mysql 8.0.
+var mysql57CommonConfigs = map[string]string{
+	"expire_logs_days": "7",
+	"query_cache_size": "0",
+}
+
+// mysql57StaticConfigs are the static configs removed in mysql 8.0.
+var mysql57StaticConfigs = map[string]string{
+	"query_cache_type":          "OFF",
+	"innodb_log_files_in_group": "2",
+}
+
+// mysql80CommonConfigs replace the mysql57CommonConfigs in mysql 8.0.
+var mysql80CommonConfigs = map[string]string{
+	"binlog_expire_logs_seconds": "604800",
+}
+
+var mysql80StaticConfigs = map[string]string{
+	// xenon and the metrics exporter authenticate with mysql_native_password.
+	"default_authentication_plugin": "mysql_native_password",
+}
+
 var mysqlTokudbConfigs = map[string]string{
 	"loose_tokudb_directio": "ON",
 }
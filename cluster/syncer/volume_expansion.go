@@ -0,0 +1,254 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// VolumeExpansionSyncer expands the data and the separate volumes to their
+// sizes. The claims of the existing pods are expanded if their storage class
+// allows it, then the statefulset is deleted with the pods orphaned, and
+// recreated by the cluster controller with the new volume claim templates.
+type VolumeExpansionSyncer struct {
+	log logr.Logger
+
+	*cluster.Cluster
+
+	cli client.Client
+}
+
+func NewVolumeExpansionSyncer(log logr.Logger, cli client.Client, c *cluster.Cluster) *VolumeExpansionSyncer {
+	return &VolumeExpansionSyncer{
+		log:     log,
+		Cluster: c,
+		cli:     cli,
+	}
+}
+
+// Object returns the object for which sync applies.
+func (s *VolumeExpansionSyncer) Object() interface{} { return nil }
+
+// GetObject returns the object for which sync applies
+// Deprecated: use github.com/presslabs/controller-util/syncer.Object() instead.
+func (s *VolumeExpansionSyncer) GetObject() interface{} { return nil }
+
+// Owner returns the object owner or nil if object does not have one.
+func (s *VolumeExpansionSyncer) ObjectOwner() runtime.Object { return s.Cluster }
+
+// GetOwner returns the object owner or nil if object does not have one.
+// Deprecated: use github.com/presslabs/controller-util/syncer.ObjectOwner() instead.
+func (s *VolumeExpansionSyncer) GetOwner() runtime.Object { return s.Cluster }
+
+func (s *VolumeExpansionSyncer) Sync(ctx context.Context) (syncer.SyncResult, error) {
+	result := syncer.SyncResult{}
+	if !s.Spec.Persistence.Enabled {
+		return result, nil
+	}
+	sizes := s.getVolumeSizes()
+
+	sts := &appsv1.StatefulSet{}
+	if err := s.cli.Get(ctx, types.NamespacedName{
+		Namespace: s.Namespace,
+		Name:      s.GetNameForResource(utils.StatefulSet),
+	}, sts); err != nil {
+		if errors.IsNotFound(err) {
+			return result, nil
+		}
+		return result, err
+	}
+	// wait for the statefulset to be recreated.
+	if sts.DeletionTimestamp != nil {
+		return result, nil
+	}
+
+	pvcs := corev1.PersistentVolumeClaimList{}
+	if err := s.cli.List(ctx, &pvcs, &client.ListOptions{
+		Namespace:     s.Namespace,
+		LabelSelector: s.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return result, err
+	}
+
+	var volumes []apiv1.VolumeStatus
+	var expanded, unsupported []string
+	for i := range pvcs.Items {
+		pvc := &pvcs.Items[i]
+		size, ok := getClaimSize(pvc, sts, sizes)
+		if !ok {
+			continue
+		}
+
+		volume, patched, err := s.expandVolume(ctx, pvc, size)
+		if err != nil {
+			return result, err
+		}
+		if volume.Phase == apiv1.VolumeResizeUnsupported {
+			unsupported = append(unsupported, pvc.Name)
+		}
+		if patched {
+			expanded = append(expanded, pvc.Name)
+		}
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	s.Status.Volumes = volumes
+
+	if len(unsupported) > 0 {
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeWarning, "VolumeExpansionUnsupported",
+			fmt.Sprintf("the storage class of %s does not allow the expansion", strings.Join(unsupported, ", ")))
+		return result, nil
+	}
+
+	// the claims of the new pods take the size of the templates.
+	for _, template := range sts.Spec.VolumeClaimTemplates {
+		size, ok := sizes[template.Name]
+		if !ok || template.Spec.Resources.Requests.Storage().Cmp(size) >= 0 {
+			continue
+		}
+		if err := s.cli.Delete(ctx, sts, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil {
+			return result, err
+		}
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeNormal, "StatefulSetRecreating",
+			fmt.Sprintf("recreating the statefulset with the %s volume size %s", template.Name, size.String()))
+		return result, nil
+	}
+
+	if len(expanded) > 0 {
+		result.Operation = controllerutil.OperationResultUpdated
+		result.SetEventData(corev1.EventTypeNormal, "VolumeExpansionStarted",
+			fmt.Sprintf("expanding %s", strings.Join(expanded, ", ")))
+	}
+	return result, nil
+}
+
+// getVolumeSizes returns the sizes of the data and the separate volumes by
+// the names of the volume claim templates.
+func (s *VolumeExpansionSyncer) getVolumeSizes() map[string]resource.Quantity {
+	sizes := make(map[string]resource.Quantity)
+	if size, err := resource.ParseQuantity(s.Spec.Persistence.Size); err == nil {
+		sizes[utils.DataVolumeName] = size
+	}
+	for _, volume := range s.GetExtraVolumes() {
+		if size, err := resource.ParseQuantity(volume.Size); err == nil {
+			sizes[volume.Name] = size
+		}
+	}
+	return sizes
+}
+
+// getClaimSize returns the size of the volume the claim is created for, the
+// claims are named <template>-<statefulset>-<ordinal>.
+func getClaimSize(pvc *corev1.PersistentVolumeClaim, sts *appsv1.StatefulSet,
+	sizes map[string]resource.Quantity) (resource.Quantity, bool) {
+	for name, size := range sizes {
+		if strings.HasPrefix(pvc.Name, fmt.Sprintf("%s-%s-", name, sts.Name)) {
+			return size, true
+		}
+	}
+	return resource.Quantity{}, false
+}
+
+// expandVolume requests the size for the claim if it is smaller, and returns
+// its status and whether it is patched.
+func (s *VolumeExpansionSyncer) expandVolume(ctx context.Context, pvc *corev1.PersistentVolumeClaim,
+	size resource.Quantity) (apiv1.VolumeStatus, bool, error) {
+	capacity := pvc.Status.Capacity[corev1.ResourceStorage]
+	volume := apiv1.VolumeStatus{
+		Name:     pvc.Name,
+		Size:     size.String(),
+		Capacity: capacity.String(),
+		Phase:    apiv1.VolumeReady,
+	}
+
+	request := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if request.Cmp(size) < 0 {
+		allowed, err := s.allowExpansion(ctx, pvc)
+		if err != nil {
+			return volume, false, err
+		}
+		if !allowed {
+			volume.Phase = apiv1.VolumeResizeUnsupported
+			volume.Message = fmt.Sprintf("the storage class does not allow the expansion from %s", request.String())
+			return volume, false, nil
+		}
+
+		patch := client.MergeFrom(pvc.DeepCopy())
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		if err := s.cli.Patch(ctx, pvc, patch); err != nil {
+			return volume, false, err
+		}
+		volume.Phase = apiv1.VolumeResizing
+		return volume, true, nil
+	}
+
+	if capacity.Cmp(size) >= 0 {
+		return volume, false, nil
+	}
+
+	volume.Phase = apiv1.VolumeResizing
+	for _, cond := range pvc.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		if cond.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
+			volume.Phase = apiv1.VolumeFileSystemResizePending
+		}
+		if len(cond.Message) > 0 {
+			volume.Message = cond.Message
+		}
+	}
+	return volume, false, nil
+}
+
+// allowExpansion returns true if the storage class of the claim allows the expansion.
+func (s *VolumeExpansionSyncer) allowExpansion(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	if pvc.Spec.StorageClassName == nil || len(*pvc.Spec.StorageClassName) == 0 {
+		return false, nil
+	}
+
+	class := &storagev1.StorageClass{}
+	if err := s.cli.Get(ctx, types.NamespacedName{Name: *pvc.Spec.StorageClassName}, class); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion, nil
+}
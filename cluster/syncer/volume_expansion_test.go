@@ -0,0 +1,210 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestVolumeExpansionSync(t *testing.T) {
+	tests := []struct {
+		name string
+		// the volume to expand, the data volume by default.
+		volume       string
+		size         string
+		class        string
+		request      string
+		capacity     string
+		templateSize string
+		// the condition of the claim.
+		condition  corev1.PersistentVolumeClaimConditionType
+		wantPhase  apiv1.VolumeResizePhase
+		wantReason string
+		// the request of the claim after the sync.
+		wantRequest string
+		// whether the statefulset is deleted to be recreated.
+		wantDeleted bool
+	}{
+		{
+			name:         "unchanged",
+			size:         "10Gi",
+			class:        "expandable",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "10Gi",
+			wantPhase:    apiv1.VolumeReady,
+			wantRequest:  "10Gi",
+		},
+		{
+			name:         "expanded and the statefulset recreated",
+			size:         "20Gi",
+			class:        "expandable",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "10Gi",
+			wantPhase:    apiv1.VolumeResizing,
+			wantReason:   "StatefulSetRecreating",
+			wantRequest:  "20Gi",
+			wantDeleted:  true,
+		},
+		{
+			name:         "binlog volume expanded and the statefulset recreated",
+			volume:       utils.BinlogVolumeName,
+			size:         "20Gi",
+			class:        "expandable",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "10Gi",
+			wantPhase:    apiv1.VolumeResizing,
+			wantReason:   "StatefulSetRecreating",
+			wantRequest:  "20Gi",
+			wantDeleted:  true,
+		},
+		{
+			name:         "expanded",
+			size:         "20Gi",
+			class:        "expandable",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "20Gi",
+			wantPhase:    apiv1.VolumeResizing,
+			wantReason:   "VolumeExpansionStarted",
+			wantRequest:  "20Gi",
+		},
+		{
+			name:         "storage class does not allow the expansion",
+			size:         "20Gi",
+			class:        "fixed",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "10Gi",
+			wantPhase:    apiv1.VolumeResizeUnsupported,
+			wantReason:   "VolumeExpansionUnsupported",
+			wantRequest:  "10Gi",
+		},
+		{
+			name:         "storage class not found",
+			size:         "20Gi",
+			class:        "missing",
+			request:      "10Gi",
+			capacity:     "10Gi",
+			templateSize: "10Gi",
+			wantPhase:    apiv1.VolumeResizeUnsupported,
+			wantReason:   "VolumeExpansionUnsupported",
+			wantRequest:  "10Gi",
+		},
+		{
+			name:         "waiting for the file system",
+			size:         "20Gi",
+			class:        "expandable",
+			request:      "20Gi",
+			capacity:     "10Gi",
+			templateSize: "20Gi",
+			condition:    corev1.PersistentVolumeClaimFileSystemResizePending,
+			wantPhase:    apiv1.VolumeFileSystemResizePending,
+			wantRequest:  "20Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCluster()
+			c.Spec.Persistence.Enabled = true
+			c.Spec.Persistence.Size = tt.size
+			volume := utils.DataVolumeName
+			if len(tt.volume) != 0 {
+				volume = tt.volume
+				c.Spec.Persistence.Size = "10Gi"
+				c.Spec.Persistence.Binlog = &apiv1.VolumeOpts{Size: tt.size}
+			}
+
+			allow, deny := true, false
+			sts := &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: c.GetNameForResource(utils.StatefulSet), Namespace: c.Namespace},
+				Spec: appsv1.StatefulSetSpec{
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+						ObjectMeta: metav1.ObjectMeta{Name: volume},
+						Spec: corev1.PersistentVolumeClaimSpec{Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.templateSize)},
+						}},
+					}},
+				},
+			}
+			pvc := &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      volume + "-" + sts.Name + "-0",
+					Namespace: c.Namespace,
+					Labels:    c.GetSelectorLabels(),
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: &tt.class,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.request)},
+					},
+				},
+				Status: corev1.PersistentVolumeClaimStatus{
+					Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(tt.capacity)},
+				},
+			}
+			if len(tt.condition) != 0 {
+				pvc.Status.Conditions = []corev1.PersistentVolumeClaimCondition{{Type: tt.condition, Status: corev1.ConditionTrue}}
+			}
+			cli := newFakeClient(c, sts, pvc,
+				&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "expandable"}, AllowVolumeExpansion: &allow},
+				&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fixed"}, AllowVolumeExpansion: &deny},
+			)
+
+			result, err := NewVolumeExpansionSyncer(testLog, cli, c).Sync(context.TODO())
+			if err != nil {
+				t.Fatalf("Sync() error = %v", err)
+			}
+
+			if len(c.Status.Volumes) != 1 || c.Status.Volumes[0].Phase != tt.wantPhase {
+				t.Errorf("volumes = %v, want phase %q", c.Status.Volumes, tt.wantPhase)
+			}
+			if result.EventReason != tt.wantReason {
+				t.Errorf("event = %q, want %q", result.EventReason, tt.wantReason)
+			}
+
+			got := &corev1.PersistentVolumeClaim{}
+			if err := cli.Get(context.TODO(), client.ObjectKeyFromObject(pvc), got); err != nil {
+				t.Fatal(err)
+			}
+			request := got.Spec.Resources.Requests[corev1.ResourceStorage]
+			if want := resource.MustParse(tt.wantRequest); request.Cmp(want) != 0 {
+				t.Errorf("request = %s, want %s", request.String(), tt.wantRequest)
+			}
+
+			err = cli.Get(context.TODO(), client.ObjectKeyFromObject(sts), &appsv1.StatefulSet{})
+			if deleted := errors.IsNotFound(err); deleted != tt.wantDeleted {
+				t.Errorf("statefulset deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+		})
+	}
+}
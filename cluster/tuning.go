@@ -0,0 +1,211 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"math"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// memoryBudgetRatio is the ratio of the memory limit that can be used by
+	// the buffers, the rest is left to mysqld itself.
+	memoryBudgetRatio = 0.9
+	// minConnections is the number of the connections always reserved in the budget.
+	minConnections = 16
+	// threadOverhead is the memory of each connection besides the tuned buffers,
+	// the thread stack and the binlog caches.
+	threadOverhead = 352 * kb
+)
+
+// tuningProfile defines how the configs are derived from the resources.
+type tuningProfile struct {
+	// bufferPoolRatio is the ratio of the memory request used by the buffer pool.
+	bufferPoolRatio float64
+	// logFileRatio is the size of each redo log file relative to the buffer pool.
+	logFileRatio   float64
+	logBufferSize  int64
+	maxConnections int64
+	// tableOpenCachePerGB is the number of the open tables per GB of memory.
+	tableOpenCachePerGB int64
+	ioCapacity          int64
+	// threadBuffers are allocated by each connection, including the in-memory
+	// temporary tables limited by tmp_table_size and max_heap_table_size.
+	threadBuffers map[string]int64
+}
+
+var tuningProfiles = map[apiv1.TuningProfile]tuningProfile{
+	apiv1.TuningProfileOLTP: {
+		bufferPoolRatio:     0.65,
+		logFileRatio:        0.25,
+		logBufferSize:       16 * mb,
+		maxConnections:      1024,
+		tableOpenCachePerGB: 1000,
+		ioCapacity:          1000,
+		threadBuffers: map[string]int64{
+			"sort_buffer_size":     256 * kb,
+			"join_buffer_size":     256 * kb,
+			"read_buffer_size":     128 * kb,
+			"read_rnd_buffer_size": 256 * kb,
+			"tmp_table_size":       2 * mb,
+			"max_heap_table_size":  2 * mb,
+		},
+	},
+	apiv1.TuningProfileSmall: {
+		bufferPoolRatio:     0.5,
+		logFileRatio:        0.25,
+		logBufferSize:       8 * mb,
+		maxConnections:      128,
+		tableOpenCachePerGB: 400,
+		ioCapacity:          200,
+		threadBuffers: map[string]int64{
+			"sort_buffer_size":     256 * kb,
+			"join_buffer_size":     256 * kb,
+			"read_buffer_size":     128 * kb,
+			"read_rnd_buffer_size": 256 * kb,
+			"tmp_table_size":       1 * mb,
+			"max_heap_table_size":  1 * mb,
+		},
+	},
+	apiv1.TuningProfileAnalytical: {
+		bufferPoolRatio:     0.5,
+		logFileRatio:        0.125,
+		logBufferSize:       32 * mb,
+		maxConnections:      256,
+		tableOpenCachePerGB: 400,
+		ioCapacity:          2000,
+		threadBuffers: map[string]int64{
+			"sort_buffer_size":     4 * mb,
+			"join_buffer_size":     4 * mb,
+			"read_buffer_size":     1 * mb,
+			"read_rnd_buffer_size": 2 * mb,
+			"tmp_table_size":       16 * mb,
+			"max_heap_table_size":  16 * mb,
+		},
+	},
+}
+
+// tuneMysqlConf sets the innodb and connection configs by the resources of the
+// mysql container. The buffer pool is sized by the memory request, while the
+// buffer pool, the global buffers and the buffers of max_connections together
+// never exceed the budget of the memory limit, the configs set in MysqlConf
+// are shrunk to fit in if needed.
+func (c *Cluster) tuneMysqlConf() {
+	profile, ok := tuningProfiles[c.Spec.MysqlOpts.TuningProfile]
+	if !ok {
+		profile = tuningProfiles[apiv1.TuningProfileOLTP]
+	}
+
+	resources := c.Spec.MysqlOpts.Resources
+	mem := resources.Requests.Memory().Value()
+	limit := resources.Limits.Memory().Value()
+	if mem == 0 {
+		mem = limit
+	}
+	if limit == 0 {
+		limit = mem
+	}
+	budget := int64(memoryBudgetRatio * float64(limit))
+	cpu := resources.Limits.Cpu().MilliValue()
+	if cpu == 0 {
+		cpu = resources.Requests.Cpu().MilliValue()
+	}
+
+	// the buffers of each connection.
+	threadBuffers := make(map[string]int64)
+	threadSize := threadOverhead
+	for name, size := range profile.threadBuffers {
+		threadBuffers[name] = c.getMysqlConfSize(name, size)
+		threadSize += threadBuffers[name]
+	}
+	// the reserved connections may take at most a quarter of the budget.
+	if maxSize := budget / 4 / minConnections; budget > 0 && threadSize > maxSize {
+		ratio := float64(maxSize-threadOverhead) / float64(threadSize-threadOverhead)
+		threadSize = threadOverhead
+		for name, size := range threadBuffers {
+			threadBuffers[name] = utils.Max(int64(ratio*float64(size)), 32*kb)
+			threadSize += threadBuffers[name]
+		}
+	}
+
+	logBufferSize := c.getMysqlConfSize("innodb_log_buffer_size", profile.logBufferSize)
+	global := logBufferSize + c.getMysqlConfSize("key_buffer_size", 32*mb)
+
+	bufferPoolSize := c.getMysqlConfSize("innodb_buffer_pool_size",
+		utils.Max(int64(profile.bufferPoolRatio*float64(mem)), 128*mb))
+	if budget > 0 {
+		bufferPoolSize = utils.Min(bufferPoolSize, budget-global-minConnections*threadSize)
+	}
+	// the minimum size of the buffer pool.
+	bufferPoolSize = utils.Max(bufferPoolSize, 5*mb)
+
+	maxConnections := c.getMysqlConfSize("max_connections", profile.maxConnections)
+	if budget > 0 {
+		maxConnections = utils.Min(maxConnections, (budget-global-bufferPoolSize)/threadSize)
+	}
+	maxConnections = utils.Max(maxConnections, 1)
+
+	// the size of the redo log file should be a multiple of 1MB.
+	logFileSize := utils.Min(utils.Max(int64(profile.logFileRatio*float64(bufferPoolSize))/mb*mb, 48*mb), 2*gb)
+	tableOpenCache := utils.Min(utils.Max(profile.tableOpenCachePerGB*mem/gb, 400), 4000)
+	instances := math.Max(math.Min(math.Ceil(float64(cpu)/float64(1000)), math.Floor(float64(bufferPoolSize)/float64(gb))), 1)
+
+	c.setMysqlConfSize("innodb_buffer_pool_size", bufferPoolSize)
+	c.setMysqlConfSize("innodb_buffer_pool_instances", int64(instances))
+	c.setMysqlConfSize("innodb_log_buffer_size", logBufferSize)
+	c.setMysqlConfSize("max_connections", maxConnections)
+	for name, size := range threadBuffers {
+		c.setMysqlConfSize(name, size)
+	}
+	c.setMysqlConfDefault("innodb_log_file_size", logFileSize)
+	c.setMysqlConfDefault("table_open_cache", tableOpenCache)
+	c.setMysqlConfDefault("innodb_io_capacity", profile.ioCapacity)
+	c.setMysqlConfDefault("innodb_io_capacity_max", 2*profile.ioCapacity)
+}
+
+// getMysqlConfSize returns the size set in MysqlConf, or the default one if it
+// is not set or invalid.
+func (c *Cluster) getMysqlConfSize(name string, defaultSize int64) int64 {
+	value, ok := c.Spec.MysqlOpts.MysqlConf[name]
+	if !ok {
+		return defaultSize
+	}
+	if value.Type == intstr.Int {
+		return int64(value.IntVal)
+	}
+	if size, ok := utils.ParseMysqlSize(value.StrVal); ok {
+		return size
+	}
+	return defaultSize
+}
+
+func (c *Cluster) setMysqlConfSize(name string, size int64) {
+	// the sizes may overflow the int32 of the IntOrString.
+	c.Spec.MysqlOpts.MysqlConf[name] = intstr.FromString(strconv.FormatInt(size, 10))
+}
+
+// setMysqlConfDefault sets the config only if it is not set in MysqlConf.
+func (c *Cluster) setMysqlConfDefault(name string, size int64) {
+	if _, ok := c.Spec.MysqlOpts.MysqlConf[name]; !ok {
+		c.setMysqlConfSize(name, size)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+func newTuningCluster(profile apiv1.TuningProfile, request, limit, cpu string, conf apiv1.MysqlConf) *Cluster {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	if len(request) != 0 {
+		resources.Requests[corev1.ResourceMemory] = resource.MustParse(request)
+	}
+	if len(limit) != 0 {
+		resources.Limits[corev1.ResourceMemory] = resource.MustParse(limit)
+	}
+	if len(cpu) != 0 {
+		resources.Limits[corev1.ResourceCPU] = resource.MustParse(cpu)
+	}
+
+	return New(&apiv1.Cluster{
+		Spec: apiv1.ClusterSpec{
+			MysqlOpts: apiv1.MysqlOpts{
+				TuningProfile: profile,
+				Resources:     resources,
+				MysqlConf:     conf,
+			},
+		},
+	})
+}
+
+func TestTuneMysqlConf(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile apiv1.TuningProfile
+		request string
+		limit   string
+		cpu     string
+		conf    apiv1.MysqlConf
+		want    map[string]string
+	}{
+		{
+			name:    "oltp",
+			profile: apiv1.TuningProfileOLTP,
+			request: "4Gi",
+			limit:   "4Gi",
+			cpu:     "2",
+			want: map[string]string{
+				"innodb_buffer_pool_size":      "2791728742",
+				"innodb_buffer_pool_instances": "2",
+				"innodb_log_buffer_size":       "16777216",
+				"innodb_log_file_size":         "697303040",
+				"max_connections":              "187",
+				"table_open_cache":             "4000",
+				"tmp_table_size":               "2097152",
+				"max_heap_table_size":          "2097152",
+				"innodb_io_capacity":           "1000",
+				"innodb_io_capacity_max":       "2000",
+			},
+		},
+		{
+			name:    "small",
+			profile: apiv1.TuningProfileSmall,
+			request: "1Gi",
+			limit:   "1Gi",
+			cpu:     "500m",
+			want: map[string]string{
+				"innodb_buffer_pool_size":      "536870912",
+				"innodb_buffer_pool_instances": "1",
+				"innodb_log_buffer_size":       "8388608",
+				"max_connections":              "114",
+				"table_open_cache":             "400",
+				"tmp_table_size":               "1048576",
+				"innodb_io_capacity":           "200",
+			},
+		},
+		{
+			name:    "analytical",
+			profile: apiv1.TuningProfileAnalytical,
+			request: "8Gi",
+			limit:   "8Gi",
+			cpu:     "4",
+			want: map[string]string{
+				"innodb_buffer_pool_size":      "4294967296",
+				"innodb_buffer_pool_instances": "4",
+				"innodb_log_buffer_size":       "33554432",
+				"sort_buffer_size":             "4194304",
+				"max_connections":              "74",
+				"tmp_table_size":               "16777216",
+				"innodb_io_capacity":           "2000",
+			},
+		},
+		{
+			name:    "unknown profile falls back to oltp",
+			profile: apiv1.TuningProfile("unknown"),
+			request: "4Gi",
+			limit:   "4Gi",
+			cpu:     "2",
+			want: map[string]string{
+				"innodb_buffer_pool_size": "2791728742",
+				"max_connections":         "187",
+			},
+		},
+		{
+			name:    "small limit shrinks the buffers",
+			profile: apiv1.TuningProfileOLTP,
+			request: "256Mi",
+			limit:   "256Mi",
+			want: map[string]string{
+				"innodb_buffer_pool_instances": "1",
+				"innodb_log_file_size":         "50331648",
+				"table_open_cache":             "400",
+			},
+		},
+		{
+			name:    "limit above the request",
+			profile: apiv1.TuningProfileOLTP,
+			request: "1Gi",
+			limit:   "2Gi",
+			want: map[string]string{
+				"innodb_buffer_pool_size": "697932185",
+			},
+		},
+		{
+			name:    "unset limit uses the request",
+			profile: apiv1.TuningProfileOLTP,
+			request: "1Gi",
+			want: map[string]string{
+				"innodb_buffer_pool_size": "697932185",
+				"max_connections":         "39",
+			},
+		},
+		{
+			name:    "unset resources",
+			profile: apiv1.TuningProfileOLTP,
+			want: map[string]string{
+				"innodb_buffer_pool_size":      "134217728",
+				"innodb_buffer_pool_instances": "1",
+				"innodb_log_file_size":         "50331648",
+				"max_connections":              "1024",
+				"table_open_cache":             "400",
+			},
+		},
+		{
+			name:    "mysqlConf is shrunk to fit in the limit",
+			profile: apiv1.TuningProfileOLTP,
+			request: "1Gi",
+			limit:   "1Gi",
+			conf: apiv1.MysqlConf{
+				"innodb_buffer_pool_size": intstr.FromString("8G"),
+				"max_connections":         intstr.FromInt(4096),
+			},
+			want: map[string]string{
+				"innodb_buffer_pool_size": "828479897",
+				"max_connections":         "16",
+			},
+		},
+		{
+			name:    "mysqlConf takes precedence",
+			profile: apiv1.TuningProfileOLTP,
+			request: "4Gi",
+			limit:   "4Gi",
+			conf: apiv1.MysqlConf{
+				"innodb_buffer_pool_size": intstr.FromString("1G"),
+				"max_connections":         intstr.FromInt(100),
+				"table_open_cache":        intstr.FromInt(1000),
+			},
+			want: map[string]string{
+				"innodb_buffer_pool_size": "1073741824",
+				"max_connections":         "100",
+				"table_open_cache":        "1000",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTuningCluster(tt.profile, tt.request, tt.limit, tt.cpu, tt.conf)
+			c.EnsureMysqlConf()
+			conf := c.Spec.MysqlOpts.MysqlConf
+
+			for key, want := range tt.want {
+				value := conf[key]
+				if got := value.String(); got != want {
+					t.Errorf("%s = %s, want %s", key, got, want)
+				}
+			}
+
+			limit := c.Spec.MysqlOpts.Resources.Limits.Memory().Value()
+			if limit == 0 {
+				limit = c.Spec.MysqlOpts.Resources.Requests.Memory().Value()
+			}
+			if limit == 0 {
+				return
+			}
+			if used, budget := getTunedMemory(c), int64(memoryBudgetRatio*float64(limit)); used > budget {
+				t.Errorf("the tuned configs use %d bytes, above the budget %d", used, budget)
+			}
+		})
+	}
+}
+
+// getTunedMemory returns the memory used by the buffer pool, the global buffers
+// and the buffers of max_connections.
+func getTunedMemory(c *Cluster) int64 {
+	profile, ok := tuningProfiles[c.Spec.MysqlOpts.TuningProfile]
+	if !ok {
+		profile = tuningProfiles[apiv1.TuningProfileOLTP]
+	}
+
+	threadSize := threadOverhead
+	for name := range profile.threadBuffers {
+		threadSize += c.getMysqlConfSize(name, 0)
+	}
+	global := c.getMysqlConfSize("innodb_log_buffer_size", 0) + c.getMysqlConfSize("key_buffer_size", 32*mb)
+	return c.getMysqlConfSize("innodb_buffer_pool_size", 0) + global +
+		c.getMysqlConfSize("max_connections", 0)*threadSize
+}
@@ -52,6 +52,24 @@ func main() {
 	initCmd := sidecar.NewInitCommand(cfg)
 	cmd.AddCommand(initCmd)
 
+	cloneCmd := sidecar.NewCloneCommand(cfg)
+	cmd.AddCommand(cloneCmd)
+
+	httpCmd := sidecar.NewHttpCommand(cfg)
+	cmd.AddCommand(httpCmd)
+
+	serveCmd := sidecar.NewServeCommand(cfg)
+	cmd.AddCommand(serveCmd)
+
+	backupCmd := sidecar.NewBackupCommand()
+	cmd.AddCommand(backupCmd)
+
+	purgeCmd := sidecar.NewPurgeCommand()
+	cmd.AddCommand(purgeCmd)
+
+	archiveCmd := sidecar.NewArchiveCommand()
+	cmd.AddCommand(archiveCmd)
+
 	if err := cmd.Execute(); err != nil {
 		log.Error(err, "failed to execute command", "cmd", cmd)
 		os.Exit(1)
@@ -0,0 +1,222 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/presslabs/controller-util/syncer"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	backupsyncer "github.com/zhyass/mysql-operator/backup/syncer"
+	"github.com/zhyass/mysql-operator/cluster"
+)
+
+// backupRequeueAfter is the time to wait before retrying to choose a host for the backup.
+const backupRequeueAfter = 30 * time.Second
+
+// BackupReconciler reconciles a Backup object
+type BackupReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=backups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=backups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=backups/finalizers,verbs=update
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the backup closer to the desired state. It runs a
+// job that streams a xtrabackup from a healthy follower, or the leader if there
+// is none, to the destination.
+func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("backup", req.NamespacedName)
+
+	instance := backup.New(&apiv1.Backup{})
+	err := r.Get(ctx, req.NamespacedName, instance.Unwrap())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			log.Info("instance not found, maybe removed")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.purgeBackup(ctx, instance)
+	}
+
+	// the backup has finished, nothing to do.
+	if instance.Status.Completed {
+		return reconcile.Result{}, nil
+	}
+
+	status := *instance.Status.DeepCopy()
+	defer func() {
+		if !reflect.DeepEqual(status, instance.Status) {
+			sErr := r.Status().Update(ctx, instance.Unwrap())
+			if sErr != nil {
+				log.Error(sErr, "failed to update backup status")
+			}
+		}
+	}()
+
+	mysqlCluster := cluster.New(&apiv1.Cluster{})
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: instance.Namespace,
+		Name:      instance.Spec.ClusterName,
+	}, mysqlCluster.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			instance.UpdateStatusCondition(apiv1.BackupFailed, corev1.ConditionTrue, "ClusterNotFound",
+				fmt.Sprintf("cluster %s not found", instance.Spec.ClusterName))
+			instance.Status.Completed = true
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if len(instance.Spec.HostName) == 0 {
+		hostName, err := r.getBackupHost(ctx, mysqlCluster)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(hostName) == 0 {
+			log.Info("no healthy node found, waiting")
+			instance.UpdateStatusCondition(apiv1.BackupComplete, corev1.ConditionFalse, "NoHealthyNode",
+				"waiting for a healthy node to take the backup from")
+			return reconcile.Result{RequeueAfter: backupRequeueAfter}, nil
+		}
+
+		// save the host into spec, the job will be created in the next reconcile.
+		instance.Spec.HostName = hostName
+		if err = r.Update(ctx, instance.Unwrap()); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	jobSyncer := backupsyncer.NewJobSyncer(r.Client, mysqlCluster, instance)
+	if err = syncer.Sync(ctx, jobSyncer, r.Recorder); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// purgeBackup deletes the backup from the storage before removing its finalizer.
+func (r *BackupReconciler) purgeBackup(ctx context.Context, b *backup.Backup) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(b.Unwrap(), backup.ArtifactsFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	// the backup job has been started, there may be something uploaded.
+	if len(b.Status.BackupURL) != 0 {
+		image, err := r.getPurgeImage(ctx, b)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		purgeSyncer := backupsyncer.NewPurgeJobSyncer(r.Client, b, image)
+		if err := syncer.Sync(ctx, purgeSyncer, r.Recorder); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		finished, succeeded := backupsyncer.IsJobFinished(purgeSyncer.Object().(*batchv1.Job))
+		if !finished {
+			// wait for the job to finish, its changes will trigger the reconcile.
+			return reconcile.Result{}, nil
+		}
+		if !succeeded {
+			r.Recorder.Eventf(b.Unwrap(), corev1.EventTypeWarning, "PurgeFailed",
+				"failed to delete %s from the storage", b.Status.BackupURL)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(b.Unwrap(), backup.ArtifactsFinalizer)
+	return reconcile.Result{}, r.Update(ctx, b.Unwrap())
+}
+
+// getPurgeImage returns the image of the purge job, which matches the mysql
+// version of the cluster like the backup job. The backup may outlive the
+// cluster, eg: the final backup, then its own image is used.
+func (r *BackupReconciler) getPurgeImage(ctx context.Context, b *backup.Backup) (string, error) {
+	c := cluster.New(&apiv1.Cluster{})
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: b.Namespace,
+		Name:      b.Spec.ClusterName,
+	}, c.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			return b.Spec.Image, nil
+		}
+		return "", err
+	}
+	return c.GetBackupImage(b.Spec.Image), nil
+}
+
+// getBackupHost returns the name of a healthy follower of the cluster, or the
+// healthy leader if there is no healthy follower, eg: the cluster has only one
+// replica.
+func (r *BackupReconciler) getBackupHost(ctx context.Context, c *cluster.Cluster) (string, error) {
+	for _, role := range []string{"follower", "leader"} {
+		labels := c.GetSelectorLabels()
+		labels["role"] = role
+		labels["healthy"] = "yes"
+
+		list := corev1.PodList{}
+		if err := r.List(ctx, &list, &client.ListOptions{
+			Namespace:     c.Namespace,
+			LabelSelector: labels.AsSelector(),
+		}); err != nil {
+			return "", err
+		}
+
+		for _, pod := range list.Items {
+			if pod.DeletionTimestamp == nil {
+				return pod.Name, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiv1.Backup{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestGetPurgeImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "mysql 5.7", version: "5.7.33", want: utils.SidecarImages["5.7"]},
+		{name: "mysql 8.0", version: "8.0.25", want: utils.SidecarImages["8.0"]},
+		{name: "cluster deleted", want: utils.SidecarImages["5.7"]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			if len(tt.version) != 0 {
+				objs = append(objs, &apiv1.Cluster{
+					ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"},
+					Spec:       apiv1.ClusterSpec{MysqlVersion: tt.version},
+					Status:     apiv1.ClusterStatus{MysqlVersion: tt.version},
+				})
+			}
+
+			scheme := runtime.NewScheme()
+			_ = apiv1.AddToScheme(scheme)
+			r := &BackupReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()}
+
+			b := backup.New(&apiv1.Backup{
+				ObjectMeta: metav1.ObjectMeta{Name: "sample-backup", Namespace: "default"},
+				Spec:       apiv1.BackupSpec{ClusterName: "sample", Image: utils.SidecarImages["5.7"]},
+			})
+			got, err := r.getPurgeImage(context.TODO(), b)
+			if err != nil {
+				t.Fatalf("getPurgeImage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getPurgeImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
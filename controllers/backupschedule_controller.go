@@ -0,0 +1,258 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/cluster"
+)
+
+// scheduleLookBack is the max duration to look back for the missed schedules.
+const scheduleLookBack = 24 * time.Hour
+
+// BackupScheduleReconciler takes the scheduled backups of a Cluster object
+type BackupScheduleReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Reconcile creates the Backup objects according to the backup schedule of the
+// cluster, and removes the scheduled backups out of the retention.
+func (r *BackupScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("cluster", req.NamespacedName)
+
+	instance := cluster.New(&apiv1.Cluster{})
+	err := r.Get(ctx, req.NamespacedName, instance.Unwrap())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			log.Info("instance not found, maybe removed")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	status := *instance.Status.DeepCopy()
+	defer func() {
+		if !reflect.DeepEqual(status, instance.Status) {
+			sErr := r.Status().Update(ctx, instance.Unwrap())
+			if sErr != nil {
+				log.Error(sErr, "failed to update cluster status")
+			}
+		}
+	}()
+
+	list := apiv1.BackupList{}
+	if err = r.List(ctx, &list, &client.ListOptions{Namespace: instance.Namespace}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var scheduled []*backup.Backup
+	running := false
+	for i := range list.Items {
+		b := backup.New(&list.Items[i])
+		if b.Spec.ClusterName != instance.Name {
+			continue
+		}
+
+		if b.IsSucceeded() && b.Status.CompletionTime != nil {
+			if instance.Status.LastSuccessfulBackupTime == nil ||
+				instance.Status.LastSuccessfulBackupTime.Before(b.Status.CompletionTime) {
+				instance.Status.LastSuccessfulBackupTime = b.Status.CompletionTime
+			}
+		}
+
+		if metav1.IsControlledBy(b.Unwrap(), instance.Unwrap()) {
+			scheduled = append(scheduled, b)
+			if !b.Status.Completed {
+				running = true
+			}
+		}
+	}
+
+	schedule := instance.Spec.BackupSchedule
+	if schedule == nil {
+		return reconcile.Result{}, nil
+	}
+
+	if err = r.removeExpiredBackups(ctx, schedule, scheduled); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	sched, err := cron.ParseStandard(schedule.Schedule)
+	if err != nil {
+		r.Recorder.Eventf(instance.Unwrap(), corev1.EventTypeWarning, "InvalidSchedule",
+			"unparseable schedule %q: %s", schedule.Schedule, err)
+		return reconcile.Result{}, nil
+	}
+
+	now := time.Now()
+	missed, next := getNextSchedule(instance, sched, now)
+	result := reconcile.Result{RequeueAfter: next.Sub(now)}
+	if missed.IsZero() {
+		return result, nil
+	}
+
+	if running {
+		log.V(1).Info("a scheduled backup is still running, skip this schedule", "schedule", missed)
+		return result, nil
+	}
+
+	if err = r.createScheduledBackup(ctx, instance, missed); err != nil {
+		return reconcile.Result{}, err
+	}
+	instance.Status.LastScheduledBackupTime = &metav1.Time{Time: missed}
+
+	return result, nil
+}
+
+// createScheduledBackup creates a Backup object owned by the cluster.
+func (r *BackupScheduleReconciler) createScheduledBackup(ctx context.Context, c *cluster.Cluster, scheduledTime time.Time) error {
+	b := &apiv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       fmt.Sprintf("%s-auto-%s", c.Name, scheduledTime.UTC().Format("20060102150405")),
+			Namespace:  c.Namespace,
+			Labels:     labels.Set{"mysql.radondb.io/cluster": c.Name},
+			Finalizers: []string{backup.ArtifactsFinalizer},
+		},
+		Spec: apiv1.BackupSpec{
+			ClusterName: c.Name,
+			Image:       c.Spec.PodSpec.SidecarImage,
+			Destination: c.Spec.BackupSchedule.Destination,
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(c.Unwrap(), b, r.Scheme); err != nil {
+		return err
+	}
+
+	if err := r.Create(ctx, b); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeNormal, "BackupScheduled", "created backup %s", b.Name)
+	return nil
+}
+
+// removeExpiredBackups deletes the completed scheduled backups which exceed the
+// backupsToKeep or are older than the daysToKeep. Only the successful backups
+// count toward the backupsToKeep, and the newest successful one is never
+// deleted, so the failing schedules do not remove the last restorable backup.
+func (r *BackupScheduleReconciler) removeExpiredBackups(ctx context.Context, schedule *apiv1.BackupSchedule, backups []*backup.Backup) error {
+	// sort the backups, the newest first.
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[j].CreationTimestamp.Before(&backups[i].CreationTimestamp)
+	})
+
+	kept := int32(0)
+	for _, b := range backups {
+		if !b.Status.Completed || !b.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if b.IsSucceeded() && kept == 0 {
+			kept++
+			continue
+		}
+
+		expired := false
+		if schedule.BackupsToKeep != nil && kept >= *schedule.BackupsToKeep {
+			expired = true
+		}
+		if schedule.DaysToKeep != nil &&
+			time.Since(b.CreationTimestamp.Time) > time.Duration(*schedule.DaysToKeep)*24*time.Hour {
+			expired = true
+		}
+
+		if !expired {
+			if b.IsSucceeded() {
+				kept++
+			}
+			continue
+		}
+
+		if err := r.Delete(ctx, b.Unwrap()); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getNextSchedule returns the latest missed schedule time (zero if there is
+// none) and the next schedule time.
+func getNextSchedule(c *cluster.Cluster, sched cron.Schedule, now time.Time) (time.Time, time.Time) {
+	var lastMissed time.Time
+
+	earliest := c.CreationTimestamp.Time
+	if c.Status.LastScheduledBackupTime != nil {
+		earliest = c.Status.LastScheduledBackupTime.Time
+	}
+
+	// only the latest missed schedule matters, skip the too old ones.
+	if earliest.Before(now.Add(-scheduleLookBack)) {
+		earliest = now.Add(-scheduleLookBack)
+	}
+
+	for t := sched.Next(earliest); !t.After(now); t = sched.Next(t) {
+		lastMissed = t
+	}
+
+	return lastMissed, sched.Next(now)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BackupScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("backupschedule").
+		For(&apiv1.Cluster{}).
+		// watch all the backups of the cluster to track the last successful backup.
+		Watches(&source.Kind{Type: &apiv1.Backup{}}, handler.EnqueueRequestsFromMapFunc(
+			func(obj client.Object) []reconcile.Request {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{
+					Namespace: obj.GetNamespace(),
+					Name:      obj.(*apiv1.Backup).Spec.ClusterName,
+				}}}
+			})).
+		Complete(r)
+}
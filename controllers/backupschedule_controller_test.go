@@ -0,0 +1,221 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
+	"github.com/zhyass/mysql-operator/cluster"
+)
+
+func TestGetNextSchedule(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 30, 0, 0, time.UTC)
+	hourly, err := cron.ParseStandard("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		created       time.Time
+		lastScheduled *time.Time
+		wantMissed    time.Time
+	}{
+		{
+			name:    "created before the last schedule",
+			created: now.Add(-40 * time.Minute),
+			// 11:50 -> 12:00
+			wantMissed: time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "created after the last schedule",
+			created: now.Add(-10 * time.Minute),
+		},
+		{
+			name:          "already scheduled",
+			created:       now.Add(-48 * time.Hour),
+			lastScheduled: timePtr(time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:          "several schedules missed",
+			created:       now.Add(-48 * time.Hour),
+			lastScheduled: timePtr(time.Date(2021, 6, 10, 9, 0, 0, 0, time.UTC)),
+			wantMissed:    time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:          "missed longer than the look back",
+			created:       now.Add(-30 * 24 * time.Hour),
+			lastScheduled: timePtr(now.Add(-10 * 24 * time.Hour)),
+			wantMissed:    time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cluster.New(&apiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(tt.created)},
+			})
+			if tt.lastScheduled != nil {
+				c.Status.LastScheduledBackupTime = &metav1.Time{Time: *tt.lastScheduled}
+			}
+
+			missed, next := getNextSchedule(c, hourly, now)
+			if !missed.Equal(tt.wantMissed) {
+				t.Errorf("missed = %v, want %v", missed, tt.wantMissed)
+			}
+			if want := time.Date(2021, 6, 10, 13, 0, 0, 0, time.UTC); !next.Equal(want) {
+				t.Errorf("next = %v, want %v", next, want)
+			}
+		})
+	}
+}
+
+func TestRemoveExpiredBackups(t *testing.T) {
+	type testBackup struct {
+		name      string
+		age       time.Duration
+		completed bool
+		succeeded bool
+	}
+	tests := []struct {
+		name          string
+		backupsToKeep *int32
+		daysToKeep    *int32
+		backups       []testBackup
+		want          []string
+	}{
+		{
+			name:          "backups to keep",
+			backupsToKeep: int32Ptr(2),
+			backups: []testBackup{
+				{"b1", 1 * time.Hour, true, true},
+				{"b2", 2 * time.Hour, true, true},
+				{"b3", 3 * time.Hour, true, true},
+			},
+			want: []string{"b1", "b2"},
+		},
+		{
+			name:          "failed backups do not count",
+			backupsToKeep: int32Ptr(2),
+			backups: []testBackup{
+				{"b1", 1 * time.Hour, true, false},
+				{"b2", 2 * time.Hour, true, false},
+				{"b3", 3 * time.Hour, true, true},
+				{"b4", 4 * time.Hour, true, true},
+				{"b5", 5 * time.Hour, true, true},
+			},
+			want: []string{"b1", "b2", "b3", "b4"},
+		},
+		{
+			name:          "running backups are kept",
+			backupsToKeep: int32Ptr(1),
+			backups: []testBackup{
+				{"b1", 1 * time.Hour, false, false},
+				{"b2", 2 * time.Hour, true, true},
+				{"b3", 3 * time.Hour, true, true},
+			},
+			want: []string{"b1", "b2"},
+		},
+		{
+			name:       "days to keep",
+			daysToKeep: int32Ptr(1),
+			backups: []testBackup{
+				{"b1", 1 * time.Hour, true, true},
+				{"b2", 48 * time.Hour, true, true},
+				{"b3", 72 * time.Hour, true, false},
+			},
+			want: []string{"b1"},
+		},
+		{
+			name:       "newest successful backup is never deleted",
+			daysToKeep: int32Ptr(1),
+			backups: []testBackup{
+				{"b1", 30 * time.Hour, true, false},
+				{"b2", 48 * time.Hour, true, true},
+				{"b3", 72 * time.Hour, true, true},
+			},
+			want: []string{"b2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objs []client.Object
+			var backups []*backup.Backup
+			for _, tb := range tt.backups {
+				status := corev1.ConditionFalse
+				if tb.succeeded {
+					status = corev1.ConditionTrue
+				}
+				b := &apiv1.Backup{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              tb.name,
+						Namespace:         "default",
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-tb.age)),
+					},
+					Status: apiv1.BackupStatus{
+						Completed:  tb.completed,
+						Conditions: []apiv1.BackupCondition{{Type: apiv1.BackupComplete, Status: status}},
+					},
+				}
+				objs = append(objs, b)
+				backups = append(backups, backup.New(b.DeepCopy()))
+			}
+
+			scheme := runtime.NewScheme()
+			_ = apiv1.AddToScheme(scheme)
+			r := &BackupScheduleReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()}
+
+			schedule := &apiv1.BackupSchedule{BackupsToKeep: tt.backupsToKeep, DaysToKeep: tt.daysToKeep}
+			if err := r.removeExpiredBackups(context.TODO(), schedule, backups); err != nil {
+				t.Fatalf("removeExpiredBackups() error = %v", err)
+			}
+
+			list := apiv1.BackupList{}
+			if err := r.List(context.TODO(), &list); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, b := range list.Items {
+				got = append(got, b.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
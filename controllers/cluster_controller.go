@@ -18,23 +18,34 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/presslabs/controller-util/syncer"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/backup"
 	"github.com/zhyass/mysql-operator/cluster"
 	clustersyncer "github.com/zhyass/mysql-operator/cluster/syncer"
+	"github.com/zhyass/mysql-operator/utils"
 )
 
 // ClusterReconciler reconciles a Cluster object
@@ -45,16 +56,20 @@ type ClusterReconciler struct {
 	Recorder record.EventRecorder
 }
 
-// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets;deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mysql.radondb.io,resources=clusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mysql.radondb.io,resources=clusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mysql.radondb.io,resources=clusters/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=configmaps;secrets;services;pods;persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;create;patch
 // +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods/exec,verbs=create
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=backups,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -92,6 +107,29 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}()
 
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.finalizeCluster(ctx, instance)
+	}
+
+	// the finalizer is only needed to keep the data.
+	if instance.Spec.DeletionPolicy != apiv1.ClusterDelete {
+		if !controllerutil.ContainsFinalizer(instance.Unwrap(), cluster.DeletionFinalizer) {
+			controllerutil.AddFinalizer(instance.Unwrap(), cluster.DeletionFinalizer)
+			return reconcile.Result{}, r.Update(ctx, instance.Unwrap())
+		}
+	} else if controllerutil.ContainsFinalizer(instance.Unwrap(), cluster.DeletionFinalizer) {
+		controllerutil.RemoveFinalizer(instance.Unwrap(), cluster.DeletionFinalizer)
+		return reconcile.Result{}, r.Update(ctx, instance.Unwrap())
+	}
+
+	if needResolveRestoreSource(instance) {
+		// the backup location must be fixed before creating the statefulset.
+		return r.resolveRestoreSource(ctx, instance)
+	}
+
+	// keep the running version until the upgrade to the new version is started.
+	pinMysqlVersion(instance)
+
 	configMapSyncer := clustersyncer.NewConfigMapSyncer(r.Client, instance)
 	if err = syncer.Sync(ctx, configMapSyncer, r.Recorder); err != nil {
 		return reconcile.Result{}, err
@@ -102,6 +140,14 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return reconcile.Result{}, err
 	}
 
+	// the certificate must be issued before the pods mount it.
+	if tls := instance.Spec.TLS; tls != nil && len(tls.SecretName) == 0 && tls.IssuerRef != nil {
+		certificateSyncer := clustersyncer.NewCertificateSyncer(r.Client, instance)
+		if err = syncer.Sync(ctx, certificateSyncer, r.Recorder); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// run the syncers for services, pdb and statefulset
 	syncers := []syncer.Interface{
 		clustersyncer.NewRoleSyncer(r.Client, instance),
@@ -110,8 +156,17 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		clustersyncer.NewHeadlessSVCSyncer(r.Client, instance),
 		clustersyncer.NewLeaderSVCSyncer(r.Client, instance),
 		clustersyncer.NewFollowerSVCSyncer(r.Client, instance),
+		clustersyncer.NewPDBSyncer(r.Client, instance),
 		clustersyncer.NewStatefulSetSyncer(r.Client, instance),
 	}
+	if instance.Spec.Proxy != nil {
+		syncers = append(syncers,
+			clustersyncer.NewProxyDeploymentSyncer(r.Client, instance),
+			clustersyncer.NewProxySVCSyncer(r.Client, instance),
+		)
+	} else if err = r.deleteProxy(ctx, instance); err != nil {
+		return reconcile.Result{}, err
+	}
 
 	// run the syncers
 	for _, sync := range syncers {
@@ -123,16 +178,259 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return ctrl.Result{}, nil
 }
 
+// finalizeCluster handles the data by the deletion policy before removing the
+// finalizer, then the other resources are garbage collected.
+func (r *ClusterReconciler) finalizeCluster(ctx context.Context, c *cluster.Cluster) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(c.Unwrap(), cluster.DeletionFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	retain := c.Spec.DeletionPolicy == apiv1.ClusterRetain
+	if c.Spec.DeletionPolicy == apiv1.ClusterBackupThenDelete {
+		if len(c.Status.FinalBackup) == 0 {
+			b, err := r.createFinalBackup(ctx, c)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			c.Status.FinalBackup = b.Name
+			r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeNormal, "FinalBackupStarted",
+				"taking the backup %s before deleting the cluster", b.Name)
+			return reconcile.Result{RequeueAfter: backupRequeueAfter}, nil
+		}
+
+		b := backup.New(&apiv1.Backup{})
+		err := r.Get(ctx, types.NamespacedName{Namespace: c.Namespace, Name: c.Status.FinalBackup}, b.Unwrap())
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		if err == nil && !b.Status.Completed {
+			return reconcile.Result{RequeueAfter: backupRequeueAfter}, nil
+		}
+		// never lose the data without a backup.
+		if err != nil || !b.IsSucceeded() {
+			r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeWarning, "FinalBackupFailed",
+				"the backup %s failed, the volumes are retained", c.Status.FinalBackup)
+			retain = true
+		}
+	}
+
+	if retain {
+		if err := r.releaseData(ctx, c); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(c.Unwrap(), cluster.DeletionFinalizer)
+	return reconcile.Result{}, r.Update(ctx, c.Unwrap())
+}
+
+// createFinalBackup creates the Backup taken before deleting the cluster, it
+// is not owned by the cluster so it is kept after the deletion. The name is
+// derived from the uid of the cluster, so the backup is created only once even
+// if recording it in the status fails.
+func (r *ClusterReconciler) createFinalBackup(ctx context.Context, c *cluster.Cluster) (*apiv1.Backup, error) {
+	uid := string(c.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	name := fmt.Sprintf("%s-final-%s", c.Name, uid)
+	b := &apiv1.Backup{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: c.Namespace, Name: name}, b)
+	if err == nil {
+		return b, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	b = &apiv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  c.Namespace,
+			Labels:     labels.Set{"mysql.radondb.io/cluster": c.Name},
+			Finalizers: []string{backup.ArtifactsFinalizer},
+		},
+		Spec: apiv1.BackupSpec{
+			ClusterName: c.Name,
+			Image:       c.Spec.PodSpec.SidecarImage,
+			Destination: *c.Spec.FinalBackup,
+		},
+	}
+
+	if err := r.Create(ctx, b); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return b, nil
+}
+
+// releaseData removes the owner references of the cluster from the volumes
+// and the secret, so they are not garbage collected.
+func (r *ClusterReconciler) releaseData(ctx context.Context, c *cluster.Cluster) error {
+	pvcs := corev1.PersistentVolumeClaimList{}
+	if err := r.List(ctx, &pvcs, &client.ListOptions{
+		Namespace:     c.Namespace,
+		LabelSelector: c.GetSelectorLabels().AsSelector(),
+	}); err != nil {
+		return err
+	}
+
+	objs := []client.Object{}
+	for i := range pvcs.Items {
+		objs = append(objs, &pvcs.Items[i])
+	}
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: c.Namespace,
+		Name:      c.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+	} else {
+		objs = append(objs, secret)
+	}
+
+	var retained []string
+	for _, obj := range objs {
+		var refs []metav1.OwnerReference
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.UID != c.UID {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == len(obj.GetOwnerReferences()) {
+			continue
+		}
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		obj.SetOwnerReferences(refs)
+		if err := r.Patch(ctx, obj, patch); err != nil {
+			return err
+		}
+		retained = append(retained, obj.GetName())
+	}
+
+	if len(retained) > 0 {
+		r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeNormal, "DataRetained",
+			"%s are retained", strings.Join(retained, ", "))
+	}
+	return nil
+}
+
+// deleteProxy removes the proxy deployment and service after the proxy is disabled.
+func (r *ClusterReconciler) deleteProxy(ctx context.Context, c *cluster.Cluster) error {
+	objs := []client.Object{
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:      c.GetNameForResource(utils.ProxyDeployment),
+			Namespace: c.Namespace,
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      c.GetNameForResource(utils.ProxyService),
+			Namespace: c.Namespace,
+		}},
+	}
+	for _, obj := range objs {
+		if err := r.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRestoreSource saves the location of the backup to restore from into the
+// cluster spec, so that the cluster does not depend on the Backup object anymore.
+func (r *ClusterReconciler) resolveRestoreSource(ctx context.Context, c *cluster.Cluster) (ctrl.Result, error) {
+	restore := c.Spec.RestoreFrom
+	b := backup.New(&apiv1.Backup{})
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: c.Namespace,
+		Name:      restore.BackupName,
+	}, b.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeWarning, "BackupNotFound",
+				"backup %s to restore from not found", restore.BackupName)
+			return reconcile.Result{RequeueAfter: backupRequeueAfter}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !b.IsSucceeded() {
+		r.Recorder.Eventf(c.Unwrap(), corev1.EventTypeWarning, "BackupNotReady",
+			"backup %s to restore from has not completed successfully", restore.BackupName)
+		return reconcile.Result{RequeueAfter: backupRequeueAfter}, nil
+	}
+
+	restore.BackupURL = b.Status.BackupURL
+	restore.Endpoint = b.Spec.Destination.Endpoint
+	restore.Region = b.Spec.Destination.Region
+	restore.SecretName = b.Spec.Destination.SecretName
+	if isPointInTimeRestore(restore) && len(restore.BinlogURL) == 0 {
+		restore.BinlogURL = b.GetBinlogURL()
+	}
+	return reconcile.Result{}, r.Update(ctx, c.Unwrap())
+}
+
+// needResolveRestoreSource returns true if the locations of the Backup to
+// restore from have not been saved into the cluster spec.
+func needResolveRestoreSource(c *cluster.Cluster) bool {
+	restore := c.Spec.RestoreFrom
+	if restore == nil || len(restore.BackupName) == 0 {
+		return false
+	}
+
+	return len(restore.BackupURL) == 0 || (isPointInTimeRestore(restore) && len(restore.BinlogURL) == 0)
+}
+
+// isPointInTimeRestore returns true if the archived binlogs should be applied.
+func isPointInTimeRestore(restore *apiv1.RestoreFrom) bool {
+	return restore.RestoreTime != nil || len(restore.RestoreGtid) > 0
+}
+
+// pinMysqlVersion sets the mysql version used to build the resources to the
+// running version, or the version being upgraded to. The spec is not saved.
+func pinMysqlVersion(c *cluster.Cluster) {
+	if version := c.GetUpgradingVersion(); len(version) != 0 {
+		c.Spec.MysqlVersion = version
+		return
+	}
+	if len(c.Status.MysqlVersion) != 0 {
+		c.Spec.MysqlVersion = c.Status.MysqlVersion
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&apiv1.Cluster{}).
 		Owns(&appsv1.StatefulSet{}).
+		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Service{}).
+		Owns(&policyv1beta1.PodDisruptionBudget{}).
 		Owns(&rbacv1.Role{}).
 		Owns(&rbacv1.RoleBinding{}).
 		Owns(&corev1.ServiceAccount{}).
 		Owns(&corev1.Secret{}).
+		// restart the pods when the certificate is renewed.
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(
+			func(obj client.Object) []reconcile.Request {
+				list := apiv1.ClusterList{}
+				if err := r.List(context.TODO(), &list, client.InNamespace(obj.GetNamespace())); err != nil {
+					r.Log.Error(err, "failed to list the clusters")
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for i := range list.Items {
+					c := cluster.New(&list.Items[i])
+					if c.Spec.TLS != nil && c.GetTLSSecretName() == obj.GetName() {
+						requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+							Namespace: c.Namespace,
+							Name:      c.Name,
+						}})
+					}
+				}
+				return requests
+			})).
 		Complete(r)
 }
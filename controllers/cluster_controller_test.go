@@ -0,0 +1,220 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestFinalizeCluster(t *testing.T) {
+	destination := &apiv1.BackupDestination{Endpoint: "http://minio:9000", Bucket: "backups"}
+	tests := []struct {
+		name        string
+		policy      apiv1.ClusterDeletionPolicy
+		finalBackup string
+		// the status of the final backup, nil if it does not exist.
+		backupOK      *corev1.ConditionStatus
+		completed     bool
+		wantRetained  bool
+		wantFinalized bool
+		wantRequeue   bool
+		wantBackup    bool
+	}{
+		{
+			name:          "retain",
+			policy:        apiv1.ClusterRetain,
+			wantRetained:  true,
+			wantFinalized: true,
+		},
+		{
+			name:        "final backup created",
+			policy:      apiv1.ClusterBackupThenDelete,
+			wantRequeue: true,
+			wantBackup:  true,
+		},
+		{
+			name:        "waiting for the final backup",
+			policy:      apiv1.ClusterBackupThenDelete,
+			finalBackup: "sample-final",
+			backupOK:    conditionPtr(corev1.ConditionUnknown),
+			wantRequeue: true,
+		},
+		{
+			name:          "final backup succeeded",
+			policy:        apiv1.ClusterBackupThenDelete,
+			finalBackup:   "sample-final",
+			backupOK:      conditionPtr(corev1.ConditionTrue),
+			completed:     true,
+			wantFinalized: true,
+		},
+		{
+			name:          "final backup failed",
+			policy:        apiv1.ClusterBackupThenDelete,
+			finalBackup:   "sample-final",
+			backupOK:      conditionPtr(corev1.ConditionFalse),
+			completed:     true,
+			wantRetained:  true,
+			wantFinalized: true,
+		},
+		{
+			name:          "final backup not found",
+			policy:        apiv1.ClusterBackupThenDelete,
+			finalBackup:   "sample-final",
+			wantRetained:  true,
+			wantFinalized: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := metav1.Now()
+			c := cluster.New(&apiv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "sample",
+					Namespace:         "default",
+					UID:               "cluster-uid",
+					DeletionTimestamp: &now,
+					Finalizers:        []string{cluster.DeletionFinalizer},
+				},
+				Spec: apiv1.ClusterSpec{DeletionPolicy: tt.policy, FinalBackup: destination},
+			})
+			c.Status.FinalBackup = tt.finalBackup
+
+			owner := []metav1.OwnerReference{{APIVersion: apiv1.GroupVersion.String(), Kind: "Cluster", Name: c.Name, UID: c.UID}}
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name:            "data-sample-mysql-0",
+				Namespace:       c.Namespace,
+				Labels:          c.GetSelectorLabels(),
+				OwnerReferences: owner,
+			}}
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:            c.GetNameForResource(utils.Secret),
+				Namespace:       c.Namespace,
+				OwnerReferences: owner,
+			}}
+			objs := []client.Object{c.Unwrap().DeepCopy(), pvc, secret}
+			if tt.backupOK != nil {
+				objs = append(objs, &apiv1.Backup{
+					ObjectMeta: metav1.ObjectMeta{Name: tt.finalBackup, Namespace: c.Namespace},
+					Status: apiv1.BackupStatus{
+						Completed:  tt.completed,
+						Conditions: []apiv1.BackupCondition{{Type: apiv1.BackupComplete, Status: *tt.backupOK}},
+					},
+				})
+			}
+
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = apiv1.AddToScheme(scheme)
+			r := &ClusterReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+				Log:      logf.Log.WithName("test"),
+				Scheme:   scheme,
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			// the update of the cluster needs the resource version.
+			if err := r.Get(context.TODO(), client.ObjectKeyFromObject(c.Unwrap()), c.Unwrap()); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := r.finalizeCluster(context.TODO(), c)
+			if err != nil {
+				t.Fatalf("finalizeCluster() error = %v", err)
+			}
+			if requeue := result.RequeueAfter > 0; requeue != tt.wantRequeue {
+				t.Errorf("requeue = %v, want %v", requeue, tt.wantRequeue)
+			}
+			if finalized := !controllerutil.ContainsFinalizer(c.Unwrap(), cluster.DeletionFinalizer); finalized != tt.wantFinalized {
+				t.Errorf("finalized = %v, want %v", finalized, tt.wantFinalized)
+			}
+			if created := len(c.Status.FinalBackup) != 0 && len(tt.finalBackup) == 0; created != tt.wantBackup {
+				t.Errorf("final backup created = %v, want %v", created, tt.wantBackup)
+			}
+
+			for _, obj := range []client.Object{&corev1.PersistentVolumeClaim{}, &corev1.Secret{}} {
+				key := client.ObjectKeyFromObject(pvc)
+				if _, ok := obj.(*corev1.Secret); ok {
+					key = client.ObjectKeyFromObject(secret)
+				}
+				if err := r.Get(context.TODO(), key, obj); err != nil {
+					t.Fatal(err)
+				}
+				if retained := len(obj.GetOwnerReferences()) == 0; retained != tt.wantRetained {
+					t.Errorf("%s retained = %v, want %v", key.Name, retained, tt.wantRetained)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateFinalBackup(t *testing.T) {
+	c := cluster.New(&apiv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", UID: "0123456789abcdef"},
+		Spec: apiv1.ClusterSpec{
+			FinalBackup: &apiv1.BackupDestination{Endpoint: "http://minio:9000", Bucket: "backups"},
+		},
+	})
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = apiv1.AddToScheme(scheme)
+	r := &ClusterReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		Log:    logf.Log.WithName("test"),
+		Scheme: scheme,
+	}
+
+	// the status may fail to record the backup, creating it again returns the
+	// existing one.
+	for i := 0; i < 2; i++ {
+		b, err := r.createFinalBackup(context.TODO(), c)
+		if err != nil {
+			t.Fatalf("createFinalBackup() error = %v", err)
+		}
+		if b.Name != "sample-final-01234567" {
+			t.Errorf("createFinalBackup() = %s, want sample-final-01234567", b.Name)
+		}
+	}
+
+	backups := apiv1.BackupList{}
+	if err := r.List(context.TODO(), &backups, client.InNamespace(c.Namespace)); err != nil {
+		t.Fatal(err)
+	}
+	if len(backups.Items) != 1 {
+		t.Errorf("%d backups created, want 1", len(backups.Items))
+	}
+}
+
+func conditionPtr(status corev1.ConditionStatus) *corev1.ConditionStatus {
+	return &status
+}
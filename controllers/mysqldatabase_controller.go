@@ -0,0 +1,173 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/mysqldatabase"
+)
+
+// databaseRequeueAfter is the time to wait before retrying to sync the database.
+const databaseRequeueAfter = 30 * time.Second
+
+// MysqlDatabaseReconciler reconciles a MysqlDatabase object
+type MysqlDatabaseReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqldatabases,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqldatabases/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqldatabases/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the database closer to the desired state. It
+// creates, alters and drops the database on the leader of the cluster.
+func (r *MysqlDatabaseReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("mysqldatabase", req.NamespacedName)
+
+	instance := mysqldatabase.New(&apiv1.MysqlDatabase{})
+	err := r.Get(ctx, req.NamespacedName, instance.Unwrap())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			log.Info("instance not found, maybe removed")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.dropDatabase(ctx, instance)
+	}
+
+	if !controllerutil.ContainsFinalizer(instance.Unwrap(), mysqldatabase.DatabaseFinalizer) {
+		controllerutil.AddFinalizer(instance.Unwrap(), mysqldatabase.DatabaseFinalizer)
+		return reconcile.Result{}, r.Update(ctx, instance.Unwrap())
+	}
+
+	status := *instance.Status.DeepCopy()
+	defer func() {
+		if !reflect.DeepEqual(status, instance.Status) {
+			sErr := r.Status().Update(ctx, instance.Unwrap())
+			if sErr != nil {
+				log.Error(sErr, "failed to update mysqldatabase status")
+			}
+		}
+	}()
+
+	mysqlCluster := cluster.New(&apiv1.Cluster{})
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: instance.Namespace,
+		Name:      instance.Spec.ClusterName,
+	}, mysqlCluster.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			instance.UpdateStatusCondition(apiv1.MysqlDatabaseReady, corev1.ConditionFalse, "ClusterNotFound",
+				fmt.Sprintf("cluster %s not found", instance.Spec.ClusterName))
+			return reconcile.Result{RequeueAfter: databaseRequeueAfter}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	query, err := mysqldatabase.BuildSyncQuery(instance)
+	if err != nil {
+		instance.UpdateStatusCondition(apiv1.MysqlDatabaseReady, corev1.ConditionFalse, "InvalidSpec", err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	runner, err := newLeaderSQLRunner(ctx, r.Client, mysqlCluster)
+	if err != nil {
+		log.Error(err, "failed to connect the leader")
+		instance.UpdateStatusCondition(apiv1.MysqlDatabaseReady, corev1.ConditionFalse, "ConnectionFailed", err.Error())
+		return reconcile.Result{RequeueAfter: databaseRequeueAfter}, nil
+	}
+	defer runner.Close()
+
+	if err = runner.RunQuery(query); err != nil {
+		log.Error(err, "failed to sync the database")
+		instance.UpdateStatusCondition(apiv1.MysqlDatabaseReady, corev1.ConditionFalse, "SyncFailed", err.Error())
+		return reconcile.Result{RequeueAfter: databaseRequeueAfter}, nil
+	}
+
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.UpdateStatusCondition(apiv1.MysqlDatabaseReady, corev1.ConditionTrue, "Synced", "the database has been synced")
+	return reconcile.Result{}, nil
+}
+
+// dropDatabase drops the database from the cluster before removing the
+// finalizer if the deletion policy is Delete. The database is left if the
+// cluster has been deleted.
+func (r *MysqlDatabaseReconciler) dropDatabase(ctx context.Context, d *mysqldatabase.MysqlDatabase) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(d.Unwrap(), mysqldatabase.DatabaseFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	if d.Spec.DeletionPolicy == apiv1.DatabaseDelete {
+		mysqlCluster := cluster.New(&apiv1.Cluster{})
+		err := r.Get(ctx, types.NamespacedName{
+			Namespace: d.Namespace,
+			Name:      d.Spec.ClusterName,
+		}, mysqlCluster.Unwrap())
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+
+		if err == nil && mysqlCluster.DeletionTimestamp.IsZero() {
+			runner, err := newLeaderSQLRunner(ctx, r.Client, mysqlCluster)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			defer runner.Close()
+
+			if err = runner.RunQuery(mysqldatabase.BuildDropQuery(d)); err != nil {
+				return reconcile.Result{}, err
+			}
+			r.Recorder.Eventf(d.Unwrap(), corev1.EventTypeNormal, "Dropped",
+				"the database %s is dropped from the cluster %s", d.Spec.Database, d.Spec.ClusterName)
+		}
+	}
+
+	controllerutil.RemoveFinalizer(d.Unwrap(), mysqldatabase.DatabaseFinalizer)
+	return reconcile.Result{}, r.Update(ctx, d.Unwrap())
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MysqlDatabaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiv1.MysqlDatabase{}).
+		Complete(r)
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/cluster"
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/mysqluser"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// userRequeueAfter is the time to wait before retrying to sync the user.
+const userRequeueAfter = 30 * time.Second
+
+// MysqlUserReconciler reconciles a MysqlUser object
+type MysqlUserReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqlusers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqlusers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=mysql.radondb.io,resources=mysqlusers/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the user closer to the desired state. It creates,
+// alters and drops the accounts on the leader of the cluster.
+func (r *MysqlUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("mysqluser", req.NamespacedName)
+
+	instance := mysqluser.New(&apiv1.MysqlUser{})
+	err := r.Get(ctx, req.NamespacedName, instance.Unwrap())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Object not found, return.  Created objects are automatically garbage collected.
+			// For additional cleanup logic use finalizers.
+			log.Info("instance not found, maybe removed")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return r.dropUser(ctx, instance)
+	}
+
+	if !controllerutil.ContainsFinalizer(instance.Unwrap(), mysqluser.UserFinalizer) {
+		controllerutil.AddFinalizer(instance.Unwrap(), mysqluser.UserFinalizer)
+		return reconcile.Result{}, r.Update(ctx, instance.Unwrap())
+	}
+
+	status := *instance.Status.DeepCopy()
+	defer func() {
+		if !reflect.DeepEqual(status, instance.Status) {
+			sErr := r.Status().Update(ctx, instance.Unwrap())
+			if sErr != nil {
+				log.Error(sErr, "failed to update mysqluser status")
+			}
+		}
+	}()
+
+	mysqlCluster := cluster.New(&apiv1.Cluster{})
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: instance.Namespace,
+		Name:      instance.Spec.ClusterName,
+	}, mysqlCluster.Unwrap()); err != nil {
+		if errors.IsNotFound(err) {
+			instance.UpdateStatusCondition(apiv1.MysqlUserReady, corev1.ConditionFalse, "ClusterNotFound",
+				fmt.Sprintf("cluster %s not found", instance.Spec.ClusterName))
+			return reconcile.Result{RequeueAfter: userRequeueAfter}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	password, err := r.getPassword(ctx, instance)
+	if err != nil {
+		instance.UpdateStatusCondition(apiv1.MysqlUserReady, corev1.ConditionFalse, "InvalidPassword", err.Error())
+		return reconcile.Result{RequeueAfter: userRequeueAfter}, nil
+	}
+
+	runner, err := newLeaderSQLRunner(ctx, r.Client, mysqlCluster)
+	if err != nil {
+		log.Error(err, "failed to connect the leader")
+		instance.UpdateStatusCondition(apiv1.MysqlUserReady, corev1.ConditionFalse, "ConnectionFailed", err.Error())
+		return reconcile.Result{RequeueAfter: userRequeueAfter}, nil
+	}
+	defer runner.Close()
+
+	if err = r.syncUser(runner, instance, password); err != nil {
+		log.Error(err, "failed to sync the user")
+		instance.UpdateStatusCondition(apiv1.MysqlUserReady, corev1.ConditionFalse, "SyncFailed", err.Error())
+		return reconcile.Result{RequeueAfter: userRequeueAfter}, nil
+	}
+
+	instance.Status.ObservedGeneration = instance.Generation
+	instance.UpdateStatusCondition(apiv1.MysqlUserReady, corev1.ConditionTrue, "Synced", "the user has been synced")
+	return reconcile.Result{}, nil
+}
+
+// syncUser creates or alters the accounts of the hosts, and drops the
+// accounts of the hosts removed from the spec. The accounts which match the
+// spec are left untouched.
+func (r *MysqlUserReconciler) syncUser(runner *internal.SQLRunner, u *mysqluser.MysqlUser, password string) error {
+	hosts := u.GetHosts()
+	for _, host := range hosts {
+		account, err := runner.GetUserAccount(u.Spec.User, host)
+		if err != nil {
+			return fmt.Errorf("failed to get %s@%s: %s", u.Spec.User, host, err)
+		}
+		query, args, err := mysqluser.BuildSyncQuery(u, host, password, account)
+		if err != nil {
+			return err
+		}
+		if len(query) == 0 {
+			continue
+		}
+		if err = runner.RunQuery(query, args...); err != nil {
+			return fmt.Errorf("failed to sync %s@%s: %s", u.Spec.User, host, err)
+		}
+	}
+
+	for _, host := range u.Status.AllowedHosts {
+		if utils.StringInSlice(host, hosts) {
+			continue
+		}
+		query, args := mysqluser.BuildDropQuery(u.Spec.User, host)
+		if err := runner.RunQuery(query, args...); err != nil {
+			return fmt.Errorf("failed to drop %s@%s: %s", u.Spec.User, host, err)
+		}
+	}
+
+	u.Status.AllowedHosts = hosts
+	return nil
+}
+
+// dropUser drops the accounts from the cluster before removing the finalizer.
+// The accounts are left if the cluster has been deleted.
+func (r *MysqlUserReconciler) dropUser(ctx context.Context, u *mysqluser.MysqlUser) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(u.Unwrap(), mysqluser.UserFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	mysqlCluster := cluster.New(&apiv1.Cluster{})
+	err := r.Get(ctx, types.NamespacedName{
+		Namespace: u.Namespace,
+		Name:      u.Spec.ClusterName,
+	}, mysqlCluster.Unwrap())
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+
+	if err == nil && mysqlCluster.DeletionTimestamp.IsZero() && len(u.Status.AllowedHosts) > 0 {
+		runner, err := newLeaderSQLRunner(ctx, r.Client, mysqlCluster)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		defer runner.Close()
+
+		for _, host := range u.Status.AllowedHosts {
+			query, args := mysqluser.BuildDropQuery(u.Spec.User, host)
+			if err = runner.RunQuery(query, args...); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	controllerutil.RemoveFinalizer(u.Unwrap(), mysqluser.UserFinalizer)
+	return reconcile.Result{}, r.Update(ctx, u.Unwrap())
+}
+
+// getPassword returns the password of the user from the secret.
+func (r *MysqlUserReconciler) getPassword(ctx context.Context, u *mysqluser.MysqlUser) (string, error) {
+	ref := u.Spec.PasswordSecretRef
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{
+		Namespace: u.Namespace,
+		Name:      ref.Name,
+	}, secret); err != nil {
+		return "", fmt.Errorf("failed to get the secret %s: %s", ref.Name, err)
+	}
+
+	password, ok := secret.Data[ref.Key]
+	if !ok || len(password) == 0 {
+		return "", fmt.Errorf("the key %s of the secret %s is empty", ref.Key, ref.Name)
+	}
+	return string(password), nil
+}
+
+// newLeaderSQLRunner connects the leader of the cluster with the operator user.
+func newLeaderSQLRunner(ctx context.Context, cli client.Client, c *cluster.Cluster) (*internal.SQLRunner, error) {
+	secret := &corev1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{
+		Namespace: c.Namespace,
+		Name:      c.GetNameForResource(utils.Secret),
+	}, secret); err != nil {
+		return nil, err
+	}
+
+	user, ok := secret.Data["operator-user"]
+	if !ok {
+		return nil, fmt.Errorf("failed to get the operator user from the secret")
+	}
+	password, ok := secret.Data["operator-password"]
+	if !ok {
+		return nil, fmt.Errorf("failed to get the operator password from the secret")
+	}
+
+	host := fmt.Sprintf("%s.%s", c.GetNameForResource(utils.LeaderService), c.Namespace)
+	return internal.NewSQLRunner(utils.BytesToString(user), utils.BytesToString(password), host, utils.MysqlPort)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MysqlUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&apiv1.MysqlUser{}).
+		// sync the users again when their passwords are changed.
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(
+			func(obj client.Object) []reconcile.Request {
+				list := apiv1.MysqlUserList{}
+				if err := r.List(context.TODO(), &list, client.InNamespace(obj.GetNamespace())); err != nil {
+					r.Log.Error(err, "failed to list the mysqlusers")
+					return nil
+				}
+
+				var requests []reconcile.Request
+				for _, u := range list.Items {
+					if u.Spec.PasswordSecretRef.Name == obj.GetName() {
+						requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+							Namespace: u.Namespace,
+							Name:      u.Name,
+						}})
+					}
+				}
+				return requests
+			})).
+		Complete(r)
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
 	"time"
@@ -27,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -98,6 +100,30 @@ func (r *StatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return reconcile.Result{}, err
 	}
 
+	// the syncers below rely on the status updated above, but not on each
+	// other, so a failure is logged and retried without blocking the others.
+	syncers := []syncer.Interface{
+		clustersyncer.NewDiskSyncer(log, r.Client, instance),
+		clustersyncer.NewUpgradeSyncer(log, r.Client, instance),
+		clustersyncer.NewReplicationTLSSyncer(log, r.Client, instance),
+		clustersyncer.NewCredentialSyncer(log, r.Client, instance),
+		clustersyncer.NewSwitchoverSyncer(log, r.Client, instance),
+		clustersyncer.NewVolumeExpansionSyncer(log, r.Client, instance),
+		clustersyncer.NewProxySQLSyncer(log, r.Client, instance),
+		clustersyncer.NewMysqlConfSyncer(log, r.Client, instance),
+		clustersyncer.NewRolloutSyncer(log, r.Client, instance),
+	}
+	var errs []error
+	for _, sync := range syncers {
+		if err := syncer.Sync(ctx, sync, r.Recorder); err != nil {
+			log.Error(err, "failed to sync", "syncer", fmt.Sprintf("%T", sync))
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return reconcile.Result{}, utilerrors.NewAggregate(errs)
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -9,9 +9,11 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/imdario/mergo v0.3.11
 	github.com/kr/text v0.2.0 // indirect
+	github.com/minio/minio-go/v7 v7.0.12
 	github.com/onsi/ginkgo v1.15.0
 	github.com/onsi/gomega v1.10.5
 	github.com/presslabs/controller-util v0.3.0-alpha.2
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/cobra v1.1.1
 	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
@@ -0,0 +1,112 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// sidecarRequestTimeout is the timeout of the requests to the sidecar, which
+// covers connecting mysql.
+const sidecarRequestTimeout = 10 * time.Second
+
+// NodeState is the state of the node served by the sidecar api.
+type NodeState struct {
+	// Role is the raft state of xenon, eg: LEADER, FOLLOWER, CANDIDATE.
+	Role        string                 `json:"role,omitempty"`
+	Leader      corev1.ConditionStatus `json:"leader"`
+	ReadOnly    corev1.ConditionStatus `json:"readOnly"`
+	Replicating corev1.ConditionStatus `json:"replicating"`
+	Lagged      corev1.ConditionStatus `json:"lagged"`
+	// Message is the last error of checking the node.
+	Message string `json:"message,omitempty"`
+}
+
+// SidecarClient requests the http servers of the sidecar in the mysql pod,
+// authenticated by the backup user.
+type SidecarClient struct {
+	user     string
+	password string
+	host     string
+	port     int
+	client   *http.Client
+}
+
+func NewSidecarClient(user, password, host string, port int) *SidecarClient {
+	return &SidecarClient{
+		user:     user,
+		password: password,
+		host:     host,
+		port:     port,
+		client:   &http.Client{Timeout: sidecarRequestTimeout},
+	}
+}
+
+// GetNodeState returns the role, the read-only and the replication state of the node.
+func (c *SidecarClient) GetNodeState() (*NodeState, error) {
+	state := &NodeState{}
+	if err := c.do(http.MethodGet, utils.NodeStatusPath, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SetLeaderWritable turns off the read-only of the node if it is the leader.
+func (c *SidecarClient) SetLeaderWritable() error {
+	return c.do(http.MethodPost, utils.LeaderWritablePath, nil)
+}
+
+// GetDiskUsage returns the disk usage of the pod.
+func (c *SidecarClient) GetDiskUsage() (*apiv1.DiskUsage, error) {
+	usage := &apiv1.DiskUsage{}
+	if err := c.do(http.MethodGet, utils.DiskUsagePath, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// do requests the path and decodes the json response into out if it is not nil.
+func (c *SidecarClient) do(method, path string, out interface{}) error {
+	url := fmt.Sprintf("http://%s:%d%s", c.host, c.port, path)
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.user, c.password)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request %s failed: %s", path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// newTestSidecar returns a client of the server, which serves the node state
+// to the user with the password.
+func newTestSidecar(t *testing.T, password string, state *NodeState) *SidecarClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "backupUser" || pass != "backupPassword" {
+			http.Error(w, "not authenticated", http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == utils.NodeStatusPath && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(state)
+		case r.URL.Path == utils.LeaderWritablePath && r.Method == http.MethodPost:
+			if state.Role != "LEADER" {
+				http.Error(w, "not the leader", http.StatusConflict)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSidecarClient("backupUser", password, host, p)
+}
+
+func TestSidecarClient(t *testing.T) {
+	leader := &NodeState{
+		Role:        "LEADER",
+		Leader:      corev1.ConditionTrue,
+		ReadOnly:    corev1.ConditionFalse,
+		Replicating: corev1.ConditionFalse,
+		Lagged:      corev1.ConditionFalse,
+	}
+
+	t.Run("node state", func(t *testing.T) {
+		got, err := newTestSidecar(t, "backupPassword", leader).GetNodeState()
+		if err != nil {
+			t.Fatalf("GetNodeState() error = %v", err)
+		}
+		if *got != *leader {
+			t.Errorf("GetNodeState() = %v, want %v", got, leader)
+		}
+	})
+
+	t.Run("not authenticated", func(t *testing.T) {
+		if _, err := newTestSidecar(t, "wrong", leader).GetNodeState(); err == nil {
+			t.Errorf("GetNodeState() error = nil, want an error")
+		}
+	})
+
+	t.Run("leader writable", func(t *testing.T) {
+		if err := newTestSidecar(t, "backupPassword", leader).SetLeaderWritable(); err != nil {
+			t.Errorf("SetLeaderWritable() error = %v", err)
+		}
+	})
+
+	t.Run("follower writable", func(t *testing.T) {
+		follower := &NodeState{Role: "FOLLOWER"}
+		if err := newTestSidecar(t, "backupPassword", follower).SetLeaderWritable(); err == nil {
+			t.Errorf("SetLeaderWritable() error = nil, want an error")
+		}
+	})
+}
@@ -43,7 +43,7 @@ type SQLRunner struct {
 }
 
 func NewSQLRunner(user, password, host string, port int) (*SQLRunner, error) {
-	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/?timeout=5s&interpolateParams=true&multiStatements=true",
+	dataSourceName := fmt.Sprintf("%s:%s@tcp(%s:%d)/?timeout=5s&interpolateParams=true&multiStatements=true&tls=preferred",
 		user, password, host, port,
 	)
 	db, err := sql.Open("mysql", dataSourceName)
@@ -75,6 +75,11 @@ func (s *SQLRunner) CheckSlaveStatusWithRetry(retry uint32) (isLagged, isReplica
 	return
 }
 
+// CheckSlaveStatus checks the replication once without retrying.
+func (s *SQLRunner) CheckSlaveStatus() (isLagged, isReplicating corev1.ConditionStatus, err error) {
+	return s.checkSlaveStatus()
+}
+
 func (s *SQLRunner) checkSlaveStatus() (isLagged, isReplicating corev1.ConditionStatus, err error) {
 	var rows *sql.Rows
 	isLagged, isReplicating = corev1.ConditionUnknown, corev1.ConditionUnknown
@@ -156,10 +161,191 @@ func (sr *SQLRunner) GetGlobalVariable(param string, val interface{}) error {
 	return sr.db.QueryRow(query).Scan(val)
 }
 
+// EnableReplicationSSL makes the replication channel use ssl, it returns true
+// if the channel has been changed. The other options of the channel are kept.
+func (sr *SQLRunner) EnableReplicationSSL() (bool, error) {
+	rows, err := sr.db.Query("show slave status;")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, rows.Err()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return false, err
+	}
+	scanArgs := make([]interface{}, len(cols))
+	for i := range scanArgs {
+		scanArgs[i] = &sql.RawBytes{}
+	}
+	if err = rows.Scan(scanArgs...); err != nil {
+		return false, err
+	}
+	if columnValue(scanArgs, cols, "Master_SSL_Allowed") == "Yes" {
+		return false, nil
+	}
+	rows.Close()
+
+	_, err = sr.db.Exec("STOP SLAVE IO_THREAD;\nCHANGE MASTER TO MASTER_SSL=1;\nSTART SLAVE IO_THREAD;")
+	return err == nil, err
+}
+
+// CheckGtidSubset checks whether all the transactions of the gtid set have been executed.
+func (sr *SQLRunner) CheckGtidSubset(gtid string) (bool, error) {
+	var subset bool
+	err := sr.db.QueryRow("SELECT GTID_SUBSET(?, @@GLOBAL.gtid_executed)", gtid).Scan(&subset)
+	return subset, err
+}
+
+// SetGlobalVariable sets the global variable, the name must have been validated.
+func (sr *SQLRunner) SetGlobalVariable(name string, value interface{}) error {
+	_, err := sr.db.Exec(fmt.Sprintf("SET GLOBAL %s = ?", name), value)
+	return err
+}
+
+// GetNativePasswordUsers returns the password hashes of the unlocked users
+// which can connect from any host with mysql_native_password.
+func (sr *SQLRunner) GetNativePasswordUsers() (map[string]string, error) {
+	rows, err := sr.db.Query("SELECT user, authentication_string FROM mysql.user WHERE host = '%' " +
+		"AND plugin = 'mysql_native_password' AND authentication_string != '' AND account_locked = 'N'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make(map[string]string)
+	for rows.Next() {
+		var user, password string
+		if err := rows.Scan(&user, &password); err != nil {
+			return nil, err
+		}
+		users[user] = password
+	}
+	return users, rows.Err()
+}
+
+// UserAccount is the state of an account on the server.
+type UserAccount struct {
+	// Plugin is the authentication plugin, AuthenticationString is the
+	// password hash of the plugin.
+	Plugin               string
+	AuthenticationString string
+	MaxQuestions         int32
+	MaxUpdates           int32
+	MaxConnections       int32
+	MaxUserConnections   int32
+	// Grants are the statements returned by SHOW GRANTS.
+	Grants []string
+}
+
+// GetUserAccount returns the account of the user on the host, nil if it does not exist.
+func (sr *SQLRunner) GetUserAccount(user, host string) (*UserAccount, error) {
+	account := &UserAccount{}
+	err := sr.db.QueryRow("SELECT plugin, authentication_string, max_questions, max_updates, "+
+		"max_connections, max_user_connections FROM mysql.user WHERE user = ? AND host = ?", user, host).Scan(
+		&account.Plugin, &account.AuthenticationString, &account.MaxQuestions, &account.MaxUpdates,
+		&account.MaxConnections, &account.MaxUserConnections)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := sr.db.Query("SHOW GRANTS FOR ?@?", user, host)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return nil, err
+		}
+		account.Grants = append(account.Grants, grant)
+	}
+	return account, rows.Err()
+}
+
+// GetBinaryLogs returns the names of the binlogs in order.
+func (sr *SQLRunner) GetBinaryLogs() ([]string, error) {
+	rows, err := sr.db.Query("SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for rows.Next() {
+		// the columns differ between the versions, the first one is the name.
+		scanArgs := make([]interface{}, len(cols))
+		for i := range scanArgs {
+			scanArgs[i] = &sql.RawBytes{}
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		names = append(names, string(*scanArgs[0].(*sql.RawBytes)))
+	}
+	return names, rows.Err()
+}
+
+// GetReplicationSource returns the host of the master and the binlog of the
+// master which contains the last executed event, they are empty if the node
+// is not replicating.
+func (sr *SQLRunner) GetReplicationSource() (host, binlog string, err error) {
+	rows, err := sr.db.Query("show slave status;")
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return "", "", rows.Err()
+	}
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", "", err
+	}
+	scanArgs := make([]interface{}, len(cols))
+	for i := range scanArgs {
+		scanArgs[i] = &sql.RawBytes{}
+	}
+	if err = rows.Scan(scanArgs...); err != nil {
+		return "", "", err
+	}
+	return columnValue(scanArgs, cols, "Master_Host"), columnValue(scanArgs, cols, "Relay_Master_Log_File"), nil
+}
+
+// RunQuery executes the statements, the arguments are interpolated by the driver.
+func (sr *SQLRunner) RunQuery(query string, args ...interface{}) error {
+	_, err := sr.db.Exec(query, args...)
+	return err
+}
+
 func (sr *SQLRunner) Close() error {
 	return sr.db.Close()
 }
 
+// EscapeIdentifier quotes the name of the database or table, `*` is kept as is.
+func EscapeIdentifier(name string) string {
+	if name == "*" {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
 func columnValue(scanArgs []interface{}, slaveCols []string, colName string) string {
 	columnIndex := -1
 	for idx := range slaveCols {
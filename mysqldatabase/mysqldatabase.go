@@ -0,0 +1,87 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqldatabase
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+// DatabaseFinalizer is the finalizer that ensures the database is dropped from
+// the cluster together with the MysqlDatabase if the deletion policy is Delete.
+const DatabaseFinalizer = "mysql.radondb.io/database"
+
+type MysqlDatabase struct {
+	*apiv1.MysqlDatabase
+}
+
+func New(d *apiv1.MysqlDatabase) *MysqlDatabase {
+	return &MysqlDatabase{
+		MysqlDatabase: d,
+	}
+}
+
+// Unwrap returns the api mysqldatabase object
+func (d *MysqlDatabase) Unwrap() *apiv1.MysqlDatabase {
+	return d.MysqlDatabase
+}
+
+// UpdateStatusCondition sets the condition to a status.
+// for example Ready condition to True, or False
+func (d *MysqlDatabase) UpdateStatusCondition(condType apiv1.MysqlDatabaseConditionType,
+	status corev1.ConditionStatus, reason, msg string) {
+	newCondition := apiv1.MysqlDatabaseCondition{
+		Type:    condType,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+
+	t := time.Now()
+
+	if len(d.Status.Conditions) == 0 {
+		newCondition.LastTransitionTime = metav1.NewTime(t)
+		d.Status.Conditions = []apiv1.MysqlDatabaseCondition{newCondition}
+	} else {
+		if i, exist := d.condExists(condType); exist {
+			cond := d.Status.Conditions[i]
+			if cond.Status != newCondition.Status {
+				newCondition.LastTransitionTime = metav1.NewTime(t)
+			} else {
+				newCondition.LastTransitionTime = cond.LastTransitionTime
+			}
+			d.Status.Conditions[i] = newCondition
+		} else {
+			newCondition.LastTransitionTime = metav1.NewTime(t)
+			d.Status.Conditions = append(d.Status.Conditions, newCondition)
+		}
+	}
+}
+
+func (d *MysqlDatabase) condExists(ty apiv1.MysqlDatabaseConditionType) (int, bool) {
+	for i, cond := range d.Status.Conditions {
+		if cond.Type == ty {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
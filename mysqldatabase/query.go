@@ -0,0 +1,59 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqldatabase
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/zhyass/mysql-operator/internal"
+)
+
+// charsetRegexp matches the names of the character sets and collations, they
+// can't be passed as the query arguments.
+var charsetRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// BuildSyncQuery returns the statements that create the database or change
+// its character set and collation.
+func BuildSyncQuery(d *MysqlDatabase) (string, error) {
+	var options strings.Builder
+	if len(d.Spec.CharacterSet) != 0 {
+		if !charsetRegexp.MatchString(d.Spec.CharacterSet) {
+			return "", fmt.Errorf("invalid character set %q", d.Spec.CharacterSet)
+		}
+		options.WriteString(" CHARACTER SET " + d.Spec.CharacterSet)
+	}
+	if len(d.Spec.Collation) != 0 {
+		if !charsetRegexp.MatchString(d.Spec.Collation) {
+			return "", fmt.Errorf("invalid collation %q", d.Spec.Collation)
+		}
+		options.WriteString(" COLLATE " + d.Spec.Collation)
+	}
+
+	name := internal.EscapeIdentifier(d.Spec.Database)
+	query := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s%s;\n", name, options.String())
+	if options.Len() != 0 {
+		query += fmt.Sprintf("ALTER DATABASE %s%s;\n", name, options.String())
+	}
+	return query, nil
+}
+
+// BuildDropQuery returns the statement that drops the database.
+func BuildDropQuery(d *MysqlDatabase) string {
+	return fmt.Sprintf("DROP DATABASE IF EXISTS %s;", internal.EscapeIdentifier(d.Spec.Database))
+}
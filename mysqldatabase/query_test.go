@@ -0,0 +1,78 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqldatabase
+
+import (
+	"testing"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+func TestBuildSyncQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    apiv1.MysqlDatabaseSpec
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default charset",
+			spec: apiv1.MysqlDatabaseSpec{Database: "shop"},
+			want: "CREATE DATABASE IF NOT EXISTS `shop`;\n",
+		},
+		{
+			name: "charset and collation",
+			spec: apiv1.MysqlDatabaseSpec{Database: "shop", CharacterSet: "utf8mb4", Collation: "utf8mb4_bin"},
+			want: "CREATE DATABASE IF NOT EXISTS `shop` CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;\n" +
+				"ALTER DATABASE `shop` CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;\n",
+		},
+		{
+			name: "quoted name",
+			spec: apiv1.MysqlDatabaseSpec{Database: "my`db"},
+			want: "CREATE DATABASE IF NOT EXISTS `my``db`;\n",
+		},
+		{
+			name:    "invalid charset",
+			spec:    apiv1.MysqlDatabaseSpec{Database: "shop", CharacterSet: "utf8; DROP DATABASE mysql"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid collation",
+			spec:    apiv1.MysqlDatabaseSpec{Database: "shop", Collation: "utf8_bin`"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildSyncQuery(New(&apiv1.MysqlDatabase{Spec: tt.spec}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildSyncQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildSyncQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDropQuery(t *testing.T) {
+	d := New(&apiv1.MysqlDatabase{Spec: apiv1.MysqlDatabaseSpec{Database: "shop"}})
+	if got, want := BuildDropQuery(d), "DROP DATABASE IF EXISTS `shop`;"; got != want {
+		t.Errorf("BuildDropQuery() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqluser
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+)
+
+// UserFinalizer is the finalizer that ensures the accounts are dropped from
+// the cluster together with the MysqlUser.
+const UserFinalizer = "mysql.radondb.io/user"
+
+type MysqlUser struct {
+	*apiv1.MysqlUser
+}
+
+func New(u *apiv1.MysqlUser) *MysqlUser {
+	return &MysqlUser{
+		MysqlUser: u,
+	}
+}
+
+// Unwrap returns the api mysqluser object
+func (u *MysqlUser) Unwrap() *apiv1.MysqlUser {
+	return u.MysqlUser
+}
+
+// GetHosts returns the hosts of the accounts, `%` if none is specified.
+func (u *MysqlUser) GetHosts() []string {
+	if len(u.Spec.Hosts) == 0 {
+		return []string{"%"}
+	}
+	return u.Spec.Hosts
+}
+
+// UpdateStatusCondition sets the condition to a status.
+// for example Ready condition to True, or False
+func (u *MysqlUser) UpdateStatusCondition(condType apiv1.MysqlUserConditionType,
+	status corev1.ConditionStatus, reason, msg string) {
+	newCondition := apiv1.MysqlUserCondition{
+		Type:    condType,
+		Status:  status,
+		Reason:  reason,
+		Message: msg,
+	}
+
+	t := time.Now()
+
+	if len(u.Status.Conditions) == 0 {
+		newCondition.LastTransitionTime = metav1.NewTime(t)
+		u.Status.Conditions = []apiv1.MysqlUserCondition{newCondition}
+	} else {
+		if i, exist := u.condExists(condType); exist {
+			cond := u.Status.Conditions[i]
+			if cond.Status != newCondition.Status {
+				newCondition.LastTransitionTime = metav1.NewTime(t)
+			} else {
+				newCondition.LastTransitionTime = cond.LastTransitionTime
+			}
+			u.Status.Conditions[i] = newCondition
+		} else {
+			newCondition.LastTransitionTime = metav1.NewTime(t)
+			u.Status.Conditions = append(u.Status.Conditions, newCondition)
+		}
+	}
+}
+
+func (u *MysqlUser) condExists(ty apiv1.MysqlUserConditionType) (int, bool) {
+	for i, cond := range u.Status.Conditions {
+		if cond.Type == ty {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
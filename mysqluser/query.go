@@ -0,0 +1,255 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqluser
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/internal"
+)
+
+// allPrivileges is the name of the privilege that includes all the others
+// except GRANT OPTION.
+const allPrivileges = "ALL PRIVILEGES"
+
+// privilegeRegexp matches the privileges such as SELECT and ALL PRIVILEGES,
+// the privileges can't be passed as the query arguments.
+var privilegeRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*$`)
+
+// grantRegexp matches the privileges and the object of a SHOW GRANTS statement,
+// the role grants of mysql 8.0 have no object and are not matched.
+var grantRegexp = regexp.MustCompile(`^GRANT (.+?) ON (.+) TO .+?( WITH GRANT OPTION)?$`)
+
+// BuildSyncQuery returns the statements that create the account on the host,
+// or change the account to match the spec. Only the privileges which differ
+// from the grants of the account are revoked or granted, so the account keeps
+// its other privileges while the user is synced. It returns an empty query if
+// the account is up to date.
+func BuildSyncQuery(u *MysqlUser, host, password string, account *internal.UserAccount) (string, []interface{}, error) {
+	var query strings.Builder
+	args := []interface{}{}
+	limits := u.Spec.ResourceLimits
+
+	if account == nil {
+		query.WriteString("CREATE USER IF NOT EXISTS ?@? IDENTIFIED BY ?" + buildLimits(limits) + ";\n")
+		args = append(args, u.Spec.User, host, password)
+	} else {
+		passwordChanged := !passwordMatches(account, password)
+		limitsChanged := limits != nil && (limits.MaxQueriesPerHour != account.MaxQuestions ||
+			limits.MaxUpdatesPerHour != account.MaxUpdates ||
+			limits.MaxConnectionsPerHour != account.MaxConnections ||
+			limits.MaxUserConnections != account.MaxUserConnections)
+		if passwordChanged || limitsChanged {
+			query.WriteString("ALTER USER ?@?")
+			args = append(args, u.Spec.User, host)
+			if passwordChanged {
+				query.WriteString(" IDENTIFIED BY ?")
+				args = append(args, password)
+			}
+			if limitsChanged {
+				query.WriteString(buildLimits(limits))
+			}
+			query.WriteString(";\n")
+		}
+	}
+
+	desired, objects, err := buildPrivileges(u.Spec.Permissions)
+	if err != nil {
+		return "", nil, err
+	}
+	current, grantOption := map[string][]string{}, map[string]bool{}
+	if account != nil {
+		current, grantOption = parseGrants(account.Grants)
+	}
+
+	// revoke before granting, the revoked ALL PRIVILEGES would take the
+	// granted privileges away.
+	var revokeObjects []string
+	for on := range current {
+		revokeObjects = append(revokeObjects, on)
+	}
+	for on := range grantOption {
+		if _, ok := current[on]; !ok {
+			revokeObjects = append(revokeObjects, on)
+		}
+	}
+	sort.Strings(revokeObjects)
+	for _, on := range revokeObjects {
+		var revoke []string
+		// ALL PRIVILEGES includes the others.
+		if !containsPrivilege(desired[on], allPrivileges) {
+			for _, priv := range current[on] {
+				if !containsPrivilege(desired[on], priv) {
+					revoke = append(revoke, priv)
+				}
+			}
+		}
+		if grantOption[on] {
+			revoke = append(revoke, "GRANT OPTION")
+		}
+		if len(revoke) != 0 {
+			query.WriteString(fmt.Sprintf("REVOKE %s ON %s FROM ?@?;\n", strings.Join(revoke, ", "), on))
+			args = append(args, u.Spec.User, host)
+		}
+	}
+
+	for _, on := range objects {
+		var grant []string
+		// the kept ALL PRIVILEGES includes the others.
+		if !containsPrivilege(current[on], allPrivileges) || !containsPrivilege(desired[on], allPrivileges) {
+			for _, priv := range desired[on] {
+				if !containsPrivilege(current[on], priv) {
+					grant = append(grant, priv)
+				}
+			}
+		}
+		if len(grant) != 0 {
+			query.WriteString(fmt.Sprintf("GRANT %s ON %s TO ?@?;\n", strings.Join(grant, ", "), on))
+			args = append(args, u.Spec.User, host)
+		}
+	}
+
+	if query.Len() == 0 {
+		return "", nil, nil
+	}
+	return query.String(), args, nil
+}
+
+// BuildDropQuery returns the statement that drops the account on the host.
+func BuildDropQuery(user, host string) (string, []interface{}) {
+	return "DROP USER IF EXISTS ?@?;", []interface{}{user, host}
+}
+
+// buildLimits returns the resource options of the account, empty if there are no limits.
+func buildLimits(limits *apiv1.MysqlUserResourceLimits) string {
+	if limits == nil {
+		return ""
+	}
+	return fmt.Sprintf(" WITH MAX_QUERIES_PER_HOUR %d MAX_UPDATES_PER_HOUR %d"+
+		" MAX_CONNECTIONS_PER_HOUR %d MAX_USER_CONNECTIONS %d", limits.MaxQueriesPerHour,
+		limits.MaxUpdatesPerHour, limits.MaxConnectionsPerHour, limits.MaxUserConnections)
+}
+
+// passwordMatches returns true if the account uses mysql_native_password with
+// the password, the hashes of the other plugins are salted and can't be compared.
+func passwordMatches(account *internal.UserAccount, password string) bool {
+	if account.Plugin != "mysql_native_password" {
+		return false
+	}
+	first := sha1.Sum([]byte(password))
+	second := sha1.Sum(first[:])
+	return account.AuthenticationString == "*"+strings.ToUpper(hex.EncodeToString(second[:]))
+}
+
+// buildPrivileges returns the privileges of the permissions by the object, the
+// objects are returned in the order of the permissions.
+func buildPrivileges(perms []apiv1.MysqlPermission) (map[string][]string, []string, error) {
+	privileges := map[string][]string{}
+	var objects []string
+	for _, perm := range perms {
+		for _, priv := range perm.Privileges {
+			if !privilegeRegexp.MatchString(priv) {
+				return nil, nil, fmt.Errorf("invalid privilege %q", priv)
+			}
+		}
+
+		tables := perm.Tables
+		if len(tables) == 0 {
+			tables = []string{"*"}
+		}
+		for _, table := range tables {
+			on := fmt.Sprintf("%s.%s", internal.EscapeIdentifier(perm.Database), internal.EscapeIdentifier(table))
+			if _, ok := privileges[on]; !ok {
+				objects = append(objects, on)
+			}
+			for _, priv := range perm.Privileges {
+				if priv = normalizePrivilege(priv); !containsPrivilege(privileges[on], priv) {
+					privileges[on] = append(privileges[on], priv)
+				}
+			}
+		}
+	}
+	return privileges, objects, nil
+}
+
+// parseGrants returns the privileges of the SHOW GRANTS statements by the
+// object, and the objects with the GRANT OPTION. USAGE means no privilege.
+func parseGrants(grants []string) (map[string][]string, map[string]bool) {
+	privileges, grantOption := map[string][]string{}, map[string]bool{}
+	for _, grant := range grants {
+		match := grantRegexp.FindStringSubmatch(grant)
+		if match == nil {
+			continue
+		}
+		on := match[2]
+		if len(match[3]) != 0 {
+			grantOption[on] = true
+		}
+		for _, priv := range splitPrivileges(match[1]) {
+			// the proxy privilege is granted on a user, not an object.
+			if priv = strings.TrimSpace(priv); priv != "USAGE" && priv != "PROXY" {
+				privileges[on] = append(privileges[on], priv)
+			}
+		}
+	}
+	return privileges, grantOption
+}
+
+// splitPrivileges splits the privileges by the commas out of the column lists,
+// such as `SELECT (a, b), INSERT`.
+func splitPrivileges(str string) []string {
+	var privileges []string
+	depth, start := 0, 0
+	for i, c := range str {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				privileges = append(privileges, str[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(privileges, str[start:])
+}
+
+// normalizePrivilege returns the privilege of the spec in the form of SHOW GRANTS.
+func normalizePrivilege(priv string) string {
+	priv = strings.Join(strings.Fields(strings.ToUpper(priv)), " ")
+	if priv == "ALL" {
+		return allPrivileges
+	}
+	return priv
+}
+
+func containsPrivilege(privileges []string, priv string) bool {
+	for _, p := range privileges {
+		if p == priv {
+			return true
+		}
+	}
+	return false
+}
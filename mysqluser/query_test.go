@@ -0,0 +1,209 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mysqluser
+
+import (
+	"reflect"
+	"testing"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/internal"
+)
+
+// passHash is the mysql_native_password hash of "pass".
+const passHash = "*196BDEDE2AE4F84CA44C47D54D78478C7E2BD7B7"
+
+func TestBuildSyncQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     apiv1.MysqlUserSpec
+		account  *internal.UserAccount
+		want     string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "no permissions",
+			spec:     apiv1.MysqlUserSpec{User: "app"},
+			want:     "CREATE USER IF NOT EXISTS ?@? IDENTIFIED BY ?;\n",
+			wantArgs: []interface{}{"app", "%", "pass"},
+		},
+		{
+			name: "permissions and limits",
+			spec: apiv1.MysqlUserSpec{
+				User: "app",
+				Permissions: []apiv1.MysqlPermission{
+					{Database: "shop", Privileges: []string{"SELECT", "INSERT"}},
+					{Database: "log`s", Tables: []string{"a", "b"}, Privileges: []string{"ALL PRIVILEGES"}},
+				},
+				ResourceLimits: &apiv1.MysqlUserResourceLimits{MaxQueriesPerHour: 100, MaxUserConnections: 10},
+			},
+			want: "CREATE USER IF NOT EXISTS ?@? IDENTIFIED BY ? WITH MAX_QUERIES_PER_HOUR 100 MAX_UPDATES_PER_HOUR 0" +
+				" MAX_CONNECTIONS_PER_HOUR 0 MAX_USER_CONNECTIONS 10;\n" +
+				"GRANT SELECT, INSERT ON `shop`.* TO ?@?;\n" +
+				"GRANT ALL PRIVILEGES ON `log``s`.`a` TO ?@?;\n" +
+				"GRANT ALL PRIVILEGES ON `log``s`.`b` TO ?@?;\n",
+			wantArgs: []interface{}{"app", "%", "pass", "app", "%", "app", "%", "app", "%"},
+		},
+		{
+			name: "global privileges",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "*", Privileges: []string{"PROCESS"}}},
+			},
+			want: "CREATE USER IF NOT EXISTS ?@? IDENTIFIED BY ?;\n" +
+				"GRANT PROCESS ON *.* TO ?@?;\n",
+			wantArgs: []interface{}{"app", "%", "pass", "app", "%"},
+		},
+		{
+			name: "invalid privilege",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "shop", Privileges: []string{"SELECT; DROP USER root"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "up to date",
+			spec: apiv1.MysqlUserSpec{
+				User:           "app",
+				Permissions:    []apiv1.MysqlPermission{{Database: "shop", Privileges: []string{"select", "Insert"}}},
+				ResourceLimits: &apiv1.MysqlUserResourceLimits{MaxUserConnections: 10},
+			},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: passHash,
+				MaxUserConnections:   10,
+				Grants: []string{
+					"GRANT USAGE ON *.* TO 'app'@'%'",
+					"GRANT SELECT, INSERT ON `shop`.* TO 'app'@'%'",
+				},
+			},
+		},
+		{
+			name: "password changed",
+			spec: apiv1.MysqlUserSpec{User: "app"},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19",
+				MaxQuestions:         100,
+			},
+			want:     "ALTER USER ?@? IDENTIFIED BY ?;\n",
+			wantArgs: []interface{}{"app", "%", "pass"},
+		},
+		{
+			name: "salted password hash",
+			spec: apiv1.MysqlUserSpec{User: "app"},
+			account: &internal.UserAccount{
+				Plugin:               "caching_sha2_password",
+				AuthenticationString: passHash,
+			},
+			want:     "ALTER USER ?@? IDENTIFIED BY ?;\n",
+			wantArgs: []interface{}{"app", "%", "pass"},
+		},
+		{
+			name: "limits changed",
+			spec: apiv1.MysqlUserSpec{
+				User:           "app",
+				ResourceLimits: &apiv1.MysqlUserResourceLimits{MaxUpdatesPerHour: 5},
+			},
+			account: &internal.UserAccount{Plugin: "mysql_native_password", AuthenticationString: passHash},
+			want: "ALTER USER ?@? WITH MAX_QUERIES_PER_HOUR 0 MAX_UPDATES_PER_HOUR 5" +
+				" MAX_CONNECTIONS_PER_HOUR 0 MAX_USER_CONNECTIONS 0;\n",
+			wantArgs: []interface{}{"app", "%"},
+		},
+		{
+			name: "privileges changed",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "shop", Privileges: []string{"SELECT", "DELETE"}}},
+			},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: passHash,
+				Grants: []string{
+					"GRANT USAGE ON *.* TO 'app'@'%'",
+					"GRANT SELECT, INSERT, UPDATE ON `shop`.* TO 'app'@'%' WITH GRANT OPTION",
+					"GRANT SELECT ON `old`.* TO 'app'@'%'",
+				},
+			},
+			want: "REVOKE SELECT ON `old`.* FROM ?@?;\n" +
+				"REVOKE INSERT, UPDATE, GRANT OPTION ON `shop`.* FROM ?@?;\n" +
+				"GRANT DELETE ON `shop`.* TO ?@?;\n",
+			wantArgs: []interface{}{"app", "%", "app", "%", "app", "%"},
+		},
+		{
+			name: "all privileges narrowed",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "shop", Privileges: []string{"SELECT"}}},
+			},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: passHash,
+				Grants:               []string{"GRANT ALL PRIVILEGES ON `shop`.* TO 'app'@'%'"},
+			},
+			want: "REVOKE ALL PRIVILEGES ON `shop`.* FROM ?@?;\n" +
+				"GRANT SELECT ON `shop`.* TO ?@?;\n",
+			wantArgs: []interface{}{"app", "%", "app", "%"},
+		},
+		{
+			name: "all privileges widened",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "shop", Privileges: []string{"ALL"}}},
+			},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: passHash,
+				Grants:               []string{"GRANT SELECT ON `shop`.* TO 'app'@'%'"},
+			},
+			want:     "GRANT ALL PRIVILEGES ON `shop`.* TO ?@?;\n",
+			wantArgs: []interface{}{"app", "%"},
+		},
+		{
+			name: "column privileges",
+			spec: apiv1.MysqlUserSpec{
+				User:        "app",
+				Permissions: []apiv1.MysqlPermission{{Database: "shop", Tables: []string{"t"}, Privileges: []string{"INSERT"}}},
+			},
+			account: &internal.UserAccount{
+				Plugin:               "mysql_native_password",
+				AuthenticationString: passHash,
+				Grants:               []string{"GRANT SELECT (`a`, `b`), INSERT ON `shop`.`t` TO 'app'@'%'"},
+			},
+			want:     "REVOKE SELECT (`a`, `b`) ON `shop`.`t` FROM ?@?;\n",
+			wantArgs: []interface{}{"app", "%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := New(&apiv1.MysqlUser{Spec: tt.spec})
+			got, args, err := BuildSyncQuery(u, "%", "pass", tt.account)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildSyncQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BuildSyncQuery() query = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) && !tt.wantErr {
+				t.Errorf("BuildSyncQuery() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// archiveInterval is the interval between two rounds of archiving.
+	archiveInterval = time.Minute
+	// binlogTimeLayout is the layout of the time the binlog was closed, which
+	// prefixes the name of the archived binlog.
+	binlogTimeLayout = "20060102150405"
+)
+
+// archiver ships the closed binlogs of the leader to the s3 storage.
+type archiver struct {
+	cfg *ArchiveConfig
+	db  *sql.DB
+	s3  *minio.Client
+
+	// the keys of the binlogs known to be archived.
+	archived map[string]bool
+}
+
+func NewArchiveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "archive",
+		Short: "archive the closed binlogs of the leader to the s3 storage.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runArchiveCommand(signals.SetupSignalHandler(), NewArchiveConfig()); err != nil {
+				log.Error(err, "archive command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func runArchiveCommand(ctx context.Context, cfg *ArchiveConfig) error {
+	client, err := newS3Client(cfg.S3Endpoint, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client: %s", err)
+	}
+
+	dsn := fmt.Sprintf("root:%s@tcp(127.0.0.1:%d)/?tls=preferred", cfg.RootPassword, utils.MysqlPort)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer db.Close()
+
+	a := &archiver{
+		cfg:      cfg,
+		db:       db,
+		s3:       client,
+		archived: make(map[string]bool),
+	}
+
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		if err := a.archive(ctx); err != nil {
+			log.Error(err, "failed to archive binlogs")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// archive uploads the closed binlogs which have not been archived yet.
+func (a *archiver) archive(ctx context.Context) error {
+	var readOnly bool
+	if err := a.db.QueryRowContext(ctx, "SELECT @@global.read_only").Scan(&readOnly); err != nil {
+		return err
+	}
+	// only the leader archives its binlogs.
+	if readOnly {
+		return nil
+	}
+
+	files, err := a.getClosedBinlogs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		local := path.Join(getBinlogPath(), name)
+		info, err := os.Stat(local)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// purged in the meantime.
+				continue
+			}
+			return err
+		}
+
+		key := path.Join(a.cfg.Prefix, fmt.Sprintf("%s-%s-%s",
+			info.ModTime().UTC().Format(binlogTimeLayout), a.cfg.HostName, name))
+		if a.archived[key] {
+			continue
+		}
+
+		if _, err = a.s3.StatObject(ctx, a.cfg.S3Bucket, key, minio.StatObjectOptions{}); err == nil {
+			a.archived[key] = true
+			continue
+		} else if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+			return fmt.Errorf("failed to check %s: %s", key, err)
+		}
+
+		if _, err = a.s3.FPutObject(ctx, a.cfg.S3Bucket, key, local, minio.PutObjectOptions{
+			ContentType: "application/octet-stream",
+		}); err != nil {
+			return fmt.Errorf("failed to upload %s: %s", name, err)
+		}
+		a.archived[key] = true
+		log.Info("binlog archived", "file", name, "key", key)
+	}
+
+	return nil
+}
+
+// getClosedBinlogs returns the binlogs except the one being written.
+func (a *archiver) getClosedBinlogs(ctx context.Context) ([]string, error) {
+	rows, err := a.db.QueryContext(ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// the number of columns differs between the mysql versions.
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	values := make([]sql.RawBytes, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		files = append(files, string(values[0]))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+	return files[:len(files)-1], nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// terminationLogPath is the file the backup result will be written to.
+var terminationLogPath = "/dev/termination-log"
+
+func NewBackupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "take a backup from the given host and upload it to the s3 storage.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runBackupCommand(NewBackupConfig()); err != nil {
+				log.Error(err, "backup command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func runBackupCommand(cfg *BackupConfig) error {
+	url := fmt.Sprintf("http://%s:%d%s", cfg.Host, utils.SidecarHTTPPort, utils.XBackupPath)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %s", err)
+	}
+	req.SetBasicAuth(cfg.User, cfg.Password)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request the backup: %s", err)
+	}
+	defer func() {
+		if err1 := resp.Body.Close(); err1 != nil {
+			log.Error(err1, "failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to request the backup: %s", resp.Status)
+	}
+
+	body := &countingReader{r: resp.Body}
+	// nolint: gosec
+	cmd := exec.Command("xbcloud", cfg.XbcloudArgs("put")...)
+	cmd.Env = cfg.XbcloudEnv()
+	cmd.Stdin = body
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Run(); err != nil {
+		return cleanFailedBackup(cfg, fmt.Errorf("failed to upload the backup: %s", err))
+	}
+
+	// the trailers are available only after the body was fully read.
+	if _, err = io.Copy(ioutil.Discard, body); err != nil {
+		return cleanFailedBackup(cfg, fmt.Errorf("failed to read the backup: %s", err))
+	}
+
+	if status := resp.Trailer.Get(backupStatusTrailer); status != backupSuccessful {
+		return cleanFailedBackup(cfg, fmt.Errorf("backup on %s failed, status: %q", cfg.Host, status))
+	}
+
+	result, err := json.Marshal(utils.BackupResult{
+		Size:    body.n,
+		GtidSet: resp.Trailer.Get(backupGtidTrailer),
+	})
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(terminationLogPath, result, 0644); err != nil {
+		return fmt.Errorf("failed to write the backup result: %s", err)
+	}
+
+	log.Info("backup command success", "name", cfg.Name, "size", body.n)
+	return nil
+}
+
+// cleanFailedBackup deletes the partial backup uploaded by xbcloud, and
+// returns the error of the backup.
+func cleanFailedBackup(cfg *BackupConfig, err error) error {
+	if err1 := runPurgeCommand(cfg); err1 != nil {
+		log.Error(err1, "failed to delete the partial backup")
+	}
+	return err
+}
+
+func NewPurgeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "delete the backup from the s3 storage.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runPurgeCommand(NewBackupConfig()); err != nil {
+				log.Error(err, "purge command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func runPurgeCommand(cfg *BackupConfig) error {
+	// nolint: gosec
+	cmd := exec.Command("xbcloud", cfg.XbcloudArgs("delete")...)
+	cmd.Env = cfg.XbcloudEnv()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to delete the backup %s: %s", cfg.Name, err)
+	}
+
+	log.Info("purge command success", "name", cfg.Name)
+	return nil
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func NewCloneCommand(cfg *Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone",
+		Short: "clone the data from a peer if the data directory is empty.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runCloneCommand(cfg); err != nil {
+				log.Error(err, "clone command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+// runCloneCommand fills the empty data directory of a new member with a
+// backup streamed from a healthy follower, the leader or the first pod. The
+// new member joins the replication from the gtid set of the backup.
+func runCloneCommand(cfg *Config) error {
+	ordinal, err := getOrdinal(cfg.HostName)
+	if err != nil {
+		return err
+	}
+	// the first pod is initialized by init-mysql or restored from the backup.
+	if ordinal == 0 {
+		log.Info("the first pod, skip clone")
+		return nil
+	}
+
+	empty, err := isDataDirEmpty()
+	if err != nil {
+		return err
+	}
+	if !empty {
+		log.Info("data directory is not empty, skip clone")
+		return nil
+	}
+
+	sources := []string{
+		fmt.Sprintf("%s.%s", cfg.FollowerServiceName, cfg.NameSpace),
+		fmt.Sprintf("%s.%s", cfg.LeaderServiceName, cfg.NameSpace),
+		// the services have no endpoint before the first leader is elected.
+		fmt.Sprintf("%s-0.%s.%s", cfg.HostName[:strings.LastIndex(cfg.HostName, "-")], cfg.ServiceName, cfg.NameSpace),
+	}
+	for _, host := range sources {
+		if err = cloneFromPeer(cfg, host); err == nil {
+			return prepareDataDir(cfg)
+		}
+		log.Error(err, "failed to clone the data", "host", host)
+
+		if err = cleanDataDir(); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to clone the data from any peer")
+}
+
+// cloneFromPeer streams a backup from the given host and extracts it to the data directory.
+func cloneFromPeer(cfg *Config, host string) error {
+	url := fmt.Sprintf("http://%s:%d%s", host, utils.SidecarHTTPPort, utils.XBackupPath)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %s", err)
+	}
+	req.SetBasicAuth(cfg.BackupUser, cfg.BackupPassword)
+
+	log.Info("cloning the data", "host", host)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request the backup: %s", err)
+	}
+	defer func() {
+		if err1 := resp.Body.Close(); err1 != nil {
+			log.Error(err1, "failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to request the backup: %s", resp.Status)
+	}
+
+	if err = extractBackup(resp.Body); err != nil {
+		return err
+	}
+
+	// the trailers are available only after the body was fully read.
+	if _, err = io.Copy(ioutil.Discard, resp.Body); err != nil {
+		return fmt.Errorf("failed to read the backup: %s", err)
+	}
+	if status := resp.Trailer.Get(backupStatusTrailer); status != backupSuccessful {
+		return fmt.Errorf("backup on %s failed, status: %q", host, status)
+	}
+	return nil
+}
+
+// cleanDataDir removes the partially cloned data.
+func cleanDataDir() error {
+	files, err := ioutil.ReadDir(dataPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %s", dataPath, err)
+	}
+	for _, file := range files {
+		if file.Name() == "lost+found" {
+			continue
+		}
+		if err = os.RemoveAll(path.Join(dataPath, file.Name())); err != nil {
+			return fmt.Errorf("failed to clean %s: %s", dataPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestCleanDataDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		wantEmpty bool
+	}{
+		{
+			name:      "empty",
+			wantEmpty: true,
+		},
+		{
+			name:      "only lost+found",
+			files:     []string{"lost+found/"},
+			wantEmpty: true,
+		},
+		{
+			name:  "partially cloned",
+			files: []string{"lost+found/", "ibdata1", "mysql/", "mysql/user.ibd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := dataPath
+			dataPath = t.TempDir()
+			defer func() { dataPath = old }()
+
+			for _, file := range tt.files {
+				var err error
+				if name := path.Join(dataPath, file); file[len(file)-1] == '/' {
+					err = os.Mkdir(name, 0755)
+				} else {
+					err = ioutil.WriteFile(name, []byte("data"), 0644)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			empty, err := isDataDirEmpty()
+			if err != nil {
+				t.Fatalf("isDataDirEmpty() error = %v", err)
+			}
+			if empty != tt.wantEmpty {
+				t.Errorf("isDataDirEmpty() = %v, want %v", empty, tt.wantEmpty)
+			}
+
+			if err = cleanDataDir(); err != nil {
+				t.Fatalf("cleanDataDir() error = %v", err)
+			}
+			if empty, _ = isDataDirEmpty(); !empty {
+				t.Errorf("the data directory is not empty after cleanDataDir()")
+			}
+			// lost+found is the mount point of the volume.
+			if len(tt.files) > 0 {
+				if _, err = os.Stat(path.Join(dataPath, "lost+found")); err != nil {
+					t.Errorf("lost+found is removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOrdinal(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    int
+		wantErr bool
+	}{
+		{name: "sample-mysql-0", want: 0},
+		{name: "sample-mysql-12", want: 12},
+		{name: "sample", want: -1, wantErr: true},
+		{name: "sample-mysql", want: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getOrdinal(tt.name)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("getOrdinal() = %d, %v, want %d, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
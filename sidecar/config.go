@@ -17,7 +17,12 @@ limitations under the License.
 package sidecar
 
 import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/blang/semver"
 
@@ -29,6 +34,10 @@ type Config struct {
 	NameSpace   string
 	ServiceName string
 
+	// the services of the leader and the healthy followers.
+	LeaderServiceName   string
+	FollowerServiceName string
+
 	// root password
 	RootPassword string
 
@@ -39,6 +48,26 @@ type Config struct {
 	MetricsUser     string
 	MetricsPassword string
 
+	// the user and password used by the operator to manage the users.
+	OperatorUser     string
+	OperatorPassword string
+
+	// the user and password used to authenticate the backup requests.
+	BackupUser     string
+	BackupPassword string
+
+	// the location of the backup to restore from, eg: s3://bucket/cluster/backup.
+	RestoreFrom string
+	// the location of the archived binlogs and the point to recover to.
+	BinlogURL   string
+	RestoreTime string
+	RestoreGtid string
+	// the options used to access the backup storage.
+	S3Endpoint  string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+
 	InitTokuDB bool
 
 	MySQLVersion semver.Version
@@ -73,6 +102,9 @@ func NewConfig() *Config {
 		NameSpace:   getEnvValue("NAMESPACE"),
 		ServiceName: getEnvValue("SERVICE_NAME"),
 
+		LeaderServiceName:   getEnvValue("LEADER_SERVICE_NAME"),
+		FollowerServiceName: getEnvValue("FOLLOWER_SERVICE_NAME"),
+
 		RootPassword: getEnvValue("MYSQL_ROOT_PASSWORD"),
 
 		ReplicationUser:     getEnvValue("MYSQL_REPL_USER"),
@@ -81,6 +113,21 @@ func NewConfig() *Config {
 		MetricsUser:     getEnvValue("METRICS_USER"),
 		MetricsPassword: getEnvValue("METRICS_PASSWORD"),
 
+		OperatorUser:     getEnvValue("OPERATOR_USER"),
+		OperatorPassword: getEnvValue("OPERATOR_PASSWORD"),
+
+		BackupUser:     getEnvValue("BACKUP_USER"),
+		BackupPassword: getEnvValue("BACKUP_PASSWORD"),
+
+		RestoreFrom: getEnvValue("RESTORE_FROM"),
+		BinlogURL:   getEnvValue("RESTORE_BINLOG_URL"),
+		RestoreTime: getEnvValue("RESTORE_TIME"),
+		RestoreGtid: getEnvValue("RESTORE_GTID"),
+		S3Endpoint:  getEnvValue("S3_ENDPOINT"),
+		S3Region:    getEnvValue("S3_REGION"),
+		S3AccessKey: getEnvValue("S3_ACCESS_KEY"),
+		S3SecretKey: getEnvValue("S3_SECRET_KEY"),
+
 		InitTokuDB: initTokuDB,
 
 		MySQLVersion: mysqlVersion,
@@ -89,3 +136,145 @@ func NewConfig() *Config {
 		ElectionTimeout:          int32(electionTimeout),
 	}
 }
+
+// BackupConfig is the configuration of the backup job.
+type BackupConfig struct {
+	// the host from which to take the backup.
+	Host string
+
+	// the user and password used to authenticate the backup requests.
+	User     string
+	Password string
+
+	// the name of the backup in the bucket.
+	Name string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+func NewBackupConfig() *BackupConfig {
+	return &BackupConfig{
+		Host: getEnvValue("BACKUP_HOST"),
+
+		User:     getEnvValue("BACKUP_USER"),
+		Password: getEnvValue("BACKUP_PASSWORD"),
+
+		Name: getEnvValue("BACKUP_NAME"),
+
+		S3Endpoint:  getEnvValue("S3_ENDPOINT"),
+		S3Region:    getEnvValue("S3_REGION"),
+		S3Bucket:    getEnvValue("S3_BUCKET"),
+		S3AccessKey: getEnvValue("S3_ACCESS_KEY"),
+		S3SecretKey: getEnvValue("S3_SECRET_KEY"),
+	}
+}
+
+// ArchiveConfig is the configuration of the binlog archiver.
+type ArchiveConfig struct {
+	HostName string
+
+	// root password
+	RootPassword string
+
+	// the directory of the archived binlogs in the bucket.
+	Prefix string
+
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+func NewArchiveConfig() *ArchiveConfig {
+	return &ArchiveConfig{
+		HostName: getEnvValue("POD_HOSTNAME"),
+
+		RootPassword: getEnvValue("MYSQL_ROOT_PASSWORD"),
+
+		Prefix: getEnvValue("ARCHIVE_PREFIX"),
+
+		S3Endpoint:  getEnvValue("S3_ENDPOINT"),
+		S3Region:    getEnvValue("S3_REGION"),
+		S3Bucket:    getEnvValue("S3_BUCKET"),
+		S3AccessKey: getEnvValue("S3_ACCESS_KEY"),
+		S3SecretKey: getEnvValue("S3_SECRET_KEY"),
+	}
+}
+
+// XtrabackupArgs returns the arguments of xtrabackup to stream a backup.
+func (cfg *Config) XtrabackupArgs() []string {
+	return []string{
+		fmt.Sprintf("--defaults-file=%s", path.Join(configPath, "my.cnf")),
+		"--backup",
+		"--stream=xbstream",
+		"--host=127.0.0.1",
+		"--user=root",
+		"--target-dir=/tmp",
+	}
+}
+
+// XtrabackupEnv returns the env of xtrabackup, the password is not passed in
+// the arguments, which are visible to the other processes.
+func (cfg *Config) XtrabackupEnv() []string {
+	return append(os.Environ(), fmt.Sprintf("MYSQL_PWD=%s", cfg.RootPassword))
+}
+
+// RestoreConfig returns the configuration used by xbcloud to download the
+// backup to restore from.
+func (cfg *Config) RestoreConfig() (*BackupConfig, error) {
+	bucket, name, err := parseS3URL(cfg.RestoreFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackupConfig{
+		Name:        name,
+		S3Endpoint:  cfg.S3Endpoint,
+		S3Region:    cfg.S3Region,
+		S3Bucket:    bucket,
+		S3AccessKey: cfg.S3AccessKey,
+		S3SecretKey: cfg.S3SecretKey,
+	}, nil
+}
+
+// XbcloudArgs returns the arguments of xbcloud to run the action (put, get or
+// delete) on the backup.
+func (cfg *BackupConfig) XbcloudArgs(action string) []string {
+	return []string{
+		action,
+		"--storage=s3",
+		fmt.Sprintf("--s3-endpoint=%s", cfg.S3Endpoint),
+		fmt.Sprintf("--s3-region=%s", cfg.S3Region),
+		fmt.Sprintf("--s3-bucket=%s", cfg.S3Bucket),
+		"--parallel=10",
+		cfg.Name,
+	}
+}
+
+// XbcloudEnv returns the env of xbcloud, which reads the credentials from the
+// env instead of the arguments visible to the other processes.
+func (cfg *BackupConfig) XbcloudEnv() []string {
+	return append(os.Environ(),
+		fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", cfg.S3AccessKey),
+		fmt.Sprintf("AWS_SECRET_ACCESS_KEY=%s", cfg.S3SecretKey),
+	)
+}
+
+// parseS3URL returns the bucket and the object name of the s3 url, eg:
+// s3://bucket/name.
+func parseS3URL(s3URL string) (string, string, error) {
+	u, err := url.Parse(s3URL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse the url %s: %s", s3URL, err)
+	}
+	if u.Scheme != "s3" || len(u.Host) == 0 || len(u.Path) <= 1 {
+		return "", "", fmt.Errorf("unsupported url %s, expected s3://bucket/name", s3URL)
+	}
+
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
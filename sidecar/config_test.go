@@ -0,0 +1,177 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestCredentialsNotInArgs(t *testing.T) {
+	cfg := &Config{RootPassword: "rootSecret"}
+	backupCfg := &BackupConfig{
+		Name:        "backup",
+		S3Endpoint:  "http://minio:9000",
+		S3Bucket:    "bucket",
+		S3AccessKey: "accessSecret",
+		S3SecretKey: "secretSecret",
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     []string
+		secrets []string
+	}{
+		{
+			name:    "xtrabackup",
+			args:    cfg.XtrabackupArgs(),
+			env:     cfg.XtrabackupEnv(),
+			secrets: []string{"MYSQL_PWD=rootSecret"},
+		},
+		{
+			name:    "xbcloud",
+			args:    backupCfg.XbcloudArgs("put"),
+			env:     backupCfg.XbcloudEnv(),
+			secrets: []string{"AWS_ACCESS_KEY_ID=accessSecret", "AWS_SECRET_ACCESS_KEY=secretSecret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := strings.Join(tt.args, " ")
+			for _, secret := range []string{"rootSecret", "accessSecret", "secretSecret"} {
+				if strings.Contains(args, secret) {
+					t.Errorf("the arguments %q contain the credential %s", args, secret)
+				}
+			}
+			for _, secret := range tt.secrets {
+				if !utils.StringInSlice(secret, tt.env) {
+					t.Errorf("the env does not contain %s", secret)
+				}
+			}
+		})
+	}
+}
+
+func TestRestoreConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantBucket string
+		wantName   string
+		wantErr    bool
+	}{
+		{
+			name:       "backup",
+			url:        "s3://bucket/sample/backup",
+			wantBucket: "bucket",
+			wantName:   "sample/backup",
+		},
+		{
+			name:    "no name",
+			url:     "s3://bucket/",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			url:     "http://bucket/backup",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				RestoreFrom: tt.url,
+				S3Endpoint:  "http://minio:9000",
+				S3AccessKey: "accessKey",
+				S3SecretKey: "secretKey",
+			}
+			got, err := cfg.RestoreConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RestoreConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.S3Bucket != tt.wantBucket || got.Name != tt.wantName {
+				t.Errorf("RestoreConfig() = %s, %s, want %s, %s", got.S3Bucket, got.Name, tt.wantBucket, tt.wantName)
+			}
+			if got.S3Endpoint != cfg.S3Endpoint || got.S3AccessKey != cfg.S3AccessKey || got.S3SecretKey != cfg.S3SecretKey {
+				t.Errorf("RestoreConfig() does not keep the storage options: %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseS3URL(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantBucket string
+		wantName   string
+		wantErr    bool
+	}{
+		{
+			name:       "backup",
+			url:        "s3://bucket/backup-2021",
+			wantBucket: "bucket",
+			wantName:   "backup-2021",
+		},
+		{
+			name:       "nested name",
+			url:        "s3://bucket/cluster/backup/",
+			wantBucket: "bucket",
+			wantName:   "cluster/backup",
+		},
+		{
+			name:    "no name",
+			url:     "s3://bucket/",
+			wantErr: true,
+		},
+		{
+			name:    "no bucket",
+			url:     "s3:///backup",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			url:     "http://bucket/backup",
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			url:     "s3://bucket/%zz",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket, name, err := parseS3URL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseS3URL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != tt.wantBucket || name != tt.wantName {
+				t.Errorf("parseS3URL() = %q, %q, want %q, %q", bucket, name, tt.wantBucket, tt.wantName)
+			}
+		})
+	}
+}
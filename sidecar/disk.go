@@ -0,0 +1,116 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	apiv1 "github.com/zhyass/mysql-operator/api/v1"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// getDiskUsage returns the usage of the data directory and the separate
+// volumes, and the size of the binlogs and the relay logs.
+func getDiskUsage() (*apiv1.DiskUsage, error) {
+	usage := &apiv1.DiskUsage{}
+
+	volumes := []struct {
+		name string
+		dir  string
+	}{
+		{utils.DataVolumeName, dataPath},
+		{utils.BinlogVolumeName, binlogPath},
+		{utils.RedoLogVolumeName, redoLogPath},
+		{utils.TmpVolumeName, tmpPath},
+	}
+	for _, volume := range volumes {
+		if _, err := os.Stat(volume.dir); err != nil {
+			if os.IsNotExist(err) {
+				// the separate volume is not declared.
+				continue
+			}
+			return nil, err
+		}
+
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(volume.dir, &stat); err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %s", volume.dir, err)
+		}
+		bsize := uint64(stat.Bsize)
+		used := (stat.Blocks - stat.Bfree) * bsize
+		// the blocks reserved for root are not available to mysql, like df.
+		available := used + stat.Bavail*bsize
+		var percent int32
+		if available > 0 {
+			percent = int32((used*100 + available - 1) / available)
+		}
+		usage.Volumes = append(usage.Volumes, apiv1.VolumeUsage{
+			Name:        volume.name,
+			Used:        formatSize(int64(used)),
+			Capacity:    formatSize(int64(stat.Blocks * bsize)),
+			UsedPercent: percent,
+		})
+	}
+
+	binlogs, err := sumFileSizes(path.Join(getBinlogPath(), "mysql-bin.[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	usage.Binlogs = formatSize(binlogs)
+
+	relayLogs, err := sumFileSizes(path.Join(getBinlogPath(), "mysql-relay-bin.[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	usage.RelayLogs = formatSize(relayLogs)
+
+	return usage, nil
+}
+
+// sumFileSizes returns the total size of the files matching the pattern.
+func sumFileSizes(pattern string) (int64, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// purged in the meantime.
+				continue
+			}
+			return 0, err
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// formatSize rounds the bytes up to MiB to keep the size readable.
+func formatSize(bytes int64) string {
+	const mi = 1 << 20
+	return resource.NewQuantity((bytes+mi-1)/mi*mi, resource.BinarySI).String()
+}
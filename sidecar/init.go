@@ -17,6 +17,7 @@ limitations under the License.
 package sidecar
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -59,6 +60,20 @@ func runInitCommand(cfg *Config) error {
 		}
 	}
 
+	for _, dir := range []string{binlogPath, redoLogPath, tmpPath} {
+		if exists, _ := checkIfPathExists(dir); !exists {
+			continue
+		}
+		if err = os.Chown(dir, 1001, 1001); err != nil {
+			return fmt.Errorf("failed to chown %s: %s", dir, err)
+		}
+	}
+
+	// restore the data directory from the backup.
+	if err = restoreDataDir(cfg); err != nil {
+		return fmt.Errorf("failed to restore the data: %s", err)
+	}
+
 	// copy appropriate my.cnf from config-map to config mount.
 	if err = copyFile(path.Join(configMapPath, "my.cnf"), path.Join(configPath, "my.cnf")); err != nil {
 		return fmt.Errorf("failed to copy my.cnf: %s", err)
@@ -70,6 +85,11 @@ func runInitCommand(cfg *Config) error {
 		}
 	}
 
+	// reset the passwords of the local users, they may have been rotated.
+	if err = writeCredentialsSql(cfg); err != nil {
+		return err
+	}
+
 	// build init.sql.
 	initSqlPath := path.Join(initFilePath, "init.sql")
 	if err = ioutil.WriteFile(initSqlPath, buildInitSql(cfg), 0644); err != nil {
@@ -104,6 +124,12 @@ func runInitCommand(cfg *Config) error {
 		return fmt.Errorf("failed to chmod scripts: %s", err)
 	}
 
+	// copy the sidecar binary to run the api in the xenon image, it is
+	// statically linked.
+	if err = copySidecarBinary(); err != nil {
+		return err
+	}
+
 	// for install tokudb.
 	if cfg.InitTokuDB {
 		arg := fmt.Sprintf("echo never > %s/enabled", sysPath)
@@ -124,6 +150,22 @@ func runInitCommand(cfg *Config) error {
 	return nil
 }
 
+// copySidecarBinary copies the running binary to the shared volume.
+func copySidecarBinary() error {
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get the sidecar binary: %s", err)
+	}
+	dst := path.Join(sidecarBinPath, "sidecar")
+	if err = copyFile(binary, dst); err != nil {
+		return fmt.Errorf("failed to copy the sidecar binary: %s", err)
+	}
+	if err = os.Chmod(dst, os.FileMode(0755)); err != nil {
+		return fmt.Errorf("failed to chmod the sidecar binary: %s", err)
+	}
+	return nil
+}
+
 func checkIfPathExists(path string) (bool, error) {
 	_, err := os.Open(path)
 
@@ -149,6 +191,18 @@ func buildExtraConfig(cfg *Config) (*ini.File, error) {
 		return nil, err
 	}
 
+	// the restored data should be fixed up before serving, which resets the
+	// passwords too.
+	if info, err := os.Stat(restoreSqlPath); err == nil && info.Size() > 0 {
+		if _, err := sec.NewKey("init-file", restoreSqlPath); err != nil {
+			return nil, err
+		}
+	} else if info, err := os.Stat(credentialsSqlPath); err == nil && info.Size() > 0 {
+		if _, err := sec.NewKey("init-file", credentialsSqlPath); err != nil {
+			return nil, err
+		}
+	}
+
 	return conf, nil
 }
 
@@ -195,7 +249,7 @@ func buildXenonConf(cfg *Config) []byte {
         "admit-defeat-ping-count": 3,
         "admin": "root",
         "ping-timeout": %d,
-        "passwd": "%s",
+        "passwd": %s,
         "host": "localhost",
         "version": "%s",
         "master-sysvars": "%s",
@@ -216,21 +270,80 @@ func buildXenonConf(cfg *Config) []byte {
     }
 }
 `, hostName, utils.XenonPort, cfg.ReplicationPassword, cfg.ReplicationUser, requestTimeout,
-		pingTimeout, cfg.RootPassword, version, masterSysVars, slaveSysVars, cfg.ElectionTimeout,
+		pingTimeout, quoteJSONString(cfg.RootPassword), version, masterSysVars, slaveSysVars, cfg.ElectionTimeout,
 		cfg.AdmitDefeatHearbeatCount, heartbeatTimeout)
 	return utils.StringToBytes(str)
 }
 
+// quoteJSONString quotes the string for the json configs, eg: the password of
+// root, which is chosen by the user.
+func quoteJSONString(str string) string {
+	data, _ := json.Marshal(str)
+	return utils.BytesToString(data)
+}
+
+// writeCredentialsSql writes the init-file that sets the password of the root
+// user to the one of the secret. The root user is never altered through the
+// binlog, each pod takes the rotated password when it is restarted, so mysql
+// always matches the password used by xenon and the probes. It is only needed
+// if the data directory has been initialized.
+func writeCredentialsSql(cfg *Config) error {
+	if exists, _ := checkIfPathExists(path.Join(dataPath, "mysql")); !exists {
+		return os.RemoveAll(credentialsSqlPath)
+	}
+
+	if err := ioutil.WriteFile(credentialsSqlPath, buildCredentialsSql(cfg), 0644); err != nil {
+		return fmt.Errorf("failed to write credentials.sql: %s", err)
+	}
+	return nil
+}
+
+// buildCredentialsSql returns the statements of the init-file, which requires
+// one statement per line.
+func buildCredentialsSql(cfg *Config) []byte {
+	password := utils.EscapeSQLString(cfg.RootPassword)
+	sql := fmt.Sprintf(`SET @@SESSION.SQL_LOG_BIN=0;
+ALTER USER IF EXISTS 'root'@'localhost' IDENTIFIED BY '%s';
+ALTER USER IF EXISTS 'root'@'127.0.0.1' IDENTIFIED BY '%s';
+`, password, password)
+
+	return utils.StringToBytes(sql)
+}
+
 func buildInitSql(cfg *Config) []byte {
+	// mysql 8.0 no longer supports creating users by GRANT ... IDENTIFIED BY.
+	if cfg.MySQLVersion.Major == 8 {
+		sql := fmt.Sprintf(`RESET MASTER;
+SET @@SESSION.SQL_LOG_BIN=0;
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED WITH mysql_native_password BY '%s';
+GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* to '%s'@'%%';
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED WITH mysql_native_password BY '%s';
+GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* to '%s'@'%%';
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED WITH mysql_native_password BY '%s';
+GRANT ALL PRIVILEGES ON *.* to '%s'@'%%' WITH GRANT OPTION;
+FLUSH PRIVILEGES;
+`, cfg.ReplicationUser, cfg.ReplicationUser, cfg.ReplicationPassword, cfg.ReplicationUser,
+			cfg.MetricsUser, cfg.MetricsUser, cfg.MetricsPassword, cfg.MetricsUser,
+			cfg.OperatorUser, cfg.OperatorUser, cfg.OperatorPassword, cfg.OperatorUser)
+
+		return utils.StringToBytes(sql)
+	}
+
 	sql := fmt.Sprintf(`RESET MASTER;
 SET @@SESSION.SQL_LOG_BIN=0;
 DELETE FROM mysql.user WHERE user='%s';
 GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* to '%s'@'%%' IDENTIFIED BY '%s';
 DELETE FROM mysql.user WHERE user='%s';
 GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* to '%s'@'%%' IDENTIFIED BY '%s';
+DELETE FROM mysql.user WHERE user='%s';
+GRANT ALL PRIVILEGES ON *.* to '%s'@'%%' IDENTIFIED BY '%s' WITH GRANT OPTION;
 FLUSH PRIVILEGES;
 `, cfg.ReplicationUser, cfg.ReplicationUser, cfg.ReplicationPassword,
-		cfg.MetricsUser, cfg.MetricsUser, cfg.MetricsPassword)
+		cfg.MetricsUser, cfg.MetricsUser, cfg.MetricsPassword,
+		cfg.OperatorUser, cfg.OperatorUser, cfg.OperatorPassword)
 
 	return utils.StringToBytes(sql)
 }
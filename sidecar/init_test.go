@@ -0,0 +1,109 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func TestBuildInitSql(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+		absent  []string
+	}{
+		{
+			version: "5.7.33",
+			want: []string{
+				"GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* to 'replUser'@'%' IDENTIFIED BY 'replPassword';",
+				"GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* to 'metricsUser'@'%' IDENTIFIED BY 'metricsPassword';",
+			},
+			absent: []string{"CREATE USER"},
+		},
+		{
+			version: "8.0.25",
+			want: []string{
+				"CREATE USER 'replUser'@'%' IDENTIFIED WITH mysql_native_password BY 'replPassword';",
+				"CREATE USER 'metricsUser'@'%' IDENTIFIED WITH mysql_native_password BY 'metricsPassword';",
+			},
+			absent: []string{"IDENTIFIED BY 'replPassword'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			sql := string(buildInitSql(&Config{
+				ReplicationUser:     "replUser",
+				ReplicationPassword: "replPassword",
+				MetricsUser:         "metricsUser",
+				MetricsPassword:     "metricsPassword",
+				MySQLVersion:        semver.MustParse(tt.version),
+			}))
+
+			for _, want := range tt.want {
+				if !strings.Contains(sql, want) {
+					t.Errorf("init sql does not contain %q", want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(sql, absent) {
+					t.Errorf("init sql contains %q", absent)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCredentialsSql(t *testing.T) {
+	sql := string(buildCredentialsSql(&Config{RootPassword: "it's\na\\pass"}))
+
+	want := `ALTER USER IF EXISTS 'root'@'localhost' IDENTIFIED BY 'it\'s\na\\pass';`
+	if !strings.Contains(sql, want) {
+		t.Errorf("credentials sql does not contain %q:\n%s", want, sql)
+	}
+	// the init-file requires one statement per line.
+	if lines := strings.Count(sql, "\n"); lines != 3 {
+		t.Errorf("credentials sql has %d lines, want 3", lines)
+	}
+}
+
+func TestBuildXenonConf(t *testing.T) {
+	cfg := &Config{
+		RootPassword:             `Qing@"123\`,
+		ReplicationUser:          "replUser",
+		ReplicationPassword:      "replPassword",
+		MySQLVersion:             semver.MustParse("5.7.33"),
+		ElectionTimeout:          10000,
+		AdmitDefeatHearbeatCount: 5,
+	}
+
+	conf := struct {
+		Mysql struct {
+			Passwd string `json:"passwd"`
+		} `json:"mysql"`
+	}{}
+	if err := json.Unmarshal(buildXenonConf(cfg), &conf); err != nil {
+		t.Fatalf("the xenon config is invalid: %v", err)
+	}
+	if conf.Mysql.Passwd != cfg.RootPassword {
+		t.Errorf("passwd = %q, want %q", conf.Mysql.Passwd, cfg.RootPassword)
+	}
+}
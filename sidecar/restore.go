@@ -0,0 +1,438 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// mysql driver used to check whether mysql has started.
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// restoreCheckInterval is the interval to check whether the restored mysql has started.
+const restoreCheckInterval = 5 * time.Second
+
+// restoreDataDir fills the empty data directory of the first pod of a cluster
+// restored from a backup with the backup and the archived binlogs. The other
+// pods clone the data from their peers.
+func restoreDataDir(cfg *Config) error {
+	if len(cfg.RestoreFrom) == 0 {
+		return nil
+	}
+
+	ordinal, err := getOrdinal(cfg.HostName)
+	if err != nil {
+		return err
+	}
+	if ordinal > 0 {
+		return nil
+	}
+
+	empty, err := isDataDirEmpty()
+	if err != nil || !empty {
+		return err
+	}
+
+	if err = downloadBackup(cfg); err != nil {
+		return err
+	}
+	if err = prepareDataDir(cfg); err != nil {
+		return err
+	}
+
+	if len(cfg.BinlogURL) > 0 {
+		return downloadBinlogs(cfg)
+	}
+	return nil
+}
+
+// isDataDirEmpty returns true if there is no data in the data directory.
+func isDataDirEmpty() (bool, error) {
+	files, err := ioutil.ReadDir(dataPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %s", dataPath, err)
+	}
+	for _, file := range files {
+		if file.Name() != "lost+found" {
+			log.Info("data directory is not empty")
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// downloadBackup downloads the backup from the storage and extracts it to the data directory.
+func downloadBackup(cfg *Config) error {
+	restoreCfg, err := cfg.RestoreConfig()
+	if err != nil {
+		return err
+	}
+
+	log.Info("downloading the backup", "url", cfg.RestoreFrom)
+	// nolint: gosec
+	download := exec.Command("xbcloud", restoreCfg.XbcloudArgs("get")...)
+	download.Env = restoreCfg.XbcloudEnv()
+	download.Stderr = os.Stderr
+	stream, err := download.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = download.Start(); err != nil {
+		return fmt.Errorf("failed to start xbcloud: %s", err)
+	}
+
+	if err = extractBackup(stream); err != nil {
+		return err
+	}
+
+	if err = download.Wait(); err != nil {
+		return fmt.Errorf("failed to download the backup: %s", err)
+	}
+	return nil
+}
+
+// extractBackup extracts the xbstream to the data directory.
+func extractBackup(stream io.Reader) error {
+	cmd := exec.Command("xbstream", "-x", "-C", dataPath)
+	cmd.Stdin = stream
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to extract the backup: %s", err)
+	}
+	return nil
+}
+
+// prepareDataDir prepares the extracted backup so that mysql can start from it,
+// and writes the restore.sql executed by mysql on the first start.
+func prepareDataDir(cfg *Config) error {
+	// nolint: gosec
+	cmd := exec.Command("xtrabackup", "--prepare", fmt.Sprintf("--target-dir=%s", dataPath))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to prepare the backup: %s", err)
+	}
+
+	if err := moveRedoLogs(); err != nil {
+		return err
+	}
+
+	// the server uuid must be regenerated, otherwise it conflicts with the source.
+	if err := os.RemoveAll(path.Join(dataPath, "auto.cnf")); err != nil {
+		return fmt.Errorf("failed to remove auto.cnf: %s", err)
+	}
+
+	gtid, err := readBackupGtid()
+	if err != nil {
+		return err
+	}
+
+	// chown -R mysql:mysql /var/lib/mysql.
+	if err = filepath.Walk(dataPath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chown(name, 1001, 1001)
+	}); err != nil {
+		return fmt.Errorf("failed to chown %s: %s", dataPath, err)
+	}
+
+	if err = ioutil.WriteFile(restoreSqlPath, buildRestoreSql(cfg, gtid), 0644); err != nil {
+		return fmt.Errorf("failed to write restore.sql: %s", err)
+	}
+
+	log.Info("data directory restored", "gtid", gtid)
+	return nil
+}
+
+// readBackupGtid returns the executed gtid set recorded by xtrabackup.
+func readBackupGtid() (string, error) {
+	data, err := ioutil.ReadFile(path.Join(dataPath, "xtrabackup_binlog_info"))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read xtrabackup_binlog_info: %s", err)
+	}
+
+	// format: <binlog file>\t<position>\t<gtid set>, the gtid set may span lines.
+	fields := strings.SplitN(string(data), "\t", 3)
+	if len(fields) < 3 {
+		return "", nil
+	}
+	return strings.Join(strings.Fields(fields[2]), ""), nil
+}
+
+// buildRestoreSql returns the statements that reset the replication state to
+// the backup and the internal users to the ones of this cluster. It is used as
+// the init-file, which requires one statement per line.
+func buildRestoreSql(cfg *Config, gtid string) []byte {
+	sql := fmt.Sprintf(`SET @@SESSION.SQL_LOG_BIN=0;
+RESET SLAVE ALL;
+RESET MASTER;
+SET GLOBAL gtid_purged='%s';
+ALTER USER IF EXISTS 'root'@'localhost' IDENTIFIED BY '%s';
+ALTER USER IF EXISTS 'root'@'127.0.0.1' IDENTIFIED BY '%s';
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED BY '%s';
+GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* to '%s'@'%%';
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED BY '%s';
+GRANT SELECT, PROCESS, REPLICATION CLIENT ON *.* to '%s'@'%%';
+DROP USER IF EXISTS '%s'@'%%';
+CREATE USER '%s'@'%%' IDENTIFIED BY '%s';
+GRANT ALL PRIVILEGES ON *.* to '%s'@'%%' WITH GRANT OPTION;
+FLUSH PRIVILEGES;
+`, gtid, utils.EscapeSQLString(cfg.RootPassword), utils.EscapeSQLString(cfg.RootPassword),
+		cfg.ReplicationUser, cfg.ReplicationUser, cfg.ReplicationPassword, cfg.ReplicationUser,
+		cfg.MetricsUser, cfg.MetricsUser, cfg.MetricsPassword, cfg.MetricsUser,
+		cfg.OperatorUser, cfg.OperatorUser, cfg.OperatorPassword, cfg.OperatorUser)
+
+	return utils.StringToBytes(sql)
+}
+
+// binlogReplay is the plan to apply the archived binlogs, it is written by the
+// init command and executed by the http server once mysql has started.
+type binlogReplay struct {
+	Files        []string `json:"files"`
+	StopDatetime string   `json:"stopDatetime,omitempty"`
+	IncludeGtids string   `json:"includeGtids,omitempty"`
+}
+
+// downloadBinlogs downloads the archived binlogs needed to recover to the
+// restore time or gtid set, and writes the plan to apply them.
+func downloadBinlogs(cfg *Config) error {
+	bucket, prefix, err := parseS3URL(cfg.BinlogURL)
+	if err != nil {
+		return err
+	}
+
+	client, err := newS3Client(cfg.S3Endpoint, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client: %s", err)
+	}
+
+	plan := binlogReplay{IncludeGtids: cfg.RestoreGtid}
+	var stopTime time.Time
+	if len(cfg.RestoreTime) > 0 {
+		if stopTime, err = time.Parse(time.RFC3339, cfg.RestoreTime); err != nil {
+			return fmt.Errorf("failed to parse the restore time %s: %s", cfg.RestoreTime, err)
+		}
+		plan.StopDatetime = stopTime.UTC().Format("2006-01-02 15:04:05")
+	}
+
+	ctx := context.TODO()
+	var keys []string
+	for obj := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
+		Prefix:    prefix + "/",
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list the binlogs: %s", obj.Err)
+		}
+		keys = append(keys, obj.Key)
+	}
+	// the names of the archived binlogs start with the time they were closed.
+	sort.Strings(keys)
+
+	if err = os.MkdirAll(binlogReplayPath, os.FileMode(0755)); err != nil {
+		return fmt.Errorf("error mkdir %s: %s", binlogReplayPath, err)
+	}
+
+	var lastClosed time.Time
+	for _, key := range keys {
+		name := path.Base(key)
+		local := path.Join(binlogReplayPath, name)
+		if err = client.FGetObject(ctx, bucket, key, local, minio.GetObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to download %s: %s", key, err)
+		}
+		plan.Files = append(plan.Files, local)
+
+		if len(name) < len(binlogTimeLayout) {
+			continue
+		}
+		if lastClosed, err = time.Parse(binlogTimeLayout, name[:len(binlogTimeLayout)]); err != nil {
+			continue
+		}
+		// the binlogs closed later only contain the changes after the restore time.
+		if !stopTime.IsZero() && lastClosed.After(stopTime) {
+			break
+		}
+	}
+
+	if !stopTime.IsZero() && lastClosed.Before(stopTime) {
+		log.Info("the binlogs have not been archived up to the restore time", "lastClosed", lastClosed)
+	}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(binlogReplayPlanPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write the binlog replay plan: %s", err)
+	}
+
+	log.Info("binlogs downloaded", "count", len(plan.Files))
+	return nil
+}
+
+// needFinishRestore returns true if the restored mysql has to be fixed up
+// after it started.
+func needFinishRestore() bool {
+	if info, err := os.Stat(restoreSqlPath); err == nil && info.Size() > 0 {
+		return true
+	}
+	_, err := os.Stat(binlogReplayPlanPath)
+	return err == nil
+}
+
+// finishRestore runs once the restored mysql has started. It empties the
+// restore.sql so that it will not be executed again when the mysql container
+// restarts, then applies the downloaded binlogs if any.
+func finishRestore(cfg *Config, stop <-chan struct{}) {
+	dsn := fmt.Sprintf("root:%s@tcp(127.0.0.1:%d)/?tls=preferred", cfg.RootPassword, utils.MysqlPort)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Error(err, "failed to open mysql connection")
+		return
+	}
+	defer db.Close()
+
+	ticker := time.NewTicker(restoreCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// mysql accepts connections only after the init-file was executed.
+		if err = db.Ping(); err != nil {
+			continue
+		}
+		if err = os.Truncate(restoreSqlPath, 0); err != nil && !os.IsNotExist(err) {
+			log.Error(err, "failed to clear restore.sql")
+			continue
+		}
+		if err = replayBinlogs(cfg); err != nil {
+			log.Error(err, "failed to apply the binlogs")
+			continue
+		}
+		log.Info("restore finished")
+		return
+	}
+}
+
+// replayBinlogs applies the downloaded binlogs with mysqlbinlog, the
+// transactions that are already in the backup are skipped by gtid.
+func replayBinlogs(cfg *Config) error {
+	data, err := ioutil.ReadFile(binlogReplayPlanPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	plan := binlogReplay{}
+	if err = json.Unmarshal(data, &plan); err != nil {
+		return fmt.Errorf("failed to parse the binlog replay plan: %s", err)
+	}
+
+	var args []string
+	if len(plan.StopDatetime) > 0 {
+		args = append(args, fmt.Sprintf("--stop-datetime=%s", plan.StopDatetime))
+	}
+	if len(plan.IncludeGtids) > 0 {
+		args = append(args, fmt.Sprintf("--include-gtids=%s", plan.IncludeGtids))
+	}
+	args = append(args, plan.Files...)
+
+	if len(plan.Files) > 0 {
+		// nolint: gosec
+		decode := exec.Command("mysqlbinlog", args...)
+		// the stop datetime is in UTC.
+		decode.Env = append(os.Environ(), "TZ=UTC")
+		decode.Stderr = os.Stderr
+		stream, err := decode.StdoutPipe()
+		if err != nil {
+			return err
+		}
+
+		apply := exec.Command("mysql", "--host=127.0.0.1", fmt.Sprintf("--port=%d", utils.MysqlPort), "--user=root")
+		apply.Env = append(os.Environ(), fmt.Sprintf("MYSQL_PWD=%s", cfg.RootPassword))
+		apply.Stdin = stream
+		apply.Stdout = os.Stdout
+		apply.Stderr = os.Stderr
+
+		if err = decode.Start(); err != nil {
+			return fmt.Errorf("failed to start mysqlbinlog: %s", err)
+		}
+		if err = apply.Run(); err != nil {
+			_ = decode.Process.Kill()
+			_ = decode.Wait()
+			return fmt.Errorf("failed to apply the binlogs: %s", err)
+		}
+		if err = decode.Wait(); err != nil {
+			return fmt.Errorf("failed to decode the binlogs: %s", err)
+		}
+	}
+
+	log.Info("binlogs applied", "count", len(plan.Files))
+	return os.RemoveAll(binlogReplayPath)
+}
+
+// moveRedoLogs moves the redo logs created by the prepare to the separate
+// volume, where mysql looks for them.
+func moveRedoLogs() error {
+	if exists, _ := checkIfPathExists(redoLogPath); !exists {
+		return nil
+	}
+
+	files, err := filepath.Glob(path.Join(dataPath, "ib_logfile*"))
+	if err != nil {
+		return err
+	}
+	for _, src := range files {
+		// the volumes are different file systems, so the file can't be renamed.
+		dst := path.Join(redoLogPath, path.Base(src))
+		if err = copyFile(src, dst); err != nil {
+			return fmt.Errorf("failed to move %s: %s", src, err)
+		}
+		if err = os.Chown(dst, 1001, 1001); err != nil {
+			return fmt.Errorf("failed to chown %s: %s", dst, err)
+		}
+		if err = os.Remove(src); err != nil {
+			return fmt.Errorf("failed to remove %s: %s", src, err)
+		}
+	}
+	return nil
+}
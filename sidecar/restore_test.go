@@ -0,0 +1,104 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestReadBackupGtid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    string
+	}{
+		{
+			name: "no binlog info",
+		},
+		{
+			name:    "single uuid",
+			content: strPtr("mysql-bin.000003\t194\t0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10\n"),
+			want:    "0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10",
+		},
+		{
+			name: "multiple uuids across lines",
+			content: strPtr("mysql-bin.000003\t194\t0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10,\n" +
+				"4c1f2d7a-c8e0-11eb-9e6e-0242ac110003:1-5\n"),
+			want: "0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10,4c1f2d7a-c8e0-11eb-9e6e-0242ac110003:1-5",
+		},
+		{
+			name:    "gtid disabled",
+			content: strPtr("mysql-bin.000001\t154\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := dataPath
+			dataPath = t.TempDir()
+			defer func() { dataPath = old }()
+
+			if tt.content != nil {
+				if err := ioutil.WriteFile(path.Join(dataPath, "xtrabackup_binlog_info"), []byte(*tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readBackupGtid()
+			if err != nil {
+				t.Fatalf("readBackupGtid() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readBackupGtid() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRestoreSql(t *testing.T) {
+	cfg := &Config{
+		RootPassword:        "rootPassword",
+		ReplicationUser:     "replUser",
+		ReplicationPassword: "replPassword",
+		MetricsUser:         "metricsUser",
+		MetricsPassword:     "metricsPassword",
+	}
+	sql := string(buildRestoreSql(cfg, "0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10"))
+
+	// the init-file requires one statement per line.
+	for _, line := range strings.Split(strings.TrimSpace(sql), "\n") {
+		if !strings.HasSuffix(line, ";") {
+			t.Errorf("statement %q spans lines", line)
+		}
+	}
+	for _, want := range []string{
+		"SET GLOBAL gtid_purged='0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10';",
+		"ALTER USER IF EXISTS 'root'@'localhost' IDENTIFIED BY 'rootPassword';",
+		"CREATE USER 'replUser'@'%' IDENTIFIED BY 'replPassword';",
+		"CREATE USER 'metricsUser'@'%' IDENTIFIED BY 'metricsPassword';",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("restore.sql does not contain %q", want)
+		}
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
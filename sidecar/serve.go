@@ -0,0 +1,234 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	"github.com/zhyass/mysql-operator/internal"
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func NewServeCommand(cfg *Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "start a http server to serve the status of the node.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runServeCommand(signals.SetupSignalHandler(), cfg); err != nil {
+				log.Error(err, "serve command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+// runServeCommand serves the api used by the operator to check the node, it
+// runs in the xenon image to use xenoncli.
+func runServeCommand(ctx context.Context, cfg *Config) error {
+	srv := newAPIServer(cfg)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "failed to stop api server")
+		}
+	}()
+
+	log.Info("starting api server", "address", srv.Addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func newAPIServer(cfg *Config) *server {
+	mux := http.NewServeMux()
+	srv := &server{
+		cfg: cfg,
+		Server: http.Server{
+			Addr:    fmt.Sprintf(":%d", utils.SidecarAPIPort),
+			Handler: mux,
+		},
+	}
+
+	mux.HandleFunc(utils.HealthPath, srv.healthHandler)
+	mux.HandleFunc(utils.RaftStatusPath, srv.raftStatusHandler)
+	mux.HandleFunc(utils.NodeStatusPath, srv.nodeStatusHandler)
+	mux.HandleFunc(utils.LeaderWritablePath, srv.leaderWritableHandler)
+
+	return srv
+}
+
+// raftStatusHandler returns the output of `xenoncli raft status`.
+func (s *server) raftStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "not authenticated", http.StatusForbidden)
+		return
+	}
+
+	out, err := getRaftStatus(r.Context())
+	if err != nil {
+		log.Error(err, "failed to get the raft status")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(out); err != nil {
+		log.Error(err, "failed writing raft status response")
+	}
+}
+
+// nodeStatusHandler returns the role, the read-only and the replication state
+// of the node, the errors are reported in the message.
+func (s *server) nodeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "not authenticated", http.StatusForbidden)
+		return
+	}
+
+	state := internal.NodeState{
+		Leader:      corev1.ConditionUnknown,
+		ReadOnly:    corev1.ConditionUnknown,
+		Replicating: corev1.ConditionUnknown,
+		Lagged:      corev1.ConditionUnknown,
+	}
+
+	role, err := getRaftRole(r.Context())
+	if err != nil {
+		log.Error(err, "failed to check the node role")
+		state.Message = err.Error()
+	}
+	state.Role = role
+	switch role {
+	case "LEADER":
+		state.Leader = corev1.ConditionTrue
+	case "FOLLOWER":
+		state.Leader = corev1.ConditionFalse
+	}
+
+	runner, err := s.newSQLRunner()
+	if err != nil {
+		log.Error(err, "failed to connect the mysql")
+		state.Message = err.Error()
+	} else {
+		defer runner.Close()
+
+		if state.Lagged, state.Replicating, err = runner.CheckSlaveStatus(); err != nil {
+			log.Error(err, "failed to check slave status")
+			state.Message = err.Error()
+		}
+		if state.ReadOnly, err = runner.CheckReadOnly(); err != nil {
+			log.Error(err, "failed to check read only")
+			state.Message = err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(state); err != nil {
+		log.Error(err, "failed writing node status response")
+	}
+}
+
+// leaderWritableHandler turns off the read-only of the leader, it is refused
+// if the node is not the leader.
+func (s *server) leaderWritableHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "not authenticated", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role, err := getRaftRole(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if role != "LEADER" {
+		http.Error(w, fmt.Sprintf("the node is %s", role), http.StatusConflict)
+		return
+	}
+
+	runner, err := s.newSQLRunner()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer runner.Close()
+
+	for _, name := range []string{"read_only", "super_read_only"} {
+		if err = runner.SetGlobalVariable(name, "OFF"); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	log.Info("the leader is set writable", "remote", r.RemoteAddr)
+	s.healthHandler(w, r)
+}
+
+// newSQLRunner connects the local mysql with the operator user, whose password
+// is not rotated, unlike the one of root read from the env at the start.
+func (s *server) newSQLRunner() (*internal.SQLRunner, error) {
+	return internal.NewSQLRunner(s.cfg.OperatorUser, s.cfg.OperatorPassword, "127.0.0.1", utils.MysqlPort)
+}
+
+// getRaftStatus runs `xenoncli raft status` and returns its json output.
+func getRaftStatus(ctx context.Context) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "xenoncli", "raft", "status")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run xenoncli: %s, %s", err, stderr.String())
+	}
+	if stderr.Len() != 0 {
+		return nil, fmt.Errorf("failed to run xenoncli: %s", stderr.String())
+	}
+	return out, nil
+}
+
+// getRaftRole returns the raft state of xenon, eg: LEADER, FOLLOWER, CANDIDATE.
+func getRaftRole(ctx context.Context) (string, error) {
+	out, err := getRaftStatus(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var status map[string]interface{}
+	if err = json.Unmarshal(out, &status); err != nil {
+		return "", err
+	}
+	role, _ := status["state"].(string)
+	return role, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+// fakeXenoncli puts a xenoncli running the script in the PATH.
+func fakeXenoncli(t *testing.T, script string) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "xenoncli"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+}
+
+func TestAPIServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		method   string
+		path     string
+		password string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "raft status",
+			script:   `echo '{"state":"LEADER"}'`,
+			method:   http.MethodGet,
+			path:     utils.RaftStatusPath,
+			password: "backupPassword",
+			wantCode: http.StatusOK,
+			wantBody: `{"state":"LEADER"}`,
+		},
+		{
+			name:     "xenoncli fails",
+			script:   "echo 'dial unix: no such file' >&2\nexit 1",
+			method:   http.MethodGet,
+			path:     utils.RaftStatusPath,
+			password: "backupPassword",
+			wantCode: http.StatusInternalServerError,
+			wantBody: "dial unix: no such file",
+		},
+		{
+			name:     "not authenticated",
+			script:   `echo '{"state":"LEADER"}'`,
+			method:   http.MethodGet,
+			path:     utils.RaftStatusPath,
+			password: "wrong",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "leader writable requires post",
+			script:   `echo '{"state":"LEADER"}'`,
+			method:   http.MethodGet,
+			path:     utils.LeaderWritablePath,
+			password: "backupPassword",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:     "follower can't be set writable",
+			script:   `echo '{"state":"FOLLOWER"}'`,
+			method:   http.MethodPost,
+			path:     utils.LeaderWritablePath,
+			password: "backupPassword",
+			wantCode: http.StatusConflict,
+			wantBody: "the node is FOLLOWER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeXenoncli(t, tt.script)
+			srv := httptest.NewServer(newAPIServer(&Config{
+				BackupUser:     "backupUser",
+				BackupPassword: "backupPassword",
+			}).Handler)
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetBasicAuth("backupUser", tt.password)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("status code = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+const (
+	// backupGtidTrailer is the trailer that carries the executed gtid set of the backup.
+	backupGtidTrailer = "X-Backup-Gtid"
+	// backupStatusTrailer is the trailer that carries the result of the backup.
+	backupStatusTrailer = "X-Backup-Status"
+
+	backupSuccessful = "Success"
+	backupFailed     = "Failed"
+
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// gtidRegexp matches the gtid set printed by xtrabackup at the end of the backup.
+var gtidRegexp = regexp.MustCompile(`GTID of the last change '([^']*)'`)
+
+type server struct {
+	cfg *Config
+	http.Server
+
+	// restoring is 1 if the restored data has not been fixed up yet.
+	restoring int32
+}
+
+func NewHttpCommand(cfg *Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "http",
+		Short: "start a http server to serve the backups.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runHttpServer(signals.SetupSignalHandler(), cfg); err != nil {
+				log.Error(err, "http command failed")
+				os.Exit(1)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func runHttpServer(ctx context.Context, cfg *Config) error {
+	srv := newServer(cfg)
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "failed to stop http server")
+		}
+	}()
+
+	// the restored data is not ready to serve until the restore is finished.
+	if needFinishRestore() {
+		atomic.StoreInt32(&srv.restoring, 1)
+		go func() {
+			finishRestore(cfg, ctx.Done())
+			atomic.StoreInt32(&srv.restoring, 0)
+		}()
+	}
+
+	log.Info("starting http server", "address", srv.Addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func newServer(cfg *Config) *server {
+	mux := http.NewServeMux()
+	srv := &server{
+		cfg: cfg,
+		Server: http.Server{
+			Addr:    fmt.Sprintf(":%d", utils.SidecarHTTPPort),
+			Handler: mux,
+		},
+	}
+
+	mux.HandleFunc(utils.HealthPath, srv.healthHandler)
+	mux.HandleFunc(utils.ReadyPath, srv.readyHandler)
+	mux.HandleFunc(utils.DiskUsagePath, srv.diskUsageHandler)
+	// only one backup can be taken at the same time.
+	mux.Handle(utils.XBackupPath, maxClients(http.HandlerFunc(srv.backupHandler), 1))
+
+	return srv
+}
+
+func (s *server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("OK")); err != nil {
+		log.Error(err, "failed writing health response")
+	}
+}
+
+func (s *server) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&s.restoring) == 1 {
+		http.Error(w, "restoring", http.StatusServiceUnavailable)
+		return
+	}
+	s.healthHandler(w, r)
+}
+
+// diskUsageHandler returns the disk usage of the pod in json.
+func (s *server) diskUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "not authenticated", http.StatusForbidden)
+		return
+	}
+
+	usage, err := getDiskUsage()
+	if err != nil {
+		log.Error(err, "failed to get the disk usage")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(usage); err != nil {
+		log.Error(err, "failed writing disk usage response")
+	}
+}
+
+// backupHandler streams a xtrabackup of the local mysql to the client. The
+// result and the gtid set of the backup are sent as http trailers.
+func (s *server) backupHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Error(w, "not authenticated", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Trailer", fmt.Sprintf("%s,%s", backupGtidTrailer, backupStatusTrailer))
+
+	var stderr bytes.Buffer
+	// nolint: gosec
+	cmd := exec.CommandContext(r.Context(), "xtrabackup", s.cfg.XtrabackupArgs()...)
+	cmd.Env = s.cfg.XtrabackupEnv()
+	cmd.Stdout = w
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	if err := cmd.Start(); err != nil {
+		log.Error(err, "failed to start xtrabackup")
+		http.Error(w, "xtrabackup failed", http.StatusInternalServerError)
+		return
+	}
+
+	if err := cmd.Wait(); err != nil {
+		log.Error(err, "xtrabackup failed")
+		w.Header().Set(backupStatusTrailer, backupFailed)
+		return
+	}
+
+	w.Header().Set(backupGtidTrailer, parseGtidSet(stderr.String()))
+	w.Header().Set(backupStatusTrailer, backupSuccessful)
+	log.Info("backup has been streamed successfully", "remote", r.RemoteAddr)
+}
+
+func (s *server) isAuthenticated(r *http.Request) bool {
+	user, pass, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(user), []byte(s.cfg.BackupUser)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(s.cfg.BackupPassword)) == 1
+}
+
+// maxClients limits the number of the concurrent requests to the handler.
+func maxClients(h http.Handler, n int) http.Handler {
+	sema := make(chan struct{}, n)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case sema <- struct{}{}:
+			defer func() { <-sema }()
+			h.ServeHTTP(w, r)
+		default:
+			http.Error(w, "another backup is in progress", http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// parseGtidSet extracts the gtid set from the xtrabackup output.
+func parseGtidSet(output string) string {
+	matches := gtidRegexp.FindStringSubmatch(output)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.ReplaceAll(matches[1], "\n", "")
+}
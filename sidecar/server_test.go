@@ -0,0 +1,163 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zhyass/mysql-operator/utils"
+)
+
+func TestParseGtidSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name: "single uuid",
+			output: "xtrabackup: Transaction log of lsn (2617520) to (2617529) was copied.\n" +
+				"MySQL binlog position: filename 'mysql-bin.000003', position '194', " +
+				"GTID of the last change '0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10'\n" +
+				"completed OK!\n",
+			want: "0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10",
+		},
+		{
+			name: "multiple uuids across lines",
+			output: "MySQL binlog position: filename 'mysql-bin.000003', position '194', " +
+				"GTID of the last change '0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10,\n" +
+				"4c1f2d7a-c8e0-11eb-9e6e-0242ac110003:1-5'\n",
+			want: "0b0a5c3c-c8e0-11eb-9e6e-0242ac110002:1-10,4c1f2d7a-c8e0-11eb-9e6e-0242ac110003:1-5",
+		},
+		{
+			name:   "empty gtid set",
+			output: "MySQL binlog position: filename 'mysql-bin.000001', position '154', GTID of the last change ''\n",
+			want:   "",
+		},
+		{
+			name:   "no gtid",
+			output: "xtrabackup: error: failed to connect\n",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGtidSet(tt.output); got != tt.want {
+				t.Errorf("parseGtidSet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeXtrabackup puts a xtrabackup script in the PATH, which writes the
+// stream to the stdout and the gtid set to the stderr.
+func fakeXtrabackup(t *testing.T, script string) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "xtrabackup"), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+}
+
+func TestBackupHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		password   string
+		wantCode   int
+		wantBody   string
+		wantStatus string
+		wantGtid   string
+	}{
+		{
+			name: "success",
+			script: "echo stream\n" +
+				"echo \"GTID of the last change 'a8c0e06c-c8e0-11eb-9e6e-0242ac110002:1-7'\" >&2\n",
+			password:   "backupPassword",
+			wantCode:   http.StatusOK,
+			wantBody:   "stream\n",
+			wantStatus: backupSuccessful,
+			wantGtid:   "a8c0e06c-c8e0-11eb-9e6e-0242ac110002:1-7",
+		},
+		{
+			name:       "xtrabackup fails after streaming",
+			script:     "echo partial\nexit 1\n",
+			password:   "backupPassword",
+			wantCode:   http.StatusOK,
+			wantBody:   "partial\n",
+			wantStatus: backupFailed,
+		},
+		{
+			name:     "not authenticated",
+			script:   "echo stream\n",
+			password: "wrong",
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeXtrabackup(t, tt.script)
+			srv := httptest.NewServer(newServer(&Config{
+				BackupUser:     "backupUser",
+				BackupPassword: "backupPassword",
+			}).Handler)
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+utils.XBackupPath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetBasicAuth("backupUser", tt.password)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Fatalf("status code = %d, want %d", resp.StatusCode, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			// the trailers are only available once the body has been read.
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if got := resp.Trailer.Get(backupStatusTrailer); got != tt.wantStatus {
+				t.Errorf("%s = %q, want %q", backupStatusTrailer, got, tt.wantStatus)
+			}
+			if got := resp.Trailer.Get(backupGtidTrailer); got != tt.wantGtid {
+				t.Errorf("%s = %q, want %q", backupGtidTrailer, got, tt.wantGtid)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import "testing"
+
+func TestNewS3Client(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{endpoint: "http://minio.default:9000", want: "http://minio.default:9000"},
+		{endpoint: "https://s3.amazonaws.com", want: "https://s3.amazonaws.com"},
+		{endpoint: "s3.amazonaws.com", want: "https://s3.amazonaws.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			client, err := newS3Client(tt.endpoint, "us-east-1", "accessKey", "secretKey")
+			if err != nil {
+				t.Fatalf("newS3Client() error = %v", err)
+			}
+			if got := client.EndpointURL().String(); got != tt.want {
+				t.Errorf("endpoint = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -39,6 +39,20 @@ var (
 	sysPath             = utils.SysVolumeMountPath
 	xenonPath           = utils.XenonVolumeMountPath
 	initFilePath        = utils.InitFileVolumeMountPath
+	// the separate volumes, they are mounted only if they are declared.
+	binlogPath  = utils.BinlogVolumeMountPath
+	redoLogPath = utils.RedoLogVolumeMountPath
+	tmpPath     = utils.TmpVolumeMountPath
+	// sidecarBinPath is shared with the sidecar container.
+	sidecarBinPath = utils.SidecarBinVolumeMountPath
+	// restoreSqlPath is the init-file that fixes up the restored data.
+	restoreSqlPath = utils.ConfVolumeMountPath + "/restore.sql"
+	// credentialsSqlPath is the init-file that resets the password of the root user.
+	credentialsSqlPath = utils.ConfVolumeMountPath + "/credentials.sql"
+	// binlogReplayPath is the directory of the downloaded binlogs to apply.
+	binlogReplayPath = utils.ConfVolumeMountPath + "/binlogs"
+	// binlogReplayPlanPath is the plan to apply the downloaded binlogs.
+	binlogReplayPlanPath = binlogReplayPath + "/replay.json"
 )
 
 // copyFile the src file to dst.
@@ -71,6 +85,14 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
+// getBinlogPath returns the directory of the binlogs.
+func getBinlogPath() string {
+	if _, err := os.Stat(binlogPath); err == nil {
+		return binlogPath
+	}
+	return dataPath
+}
+
 func getEnvValue(key string) string {
 	value := os.Getenv(key)
 	if len(value) == 0 {
@@ -82,6 +104,15 @@ func getEnvValue(key string) string {
 
 // Generate mysql server-id from pod ordinal index.
 func generateServerID(name string) (int, error) {
+	ordinal, err := getOrdinal(name)
+	if err != nil {
+		return -1, err
+	}
+	return mysqlServerIDOffset + ordinal, nil
+}
+
+// getOrdinal extracts the ordinal index from the pod name.
+func getOrdinal(name string) (int, error) {
 	idx := strings.LastIndexAny(name, "-")
 	if idx == -1 {
 		return -1, fmt.Errorf("failed to extract ordinal from hostname: %s", name)
@@ -92,5 +123,5 @@ func generateServerID(name string) (int, error) {
 		log.Error(err, "failed to extract ordinal form hostname", "hostname", name)
 		return -1, fmt.Errorf("failed to extract ordinal from hostname: %s", name)
 	}
-	return mysqlServerIDOffset + ordinal, nil
+	return ordinal, nil
 }
@@ -0,0 +1,26 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+// BackupResult is written by the backup job to its termination message, so
+// that the operator can record it in the backup status.
+type BackupResult struct {
+	// Size is the size in bytes of the uploaded backup stream.
+	Size int64 `json:"size"`
+	// GtidSet is the executed gtid set of the backup.
+	GtidSet string `json:"gtidSet,omitempty"`
+}
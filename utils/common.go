@@ -16,6 +16,26 @@ limitations under the License.
 
 package utils
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// sqlStringReplacer escapes the special characters of the quoted SQL strings.
+var sqlStringReplacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"'", "\\'",
+	"\n", "\\n",
+	"\r", "\\r",
+	"\x00", "\\0",
+	"\x1a", "\\Z",
+)
+
 func Min(a, b int64) int64 {
 	if a < b {
 		return a
@@ -29,3 +49,94 @@ func Max(a, b int64) int64 {
 	}
 	return b
 }
+
+// StringInSlice returns true if the string is in the slice.
+func StringInSlice(str string, list []string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAlphaNumeric checks if the password can be passed to the entrypoint of the
+// mysql image, which creates the users without escaping the passwords.
+func IsAlphaNumeric(str string) bool {
+	for _, c := range str {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// EscapeSQLString escapes the string to be quoted in the SQL statements. The
+// line breaks are escaped too, the init files require one statement per line.
+func EscapeSQLString(str string) string {
+	return sqlStringReplacer.Replace(str)
+}
+
+// GetSidecarImage returns the sidecar image for the mysql version. The image
+// is replaced if it is empty or the default one of another mysql version,
+// whose xtrabackup can't back up this version, the custom ones are kept.
+func GetSidecarImage(image, version string) string {
+	ver, err := semver.Parse(version)
+	if err != nil {
+		return image
+	}
+	defaultImage, ok := SidecarImages[fmt.Sprintf("%d.%d", ver.Major, ver.Minor)]
+	if !ok {
+		return image
+	}
+	if len(image) == 0 {
+		return defaultImage
+	}
+	for _, other := range SidecarImages {
+		if image == other {
+			return defaultImage
+		}
+	}
+	return image
+}
+
+// ValidateMysqlUpgrade returns an error if the upgrade path is not supported.
+// The patch versions of the same minor version and 5.7 to 8.0 can be upgraded,
+// downgrades are refused.
+func ValidateMysqlUpgrade(from, to string) error {
+	fromVer, err := semver.Parse(from)
+	if err != nil {
+		return fmt.Errorf("invalid running version %s: %s", from, err)
+	}
+	toVer, err := semver.Parse(to)
+	if err != nil {
+		return fmt.Errorf("invalid version %s: %s", to, err)
+	}
+
+	if toVer.LT(fromVer) {
+		return fmt.Errorf("downgrade from %s to %s is not supported", from, to)
+	}
+	if toVer.Major == fromVer.Major && toVer.Minor == fromVer.Minor {
+		return nil
+	}
+	if fromVer.Major == 5 && fromVer.Minor == 7 && toVer.Major == 8 && toVer.Minor == 0 {
+		return nil
+	}
+	return fmt.Errorf("upgrade from %s to %s is not supported", from, to)
+}
+
+// HashConfigs returns a short hash of the configs, which does not depend on
+// the order of the keys.
+func HashConfigs(configs map[string]string) string {
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, configs[key])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import "testing"
+
+func TestValidateMysqlUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr bool
+	}{
+		{name: "same version", from: "5.7.33", to: "5.7.33"},
+		{name: "patch upgrade", from: "5.7.33", to: "5.7.34"},
+		{name: "5.7 to 8.0", from: "5.7.33", to: "8.0.25"},
+		{name: "patch downgrade", from: "8.0.25", to: "8.0.21", wantErr: true},
+		{name: "8.0 to 5.7", from: "8.0.25", to: "5.7.33", wantErr: true},
+		{name: "skip a major version", from: "5.6.51", to: "8.0.25", wantErr: true},
+		{name: "invalid running version", from: "5.7", to: "5.7.33", wantErr: true},
+		{name: "invalid version", from: "5.7.33", to: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMysqlUpgrade(tt.from, tt.to); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMysqlUpgrade() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetSidecarImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		version string
+		want    string
+	}{
+		{name: "default for 5.7", version: "5.7.33", want: SidecarImages["5.7"]},
+		{name: "default for 8.0", version: "8.0.25", want: SidecarImages["8.0"]},
+		{
+			name:    "default of 5.7 is replaced for 8.0",
+			image:   SidecarImages["5.7"],
+			version: "8.0.25",
+			want:    SidecarImages["8.0"],
+		},
+		{
+			name:    "default of 8.0 is replaced for 5.7",
+			image:   SidecarImages["8.0"],
+			version: "5.7.33",
+			want:    SidecarImages["5.7"],
+		},
+		{
+			name:    "custom image is kept",
+			image:   "registry.local/sidecar:dev",
+			version: "8.0.25",
+			want:    "registry.local/sidecar:dev",
+		},
+		{
+			name:    "unknown version",
+			image:   SidecarImages["5.7"],
+			version: "5.6.51",
+			want:    SidecarImages["5.7"],
+		},
+		{
+			name:    "invalid version",
+			image:   SidecarImages["5.7"],
+			version: "8.0",
+			want:    SidecarImages["5.7"],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetSidecarImage(tt.image, tt.version); got != tt.want {
+				t.Errorf("GetSidecarImage() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashConfigs(t *testing.T) {
+	configs := map[string]string{"max_connections": "1024", "innodb_log_file_size": "1073741824"}
+	tests := []struct {
+		name    string
+		configs map[string]string
+		same    bool
+	}{
+		{
+			name:    "same configs",
+			configs: map[string]string{"innodb_log_file_size": "1073741824", "max_connections": "1024"},
+			same:    true,
+		},
+		{
+			name:    "changed value",
+			configs: map[string]string{"innodb_log_file_size": "1073741824", "max_connections": "2048"},
+		},
+		{
+			name:    "added key",
+			configs: map[string]string{"innodb_log_file_size": "1073741824", "max_connections": "1024", "back_log": "80"},
+		},
+		{
+			name:    "removed key",
+			configs: map[string]string{"max_connections": "1024"},
+		},
+	}
+
+	want := HashConfigs(configs)
+	if len(want) != 16 {
+		t.Fatalf("HashConfigs() = %s, want 16 characters", want)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HashConfigs(tt.configs); (got == want) != tt.same {
+				t.Errorf("HashConfigs() = %s, base %s, want same %v", got, want, tt.same)
+			}
+		})
+	}
+}
+
+func TestIsAlphaNumeric(t *testing.T) {
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{str: "", want: true},
+		{str: "Passw0rd", want: true},
+		{str: "Qing@123"},
+		{str: "pass word"},
+		{str: "pass'word"},
+		{str: "密码"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			if got := IsAlphaNumeric(tt.str); got != tt.want {
+				t.Errorf("IsAlphaNumeric() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeSQLString(t *testing.T) {
+	tests := []struct {
+		str  string
+		want string
+	}{
+		{str: "Passw0rd", want: "Passw0rd"},
+		{str: "Qing@123", want: "Qing@123"},
+		{str: "it's", want: `it\'s`},
+		{str: `back\slash`, want: `back\\slash`},
+		{str: "two\nlines\r", want: `two\nlines\r`},
+		{str: "nul\x00", want: `nul\0`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			if got := EscapeSQLString(tt.str); got != tt.want {
+				t.Errorf("EscapeSQLString() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,11 +23,20 @@ var (
 	// MySQLTagsToSemVer maps simple version to semver versions
 	MySQLTagsToSemVer = map[string]string{
 		"5.7": "5.7.33",
+		"8.0": "8.0.25",
 	}
 
 	// MysqlImageVersions is a map of supported mysql version and their image
 	MysqlImageVersions = map[string]string{
 		"5.7.33": "percona/percona-server:5.7.33",
+		"8.0.25": "percona/percona-server:8.0.25",
+	}
+
+	// SidecarImages maps the minor version of mysql to the default sidecar
+	// image, which ships the matching xtrabackup.
+	SidecarImages = map[string]string{
+		"5.7": "zhyass/sidecar:0.1",
+		"8.0": "zhyass/sidecar:0.1-mysql8.0",
 	}
 )
 
@@ -35,6 +44,7 @@ const (
 	// init containers
 	ContainerInitSidecarName = "init-sidecar"
 	ContainerInitMysqlName   = "init-mysql"
+	ContainerInitCloneName   = "init-clone"
 
 	// containers
 	ContainerMysqlName    = "mysql"
@@ -42,6 +52,13 @@ const (
 	ContainerMetricsName  = "metrics"
 	ContainerSlowLogName  = "slowlog"
 	ContainerAuditLogName = "auditlog"
+	ContainerBackupName   = "backup"
+	// ContainerSidecarName is the container that serves the status of the node.
+	ContainerSidecarName = "sidecar"
+	// ContainerBinlogArchiverName is the container that archives the binlogs.
+	ContainerBinlogArchiverName = "binlog-archiver"
+	// ContainerProxySQLName is the container of the proxy deployment.
+	ContainerProxySQLName = "proxysql"
 
 	MysqlPortName = "mysql"
 	MysqlPort     = 3306
@@ -52,28 +69,99 @@ const (
 	XenonPortName = "xenon"
 	XenonPort     = 8801
 
+	SidecarHTTPPortName = "sidecar-http"
+	SidecarHTTPPort     = 8082
+
+	SidecarAPIPortName = "sidecar-api"
+	SidecarAPIPort     = 8083
+
+	ProxyAdminPortName = "proxy-admin"
+	ProxyAdminPort     = 6032
+
 	ReplicationUser = "qc_repl"
 	MetricsUser     = "qc_metrics"
+	BackupUser      = "qc_backup"
+	// OperatorUser is used by the operator to manage the users and databases.
+	OperatorUser = "qc_operator"
+	// ProxyAdminUser is used by the operator to configure proxysql remotely.
+	ProxyAdminUser = "radmin"
+
+	// XBackupPath is the http path used to stream a xtrabackup from the sidecar.
+	XBackupPath = "/xbackup"
+	// HealthPath is the http path used to check the sidecar server.
+	HealthPath = "/health"
+	// ReadyPath is the http path used to check whether the restored data is ready to serve.
+	ReadyPath = "/ready"
+	// DiskUsagePath is the http path used to get the disk usage of the pod.
+	DiskUsagePath = "/disk"
+	// NodeStatusPath is the http path used to get the role, the read-only and the replication state of the node.
+	NodeStatusPath = "/status"
+	// RaftStatusPath is the http path used to get the raft status of xenon.
+	RaftStatusPath = "/raft/status"
+	// LeaderWritablePath is the http path used to make the leader writable.
+	LeaderWritablePath = "/leader/writable"
+
+	// SwitchoverAnnotation requests to switch the leadership over to the pod.
+	SwitchoverAnnotation = "mysql.radondb.io/switchover-to"
+	// UpgradedVersionAnnotation records the mysql version the data of the pod has been upgraded to.
+	UpgradedVersionAnnotation = "mysql.radondb.io/upgraded-version"
+	// RotateCredentialsAnnotation requests to rotate the passwords of the internal users,
+	// a new rotation is started whenever its value changes.
+	RotateCredentialsAnnotation = "mysql.radondb.io/rotate-credentials"
+	// CredentialsRotatedAnnotation records the last rotation time on the pod template.
+	CredentialsRotatedAnnotation = "mysql.radondb.io/credentials-rotated-at"
+	// TLSHashAnnotation records the hash of the certificate on the pod template.
+	TLSHashAnnotation = "mysql.radondb.io/tls-hash"
+	// StaticConfigHashAnnotation records the hash of the static configs on the pod template.
+	StaticConfigHashAnnotation = "mysql.radondb.io/static-config-hash"
+	// ProxyServersHashAnnotation records the hash of the servers applied to the proxysql pod.
+	ProxyServersHashAnnotation = "mysql.radondb.io/proxy-servers-hash"
+	// ProxyUsersHashAnnotation records the hash of the users applied to the proxysql pod.
+	ProxyUsersHashAnnotation = "mysql.radondb.io/proxy-users-hash"
+	// ProxyConfigFileHashAnnotation records the hash of proxysql.cnf on the pod template.
+	ProxyConfigFileHashAnnotation = "mysql.radondb.io/proxy-config-file-hash"
+	// DynamicConfigHashAnnotation records the hash of the dynamic configs applied to the pod.
+	DynamicConfigHashAnnotation = "mysql.radondb.io/dynamic-config-hash"
+
+	// BinlogArchiveDir is the directory of the archived binlogs under the cluster name.
+	BinlogArchiveDir = "binlogs"
 
 	// volumes names
-	ConfVolumeName     = "conf"
-	ConfMapVolumeName  = "config-map"
-	LogsVolumeName     = "logs"
-	DataVolumeName     = "data"
-	SysVolumeName      = "host-sys"
-	ScriptsVolumeName  = "scripts"
-	XenonVolumeName    = "xenon"
-	InitFileVolumeName = "init-mysql"
+	ConfVolumeName       = "conf"
+	ConfMapVolumeName    = "config-map"
+	LogsVolumeName       = "logs"
+	DataVolumeName       = "data"
+	SysVolumeName        = "host-sys"
+	ScriptsVolumeName    = "scripts"
+	XenonVolumeName      = "xenon"
+	InitFileVolumeName   = "init-mysql"
+	TLSVolumeName        = "tls"
+	ProxyConfVolumeName  = "proxysql-conf"
+	ProxyDataVolumeName  = "proxysql-data"
+	BinlogVolumeName     = "binlog"
+	RedoLogVolumeName    = "redo-log"
+	TmpVolumeName        = "tmp"
+	SidecarBinVolumeName = "sidecar-bin"
 
 	// volumes mount path.
-	ConfVolumeMountPath     = "/etc/mysql"
-	ConfMapVolumeMountPath  = "/mnt/config-map"
-	LogsVolumeMountPath     = "/var/log/mysql"
-	DataVolumeMountPath     = "/var/lib/mysql"
-	SysVolumeMountPath      = "/host-sys"
-	ScriptsVolumeMountPath  = "/scripts"
-	XenonVolumeMountPath    = "/etc/xenon"
-	InitFileVolumeMountPath = "/docker-entrypoint-initdb.d"
+	ConfVolumeMountPath      = "/etc/mysql"
+	ConfMapVolumeMountPath   = "/mnt/config-map"
+	LogsVolumeMountPath      = "/var/log/mysql"
+	DataVolumeMountPath      = "/var/lib/mysql"
+	SysVolumeMountPath       = "/host-sys"
+	ScriptsVolumeMountPath   = "/scripts"
+	XenonVolumeMountPath     = "/etc/xenon"
+	InitFileVolumeMountPath  = "/docker-entrypoint-initdb.d"
+	TLSVolumeMountPath       = "/etc/mysql-tls"
+	ProxyConfVolumeMountPath = "/etc/proxysql"
+	ProxyDataVolumeMountPath = "/var/lib/proxysql"
+	// the separate volumes are mounted out of the data directory, otherwise
+	// mysql takes them as databases.
+	BinlogVolumeMountPath  = "/var/lib/mysql-binlog"
+	RedoLogVolumeMountPath = "/var/lib/mysql-redo"
+	TmpVolumeMountPath     = "/var/lib/mysql-tmp"
+	// the sidecar binary is shared with the sidecar container of the xenon image.
+	SidecarBinVolumeMountPath = "/opt/sidecar"
 )
 
 // ResourceName is the type for aliasing resources that will be created.
@@ -92,6 +180,16 @@ const (
 	FollowerService ResourceName = "follower-service"
 	// Secret is the name of the secret that contains operator related credentials.
 	Secret ResourceName = "secret"
+	// TLSSecret is the name of the secret that contains the certificate of mysql.
+	TLSSecret ResourceName = "tls-secret"
+	// Certificate is the alias of the cert-manager certificate resource.
+	Certificate ResourceName = "certificate"
+	// PodDisruptionBudget is the alias of the pdb resource.
+	PodDisruptionBudget ResourceName = "pdb"
+	// ProxyDeployment is the alias of the proxysql deployment resource.
+	ProxyDeployment ResourceName = "proxy"
+	// ProxyService is the name of the service that points to the proxysql pods.
+	ProxyService ResourceName = "proxy-service"
 	// Role is the alias of the role resource.
 	Role ResourceName = "role"
 	// RoleBinding is the alias of the rolebinding resource.
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sizeRegexp matches the sizes with a suffix, which are only accepted in the option files.
+var sizeRegexp = regexp.MustCompile(`^([0-9]+)([KkMmGg])$`)
+
+// staticMysqlVariables can't be changed at runtime, changing them requires
+// restarting mysqld.
+var staticMysqlVariables = map[string]bool{
+	"back_log":                        true,
+	"bind_address":                    true,
+	"default_authentication_plugin":   true,
+	"ft_max_word_len":                 true,
+	"ft_min_word_len":                 true,
+	"innodb_autoinc_lock_mode":        true,
+	"innodb_buffer_pool_chunk_size":   true,
+	"innodb_buffer_pool_instances":    true,
+	"innodb_data_file_path":           true,
+	"innodb_data_home_dir":            true,
+	"innodb_doublewrite":              true,
+	"innodb_flush_method":             true,
+	"innodb_force_recovery":           true,
+	"innodb_ft_cache_size":            true,
+	"innodb_ft_max_token_size":        true,
+	"innodb_ft_min_token_size":        true,
+	"innodb_ft_sort_pll_degree":       true,
+	"innodb_ft_total_cache_size":      true,
+	"innodb_log_buffer_size":          true,
+	"innodb_log_file_size":            true,
+	"innodb_log_files_in_group":       true,
+	"innodb_log_group_home_dir":       true,
+	"innodb_numa_interleave":          true,
+	"innodb_open_files":               true,
+	"innodb_page_cleaners":            true,
+	"innodb_page_size":                true,
+	"innodb_purge_threads":            true,
+	"innodb_read_io_threads":          true,
+	"innodb_rollback_on_timeout":      true,
+	"innodb_sort_buffer_size":         true,
+	"innodb_sync_array_size":          true,
+	"innodb_temp_data_file_path":      true,
+	"innodb_undo_directory":           true,
+	"innodb_undo_tablespaces":         true,
+	"innodb_use_native_aio":           true,
+	"innodb_write_io_threads":         true,
+	"large_pages":                     true,
+	"log_error":                       true,
+	"log_slave_updates":               true,
+	"lower_case_table_names":          true,
+	"max_digest_length":               true,
+	"open_files_limit":                true,
+	"performance_schema":              true,
+	"performance_schema_digests_size": true,
+	"relay_log_recovery":              true,
+	"secure_file_priv":                true,
+	"skip_external_locking":           true,
+	"skip_name_resolve":               true,
+	"slave_load_tmpdir":               true,
+	"table_open_cache_instances":      true,
+	"thread_handling":                 true,
+	"tmpdir":                          true,
+}
+
+// IsStaticMysqlVariable returns true if the variable can't be changed at runtime.
+func IsStaticMysqlVariable(name string) bool {
+	return staticMysqlVariables[NormalizeMysqlVariable(name)]
+}
+
+// NormalizeMysqlVariable returns the name of the variable with underscores,
+// mysqld accepts both dashes and underscores in the option files.
+func NormalizeMysqlVariable(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "-", "_")
+}
+
+// ParseMysqlSize returns the bytes of the value in the option files, which is
+// an integer with an optional K, M or G suffix.
+func ParseMysqlSize(value string) (int64, bool) {
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i, true
+	}
+
+	m := sizeRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return 0, false
+	}
+	i, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToUpper(m[2]) {
+	case "K":
+		i <<= 10
+	case "M":
+		i <<= 20
+	case "G":
+		i <<= 30
+	}
+	return i, true
+}
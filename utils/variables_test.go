@@ -0,0 +1,72 @@
+/*
+Copyright 2021 zhyass.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import "testing"
+
+func TestIsStaticMysqlVariable(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "innodb_log_file_size", want: true},
+		{name: "innodb-log-file-size", want: true},
+		{name: "Performance_Schema", want: true},
+		{name: "innodb_purge_threads", want: true},
+		{name: "tmpdir", want: true},
+		{name: "max_connections"},
+		{name: "innodb-buffer-pool-size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsStaticMysqlVariable(tt.name); got != tt.want {
+				t.Errorf("IsStaticMysqlVariable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMysqlSize(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   int64
+		wantOk bool
+	}{
+		{value: "1024", want: 1024, wantOk: true},
+		{value: "0", want: 0, wantOk: true},
+		{value: "16K", want: 16 << 10, wantOk: true},
+		{value: "16k", want: 16 << 10, wantOk: true},
+		{value: "128M", want: 128 << 20, wantOk: true},
+		{value: "2G", want: 2 << 30, wantOk: true},
+		{value: "2g", want: 2 << 30, wantOk: true},
+		{value: "1.5G"},
+		{value: "2T"},
+		{value: "G"},
+		{value: "ON"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseMysqlSize(tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("ParseMysqlSize() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}